@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Move quichat's stored data to another machine in an encrypted file",
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create <file>",
+	Short: "Write an encrypted backup of the config, address book, reputations and other state",
+	Long: `Pack what quichat stores in ~/.quichat (or the --profile's directory) into
+one file encrypted with a passphrase: the config with its identities, the
+address book, reputations with verified and blocked peers, scheduled
+messages, feed state and the Nostr bridge's secret. Add transcripts with
+--transcript; their rotated parts come along.
+Examples:
+  quichat backup create laptop.qbk
+  quichat backup create laptop.qbk --transcript meeting.log`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transcripts, _ := cmd.Flags().GetStringSlice("transcript")
+		pass, err := passphrase(cmd, true)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		included, err := app.CreateBackup(f, pass, transcripts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(args[0])
+			return err
+		}
+		for _, s := range included {
+			fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s\n", s)
+		}
+		return nil
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Unpack a backup made with quichat backup create",
+	Long: `Decrypt a backup and put its files back: state into ~/.quichat (or the
+--profile's directory), transcripts into --transcript-dir. Nothing is
+replaced unless --force is given. Stop any running quichat first.
+Examples:
+  quichat backup restore laptop.qbk
+  quichat --profile work backup restore work.qbk --transcript-dir ~/logs`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts app.RestoreOptions
+		opts.TranscriptDir, _ = cmd.Flags().GetString("transcript-dir")
+		opts.Force, _ = cmd.Flags().GetBool("force")
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		pass, err := passphrase(cmd, false)
+		if err != nil {
+			return err
+		}
+		restored, err := app.RestoreBackup(f, pass, opts)
+		for _, s := range restored {
+			fmt.Fprintf(cmd.OutOrStdout(), "Restored %s\n", s)
+		}
+		return err
+	},
+}
+
+// passphrase reads the backup passphrase from --passphrase-file, or asks
+// for it on the terminal, twice when it is being chosen.
+func passphrase(cmd *cobra.Command, confirm bool) ([]byte, error) {
+	if file, _ := cmd.Flags().GetString("passphrase-file"); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if b = bytes.TrimRight(b, "\r\n"); len(b) == 0 {
+			return nil, fmt.Errorf("%s is empty", file)
+		}
+		return b, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no terminal to ask for the passphrase on; use --passphrase-file")
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), "Passphrase: ")
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(cmd.ErrOrStderr())
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		fmt.Fprint(cmd.ErrOrStderr(), "Again: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("the passphrases differ")
+		}
+	}
+	return pass, nil
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupCreateCmd, backupRestoreCmd)
+	backupCmd.PersistentFlags().String("passphrase-file", "", "read the passphrase from this file instead of asking")
+	backupCreateCmd.Flags().StringSlice("transcript", nil, "also back up this transcript and its rotated parts (repeatable)")
+	backupRestoreCmd.Flags().String("transcript-dir", ".", "directory to restore transcripts into")
+	backupRestoreCmd.Flags().Bool("force", false, "replace files that already exist")
+}
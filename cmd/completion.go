@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds how long a tab press waits for the daemon.
+const completionTimeout = 500 * time.Millisecond
+
+// completePeerIDs offers the peers this node remembers and, when a daemon
+// is running, the members of its room, each described by nick.
+func completePeerIDs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	selectProfile(cmd)
+	nicks := map[string]string{}
+	if path, err := app.ReputationPath(); err == nil {
+		if reps, err := app.LoadReputations(path); err == nil {
+			for id, rep := range reps {
+				nicks[id.String()] = rep.Nick
+			}
+		}
+	}
+	if st, err := daemonStatus(cmd); err == nil {
+		for id, label := range st.Nicks {
+			nicks[id.String()] = label
+		}
+	}
+	var out []cobra.Completion
+	for id, nick := range nicks {
+		if strings.HasPrefix(id, toComplete) {
+			out = append(out, cobra.CompletionWithDesc(id, nick))
+		}
+	}
+	slices.Sort(out)
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNicks offers the nicks of the identities in the config.
+func completeNicks(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	selectProfile(cmd)
+	cfgPath, _ := cmd.Flags().GetString("config")
+	cfg, err := app.LoadConfig(cfgPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []cobra.Completion
+	for room, id := range cfg.Identities {
+		if id.Nick != "" && strings.HasPrefix(id.Nick, toComplete) && !slices.Contains(out, id.Nick) {
+			out = append(out, cobra.CompletionWithDesc(id.Nick, "identity for "+room))
+		}
+	}
+	slices.Sort(out)
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles offers the profiles created so far.
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	names, _ := app.Profiles()
+	var out []cobra.Completion
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			out = append(out, name)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// daemonStatus asks the daemon on the default socket, or --socket where the
+// command has it, without holding up the shell for long.
+func daemonStatus(cmd *cobra.Command) (app.DaemonStatus, error) {
+	sock, err := app.DaemonSocketPath()
+	if f := cmd.Flags().Lookup("socket"); f != nil && f.Value.String() != "" {
+		sock, err = f.Value.String(), nil
+	}
+	if err != nil {
+		return app.DaemonStatus{}, err
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), completionTimeout)
+	defer cancel()
+	return app.QueryStatus(ctx, sock)
+}
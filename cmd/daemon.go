@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep a chat session running in the background",
+	Long: `Start a chat node that stays in the room after your terminal closes.
+Terminals join the session with "quichat attach"; any number can be attached
+at once. /quit in a terminal detaches it, /shutdown stops the daemon.
+Examples:
+  quichat daemon --listen 4001 --nick alice &
+  quichat attach`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer cancel()
+
+		sock, err := socketPath(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := nodeOptions(cmd)
+		if err != nil {
+			return err
+		}
+		node, err := app.NewNode(ctx, opts)
+		if err != nil {
+			return err
+		}
+		defer node.Close()
+
+		return app.RunDaemon(ctx, node, node.Nick(), sock)
+	},
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Attach this terminal to a running daemon",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer cancel()
+
+		sock, err := socketPath(cmd)
+		if err != nil {
+			return err
+		}
+		return app.Attach(ctx, sock)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd, attachCmd)
+
+	addNodeFlags(daemonCmd)
+	for _, c := range []*cobra.Command{daemonCmd, attachCmd} {
+		c.Flags().String("socket", "", "unix socket of the daemon (default ~/.quichat/daemon.sock)")
+	}
+}
+
+// socketPath returns --socket or the default daemon socket.
+func socketPath(cmd *cobra.Command) (string, error) {
+	if s, _ := cmd.Flags().GetString("socket"); s != "" {
+		return s, nil
+	}
+	return app.DaemonSocketPath()
+}
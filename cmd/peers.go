@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var peersCmd = &cobra.Command{
+	Use:   "peers",
+	Short: "Manage the persisted address book of known peers and what you think of them",
+}
+
+var peersExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the address book to a file (or stdout)",
+	Long: `Export the peers this node has seen so another machine (or a friend)
+can import them and use them to join the network without a bootstrap address.
+Examples:
+  quichat peers export > peers.json
+  quichat peers export peers.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := app.AddrBookPath()
+		if err != nil {
+			return err
+		}
+		book, err := app.LoadAddrBook(path)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return book.Write(cmd.OutOrStdout())
+		}
+		if err := book.Save(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d peers to %s\n", len(book), args[0])
+		return nil
+	},
+}
+
+var peersImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Merge peers from an exported address book",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		incoming, err := app.ReadAddrBook(f)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", args[0], err)
+		}
+
+		path, err := app.AddrBookPath()
+		if err != nil {
+			return err
+		}
+		book, err := app.LoadAddrBook(path)
+		if err != nil {
+			return err
+		}
+		n := book.Merge(incoming)
+		if err := book.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Imported %d peers (%d known)\n", n, len(book))
+		return nil
+	},
+}
+
+var peersShowCmd = &cobra.Command{
+	Use:   "show <peer-id>",
+	Short: "Show your saved opinion of a peer: activity, spam score, throttling, blocked, verified",
+	Long: `Show what this node remembers about a peer between sessions. The
+peer ID may be shortened to any unique prefix.
+Examples:
+  quichat peers show 12D3KooWDpJ7As7B`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePeerIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := app.ReputationPath()
+		if err != nil {
+			return err
+		}
+		reps, err := app.LoadReputations(path)
+		if err != nil {
+			return err
+		}
+		id, rep, err := reps.Find(args[0])
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if rep.Nick != "" {
+			fmt.Fprintf(out, "%s (%s)\n", id, rep.Nick)
+		} else {
+			fmt.Fprintln(out, id)
+		}
+		fmt.Fprintln(out, "  "+rep.Report(time.Now()))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(peersCmd)
+	peersCmd.AddCommand(peersExportCmd, peersImportCmd, peersShowCmd)
+}
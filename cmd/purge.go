@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/spf13/cobra"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete locally stored data about a peer, a room, or everything",
+	Long: `Remove what quichat has stored on this machine: the address book of
+peers seen (~/.quichat/addrbook.json), crash reports, messages waiting
+in /schedule, which feed items were announced and the Nostr bridge's
+secret. Settings in config.json are left alone.
+
+Chat messages only reach the disk in files you asked for with --transcript
+or --record. Name them here too: --room and --peer remove their entries
+from a transcript (transcript verify then reports them missing) and
+delete recordings of that room or with messages from that peer.
+
+Stop any running quichat first: a running node writes the peers it is
+connected to back into the address book.
+Examples:
+  quichat purge --peer 12D3KooW... --dry-run
+  quichat purge --room standup --transcript meeting.log --record standup.qcr
+  quichat purge --all`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts app.PurgeOptions
+		id, _ := cmd.Flags().GetString("peer")
+		opts.Room, _ = cmd.Flags().GetString("room")
+		opts.All, _ = cmd.Flags().GetBool("all")
+		opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+		opts.Transcripts, _ = cmd.Flags().GetStringSlice("transcript")
+		opts.Recordings, _ = cmd.Flags().GetStringSlice("record")
+
+		set := 0
+		for _, b := range []bool{id != "", opts.Room != "", opts.All} {
+			if b {
+				set++
+			}
+		}
+		if set != 1 {
+			return errors.New("pass exactly one of --peer, --room or --all")
+		}
+		if id != "" {
+			p, err := peer.Decode(id)
+			if err != nil {
+				return fmt.Errorf("invalid peer ID %q: %w", id, err)
+			}
+			opts.Peer = p
+		}
+
+		removed, err := app.Purge(opts)
+		out := cmd.OutOrStdout()
+		verb := "Removed"
+		if opts.DryRun {
+			verb = "Would remove"
+		}
+		for _, r := range removed {
+			fmt.Fprintf(out, "%s %s\n", verb, r)
+		}
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			switch {
+			case opts.Room != "" && len(opts.Transcripts)+len(opts.Recordings) == 0:
+				fmt.Fprintf(out, "Nothing stored for room %s; name any --transcript or --record files it was written to\n", opts.Room)
+			default:
+				fmt.Fprintln(out, "Nothing stored, nothing to remove")
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+	purgeCmd.Flags().String("peer", "", "remove what is stored about this peer ID")
+	purgeCmd.Flags().String("room", "", "remove what is stored about this room")
+	purgeCmd.Flags().Bool("all", false, "remove the address book, crash reports, scheduled messages, feed state and Nostr secret")
+	purgeCmd.Flags().Bool("dry-run", false, "only list what would be removed")
+	purgeCmd.Flags().StringSlice("transcript", nil, "also purge this transcript and its rotated parts (repeatable)")
+	purgeCmd.Flags().StringSlice("record", nil, "also purge this session recording (repeatable)")
+}
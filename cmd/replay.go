@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "Play back a session recorded with --record",
+	Long: `Play a recorded session back in this terminal with its original timing:
+what was shown and what was typed, and with --events the messages sent
+and received as well.
+Examples:
+  quichat run --nick alice --record demo.qcr
+  quichat replay demo.qcr --speed 4 --max-pause 2s`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		speed, _ := cmd.Flags().GetFloat64("speed")
+		maxPause, _ := cmd.Flags().GetDuration("max-pause")
+		events, _ := cmd.Flags().GetBool("events")
+		if speed <= 0 {
+			return fmt.Errorf("--speed must be positive")
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		opts := app.ReplayOptions{Speed: speed, MaxPause: maxPause, Events: events}
+		if err := app.Replay(cmd.Context(), f, cmd.OutOrStdout(), opts); err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().Float64("speed", 1, "playback speed; 2 is twice as fast as recorded")
+	replayCmd.Flags().Duration("max-pause", 0, "cut longer silences down to this, e.g. 2s (0 keeps them)")
+	replayCmd.Flags().Bool("events", false, "also show the messages sent and received")
+}
@@ -4,14 +4,16 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
 	"os"
 
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
 	"github.com/spf13/cobra"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "P2P_QUICHAT",
+	Use:   "quichat",
 	Short: "A brief description of your application",
 	Long: `A longer description that spans multiple lines and likely contains
 examples and usage of using your application. For example:
@@ -22,23 +24,48 @@ to quickly create a Cobra application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// the flags parsed; a failure from here on isn't about usage
+		cmd.SilenceUsage = true
+		if err := selectProfile(cmd); err != nil {
+			return err
+		}
+		lang, _ := cmd.Flags().GetString("lang")
+		a11y, _ := cmd.Flags().GetBool("a11y")
+		app.SetupTerminal(a11y)
+		return app.SetLanguage(lang)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.SilenceErrors = true
 	err := rootCmd.Execute()
 	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", app.Explain(err))
 		os.Exit(1)
 	}
 }
 
+// selectProfile applies --profile. Shell completions skip
+// PersistentPreRunE, so the ones that read state call it themselves.
+func selectProfile(cmd *cobra.Command) error {
+	name, _ := cmd.Flags().GetString("profile")
+	return app.SetProfile(name)
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.P2P_QUICHAT.yaml)")
+	rootCmd.PersistentFlags().String("config", "", "config file (default is $HOME/.quichat/config.json)")
+	rootCmd.PersistentFlags().String("profile", "", "keep config and state in ~/.quichat/profiles/<name> instead (default $QUICHAT_PROFILE)")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	rootCmd.PersistentFlags().String("lang", "", "UI language: en, es or hi (default from $LANG)")
+	rootCmd.RegisterFlagCompletionFunc("lang", cobra.FixedCompletions(app.Languages(), cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentFlags().Bool("a11y", false, "screen-reader-friendly output: no colours or redrawing, and \"From alice:\" before each message")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
 	"github.com/spf13/cobra"
@@ -14,32 +16,202 @@ var runCmd = &cobra.Command{
 	Long: `Start a chat node that connects over libp2p gossip-sub.
 Examples:
   quichat run --listen 4001 --nick alice
-  quichat run --listen 4003 --bootstrap /ip4/…/p2p/… --nick bob`,
+  quichat run --listen 4003 --bootstrap /ip4/…/p2p/… --nick bob
+  quichat run --listen 4005 --invite 3vQB7B6MrGQZaxCuFg4oh… --nick carol`,
 
 	// Only define RunE (or Run), not both
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
 		defer cancel()
 
-		port, _ := cmd.Flags().GetString("listen")
-		bootstrap, _ := cmd.Flags().GetString("bootstrap")
-		nick, _ := cmd.Flags().GetString("nick")
-
-		node, err := app.NewNode(ctx, nick, port, bootstrap)
+		opts, err := nodeOptions(cmd)
 		if err != nil {
 			return err
 		}
+		node, err := app.NewNode(ctx, opts)
+		if err != nil {
+			return err
+		}
+		defer node.Close()
 
-		return app.ChatLoop(ctx, node, nick)
+		return app.ChatLoop(ctx, node, node.Nick())
 	},
 }
 
 func init() {
 	// Add it exactly once
 	rootCmd.AddCommand(runCmd)
+	addNodeFlags(runCmd)
+}
+
+// addNodeFlags registers the flags shared by every command that joins the chat.
+func addNodeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("listen", "4001", "port to listen on (0 picks a free port)")
+	cmd.Flags().StringSlice("bootstrap", nil, "multiaddr of a bootstrap peer; repeat or separate with commas for several, all dialed at once")
+	cmd.Flags().String("nick", "", `display name (default: the room's identity in the config, else its "default" one, else "anon")`)
+	cmd.Flags().String("invite", "", "invite code from /invite (replaces --bootstrap)")
+	cmd.Flags().Bool("no-ipv6", false, "listen on IPv4 only")
+	cmd.Flags().BoolP("verbose", "v", false, "show loopback addresses and extra diagnostics")
+	cmd.Flags().BoolP("quiet", "q", false, "hide joins and connection notices (see /filter)")
+	cmd.Flags().String("allow-peers", "", "file of peer IDs; connections from anyone else are rejected")
+	cmd.Flags().String("pubsub-trace", "", "write gossipsub trace events to this file (protobuf if it ends in .pb, else JSON)")
+	cmd.Flags().String("pprof", "", "serve Go profiling endpoints on this address, e.g. localhost:6060")
+	cmd.Flags().String("wire", app.WireAuto, "message encoding: auto (protobuf once every peer supports it) or json")
+	cmd.Flags().String("proxy", "", "route outbound TCP dials through a SOCKS5 proxy, e.g. socks5://127.0.0.1:9050 (disables QUIC)")
+	cmd.Flags().Int("max-members", 0, "cap the room at this many members and hold later joiners in a lobby until /admit")
+	cmd.Flags().String("welcome", "", "message shown to peers waiting in the lobby (with --max-members)")
+	cmd.Flags().String("api", "", "serve POST /api/rooms/{room}/messages and, with --transcript, GET /api/history on this address, e.g. localhost:8081 (tokens from api.tokens in the config)")
+	cmd.Flags().String("transcript", "", "append every delivered message to this file as a signed, hash-chained log (see quichat transcript verify)")
+	cmd.Flags().String("transcript-max", "off", "keep the transcript within this size, e.g. 500M, by rotating it into parts and deleting the oldest")
+	cmd.Flags().String("record", "", "record the session (messages in and out, typed lines, output) to this file for quichat replay")
+	cmd.Flags().Bool("status-bar", true, "keep nick, room, peer count, reachability and unread counts on the terminal's bottom line")
+	cmd.Flags().String("tts", "", "read incoming messages aloud with the system speech synthesizer: all or mentions")
+	cmd.Flags().String("network", "", "keep to a separate network whose rooms only meet peers using the same name")
+	cmd.Flags().Bool("keep-key", false, "keep this room's keypair between runs, so peers can tell you from someone else taking your nick (also identities.<room>.keep_key in the config)")
+	cmd.Flags().Bool("legacy-topics", false, "join rooms under their readable topic names, to chat with clients from before topic hashing")
+	cmd.Flags().Bool("offline-lan", false, "stay on the local network: no DHT, relays or update check; find peers by LAN multicast and --bootstrap")
+	cmd.Flags().Duration("cover", 0, "send cover traffic: one fixed-size frame per interval, e.g. 500ms, padding with dummies")
+	cmd.RegisterFlagCompletionFunc("nick", completeNicks)
+	cmd.RegisterFlagCompletionFunc("wire", cobra.FixedCompletions([]string{app.WireAuto, app.WireJSON}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("tts", cobra.FixedCompletions([]string{app.TTSAll, app.TTSMentions}, cobra.ShellCompDirectiveNoFileComp))
+	addDialFlags(cmd)
+}
+
+// addDialFlags registers the dial timeout, bootstrap retry policy and
+// bandwidth limits.
+func addDialFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("dial-timeout", 0, "give up on a dial after this long (default 60s for the bootstrap peer, libp2p's own otherwise)")
+	cmd.Flags().Int("bootstrap-min", 1, "bootstrap peers that must connect before startup goes on (the rest keep dialing in the background)")
+	cmd.Flags().Int("bootstrap-retries", 0, "retry the bootstrap peer this many times before giving up")
+	cmd.Flags().Duration("bootstrap-backoff", 2*time.Second, "wait before the first bootstrap retry, doubled after each")
+	cmd.Flags().String("max-upload", "off", "cap what all connections send, e.g. 500K or 2M bytes a second (change with /throttle)")
+	cmd.Flags().String("max-download", "off", "cap what all connections receive, e.g. 500K or 2M bytes a second (change with /throttle)")
+}
 
-	// Flags
-	runCmd.Flags().String("listen", "4001", "port to listen on")
-	runCmd.Flags().String("bootstrap", "", "multiaddr of a bootstrap peer")
-	runCmd.Flags().String("nick", "anon", "display name")
+// dialOptions reads the flags from addDialFlags into opts.
+func dialOptions(cmd *cobra.Command, opts *app.Options) error {
+	opts.DialTimeout, _ = cmd.Flags().GetDuration("dial-timeout")
+	opts.BootstrapMin, _ = cmd.Flags().GetInt("bootstrap-min")
+	opts.BootstrapRetries, _ = cmd.Flags().GetInt("bootstrap-retries")
+	opts.BootstrapBackoff, _ = cmd.Flags().GetDuration("bootstrap-backoff")
+	up, _ := cmd.Flags().GetString("max-upload")
+	down, _ := cmd.Flags().GetString("max-download")
+	var err error
+	if opts.MaxUpload, err = app.ParseRate(up); err != nil {
+		return fmt.Errorf("--max-upload: %w", err)
+	}
+	if opts.MaxDownload, err = app.ParseRate(down); err != nil {
+		return fmt.Errorf("--max-download: %w", err)
+	}
+	switch {
+	case opts.DialTimeout < 0:
+		return fmt.Errorf("--dial-timeout must not be negative")
+	case opts.BootstrapMin < 1:
+		return fmt.Errorf("--bootstrap-min must be at least 1")
+	case opts.BootstrapRetries < 0:
+		return fmt.Errorf("--bootstrap-retries must not be negative")
+	case opts.BootstrapBackoff <= 0:
+		return fmt.Errorf("--bootstrap-backoff must be positive")
+	}
+	return nil
+}
+
+// nodeOptions turns the flags from addNodeFlags into app.Options.
+func nodeOptions(cmd *cobra.Command) (app.Options, error) {
+	port, _ := cmd.Flags().GetString("listen")
+	bootstrap, _ := cmd.Flags().GetStringSlice("bootstrap")
+	nick, _ := cmd.Flags().GetString("nick") // "" lets the node pick the room's identity
+	code, _ := cmd.Flags().GetString("invite")
+	noIPv6, _ := cmd.Flags().GetBool("no-ipv6")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	allowFile, _ := cmd.Flags().GetString("allow-peers")
+	cfgPath, _ := cmd.Flags().GetString("config")
+	proxyURL, _ := cmd.Flags().GetString("proxy")
+	trace, _ := cmd.Flags().GetString("pubsub-trace")
+	pprofAddr, _ := cmd.Flags().GetString("pprof")
+	wire, _ := cmd.Flags().GetString("wire")
+	maxMembers, _ := cmd.Flags().GetInt("max-members")
+	welcome, _ := cmd.Flags().GetString("welcome")
+	cover, _ := cmd.Flags().GetDuration("cover")
+	transcript, _ := cmd.Flags().GetString("transcript")
+	transcriptMax, _ := cmd.Flags().GetString("transcript-max")
+	apiAddr, _ := cmd.Flags().GetString("api")
+	tts, _ := cmd.Flags().GetString("tts")
+	statusBar, _ := cmd.Flags().GetBool("status-bar")
+	record, _ := cmd.Flags().GetString("record")
+	offlineLAN, _ := cmd.Flags().GetBool("offline-lan")
+	network, _ := cmd.Flags().GetString("network")
+	legacyTopics, _ := cmd.Flags().GetBool("legacy-topics")
+	keepKey, _ := cmd.Flags().GetBool("keep-key")
+	if maxMembers < 0 {
+		return app.Options{}, fmt.Errorf("--max-members must not be negative")
+	}
+	if cover < 0 {
+		return app.Options{}, fmt.Errorf("--cover must not be negative")
+	}
+	if wire != app.WireAuto && wire != app.WireJSON {
+		return app.Options{}, fmt.Errorf("--wire must be %s or %s, not %q", app.WireAuto, app.WireJSON, wire)
+	}
+	if err := app.ValidateNetwork(network); err != nil {
+		return app.Options{}, fmt.Errorf("--network %w", err)
+	}
+	maxBytes, err := app.ParseSize(transcriptMax)
+	if err != nil {
+		return app.Options{}, fmt.Errorf("--transcript-max: %w", err)
+	}
+	if tts != "" && tts != app.TTSAll && tts != app.TTSMentions {
+		return app.Options{}, fmt.Errorf("--tts must be %s or %s, not %q", app.TTSAll, app.TTSMentions, tts)
+	}
+
+	cfg, err := app.LoadConfig(cfgPath)
+	if err != nil {
+		return app.Options{}, fmt.Errorf("load config: %w", err)
+	}
+
+	opts := app.Options{
+		Nick:      nick,
+		Port:      port,
+		Bootstrap: bootstrap,
+		NoIPv6:    noIPv6,
+		Verbose:   verbose,
+		Quiet:     quiet,
+		Config:    cfg,
+		Proxy:     proxyURL,
+
+		PubsubTrace:   trace,
+		Pprof:         pprofAddr,
+		Wire:          wire,
+		MaxMembers:    maxMembers,
+		Welcome:       welcome,
+		Cover:         cover,
+		Transcript:    transcript,
+		TranscriptMax: maxBytes,
+		API:           apiAddr,
+		TTS:           tts,
+		StatusBar:     statusBar,
+		Record:        record,
+		OfflineLAN:    offlineLAN,
+		Network:       network,
+		LegacyTopics:  legacyTopics,
+		KeepKey:       keepKey,
+	}
+	if err := dialOptions(cmd, &opts); err != nil {
+		return app.Options{}, err
+	}
+	if allowFile != "" {
+		ids, err := app.LoadPeerList(allowFile)
+		if err != nil {
+			return app.Options{}, err
+		}
+		opts.AllowPeers = ids
+	}
+	if code != "" {
+		inv, err := app.ParseInvite(code)
+		if err != nil {
+			return app.Options{}, err
+		}
+		opts.Invite = &inv
+	}
+	return opts, nil
 }
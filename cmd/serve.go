@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a headless bootstrap/relay node",
+	Long: `Run a public node that other chat nodes can bootstrap from and relay
+through. It offers the circuit-relay service, runs the DHT in server mode and
+exposes health endpoints for systemd/Kubernetes probes:
+
+  /healthz  200 while the process is up
+  /readyz   200 once the DHT routing table has peers
+  /         status page with peer count, relay reservations and uptime
+
+Examples:
+  quichat serve --listen 4001
+  quichat serve --listen 4001 --http 127.0.0.1:8080`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer cancel()
+
+		port, _ := cmd.Flags().GetString("listen")
+		bootstrap, _ := cmd.Flags().GetStringSlice("bootstrap")
+		httpAddr, _ := cmd.Flags().GetString("http")
+		trace, _ := cmd.Flags().GetString("pubsub-trace")
+		pprofAddr, _ := cmd.Flags().GetString("pprof")
+		cfgPath, _ := cmd.Flags().GetString("config")
+
+		cfg, err := app.LoadConfig(cfgPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+
+		opts := app.Options{
+			Nick:      "serve",
+			Port:      port,
+			Bootstrap: bootstrap,
+			Config:    cfg,
+			Serve:     true,
+
+			PubsubTrace: trace,
+			Pprof:       pprofAddr,
+		}
+		if err := dialOptions(cmd, &opts); err != nil {
+			return err
+		}
+		node, err := app.NewNode(ctx, opts)
+		if err != nil {
+			return err
+		}
+		defer node.Close()
+
+		g, ctx := errgroup.WithContext(ctx)
+		if httpAddr != "" {
+			fmt.Printf("Health endpoints on http://%s/\n", httpAddr)
+			g.Go(func() error { return app.ServeHealth(ctx, httpAddr, node) })
+		}
+		g.Go(func() error {
+			<-ctx.Done()
+			return nil
+		})
+		return g.Wait()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("listen", "4001", "port to listen on (0 picks a free port)")
+	serveCmd.Flags().StringSlice("bootstrap", nil, "multiaddr of a bootstrap peer; repeat or separate with commas for several, all dialed at once")
+	serveCmd.Flags().String("pubsub-trace", "", "write gossipsub trace events to this file (protobuf if it ends in .pb, else JSON)")
+	serveCmd.Flags().String("pprof", "", "serve Go profiling endpoints on this address, e.g. localhost:6060")
+	addDialFlags(serveCmd)
+	serveCmd.Flags().String("http", ":8080", "address for /healthz, /readyz and the status page (empty disables)")
+}
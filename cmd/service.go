@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Install quichat daemon or serve mode as a systemd/launchd service",
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install [-- flags for the mode]",
+	Short: "Generate and start a user service",
+	Long: `Write a systemd user unit (Linux) or launchd agent (macOS) that runs this
+binary in daemon or serve mode, then enable and start it. Flags after "--" are
+passed to the mode; --config and --profile are carried over, and each profile
+gets a service of its own.
+Examples:
+  quichat service install -- --listen 4001 --nick alice
+  quichat service install --mode serve -- --listen 4001 --http :8080`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, err := service(cmd)
+		if err != nil {
+			return err
+		}
+		if cfgPath, _ := cmd.Flags().GetString("config"); cfgPath != "" {
+			abs, err := filepath.Abs(cfgPath)
+			if err != nil {
+				return err
+			}
+			svc.Args = append(svc.Args, "--config", abs)
+		}
+		if svc.Profile != "" {
+			svc.Args = append(svc.Args, "--profile", svc.Profile)
+		}
+		svc.Args = append(svc.Args, args...)
+
+		if err := svc.Install(); err != nil {
+			return err
+		}
+		path, _ := svc.Path()
+		fmt.Fprintf(cmd.OutOrStdout(), "Installed and started %s\n", path)
+		return nil
+	},
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the service is running",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, err := service(cmd)
+		if err != nil {
+			return err
+		}
+		return svc.Status(cmd.OutOrStdout())
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop the service and remove it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, err := service(cmd)
+		if err != nil {
+			return err
+		}
+		if err := svc.Uninstall(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Service removed")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(serviceInstallCmd, serviceStatusCmd, serviceUninstallCmd)
+	serviceCmd.PersistentFlags().String("mode", "daemon", "which mode the service runs: daemon or serve")
+	serviceCmd.RegisterFlagCompletionFunc("mode", cobra.FixedCompletions([]string{"daemon", "serve"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// service builds the app.Service selected by --mode.
+func service(cmd *cobra.Command) (app.Service, error) {
+	mode, _ := cmd.Flags().GetString("mode")
+	if mode != "daemon" && mode != "serve" {
+		return app.Service{}, fmt.Errorf("unknown mode %q (want daemon or serve)", mode)
+	}
+	return app.Service{Mode: mode, Profile: app.Profile()}, nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print a running daemon's status and exit",
+	Long: `Ask the daemon for its uptime, room, peer count, NAT status, traffic
+totals and outbox depth. It exits non-zero when no daemon is running, so
+scripts and monitoring can use it as a check.
+Examples:
+  quichat status
+  quichat status --json | jq .members`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sock, err := socketPath(cmd)
+		if err != nil {
+			return err
+		}
+		st, err := app.QueryStatus(cmd.Context(), sock)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(st)
+		}
+		fmt.Fprint(out, st.Report())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().String("socket", "", "unix socket of the daemon (default ~/.quichat/daemon.sock)")
+	statusCmd.Flags().Bool("json", false, "print the status as JSON")
+}
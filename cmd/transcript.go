@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var transcriptCmd = &cobra.Command{
+	Use:   "transcript",
+	Short: "Work with transcripts written by --transcript",
+}
+
+var transcriptVerifyCmd = &cobra.Command{
+	Use:   "verify <file>...",
+	Short: "Check a transcript for edited, reordered or missing lines",
+	Long: `Verify the hash chain of a transcript, the signature of the node that
+recorded each line and the signature of the peer that sent it. A transcript
+rotated by --transcript-max is checked by listing its parts oldest first;
+starting from a later part verifies from there on. Lines removed from the
+end of the file can't be detected, so note the entry count reported here
+somewhere safe.
+Examples:
+  quichat run --transcript meeting.log
+  quichat transcript verify meeting.log
+  quichat transcript verify meeting.log.2 meeting.log.1 meeting.log`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var readers []io.Reader
+		for _, name := range args {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			readers = append(readers, f)
+		}
+		name := strings.Join(args, " ")
+		rep, err := app.VerifyTranscript(io.MultiReader(readers...))
+		if err != nil {
+			return fmt.Errorf("%s: %w (%d entries before it verified)", name, err, rep.Entries)
+		}
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "%s: %d entries verified, recorded by:\n", name, rep.Entries)
+		for _, p := range rep.Recorders {
+			fmt.Fprintf(out, "  %s\n", p)
+		}
+		if rep.First > 1 {
+			fmt.Fprintf(out, "Starts at entry %d: the parts before it were deleted or not given\n", rep.First)
+		}
+		for _, g := range rep.Gaps {
+			fmt.Fprintf(out, "Gap: %s\n", g)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(transcriptCmd)
+	transcriptCmd.AddCommand(transcriptVerifyCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ViciousEagle03/P2P_QUICHAT/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update quichat to the latest signed release",
+	Long: `Download the release manifest, verify its ed25519 signature and replace
+this binary with the newer build for your platform.
+Examples:
+  quichat update --check
+  quichat update`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checkOnly, _ := cmd.Flags().GetBool("check")
+		cfgPath, _ := cmd.Flags().GetString("config")
+
+		cfg, err := app.LoadConfig(cfgPath)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		rel, err := app.FetchRelease(cmd.Context(), cfg.Updates)
+		if err != nil {
+			return fmt.Errorf("check for updates: %w", err)
+		}
+		out := cmd.OutOrStdout()
+		if !rel.Newer() {
+			fmt.Fprintf(out, "Quichat %s is up to date (latest %s)\n", app.Version, rel.Version)
+			return nil
+		}
+		if checkOnly {
+			fmt.Fprintf(out, "Quichat %s is available (you have %s)\n", rel.Version, app.Version)
+			return nil
+		}
+		if err := app.SelfUpdate(cmd.Context(), rel); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		fmt.Fprintf(out, "Updated to %s\n", rel.Version)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.Version = app.Version
+
+	updateCmd.Flags().Bool("check", false, "only report whether an update is available")
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// accessible selects output a screen reader can follow: no colours, no
+// cursor movement or redrawing, and chat lines that say who is speaking.
+// SetupTerminal sets it.
+var accessible bool
+
+// stripEscapes removes ANSI escape sequences from s.
+func stripEscapes(s string) string {
+	if !strings.Contains(s, "\x1b[") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// termText returns s as it should reach the terminal: without escapes
+// where colour is off.
+func termText(s string) string {
+	if !tty.color {
+		return stripEscapes(s)
+	}
+	return s
+}
+
+// plainMessage is the linear form of a chat line for --a11y:
+//
+//	From alice at 15:04:05: text (#id, note)
+//
+// Further lines of text follow on lines of their own.
+func plainMessage(at time.Time, nick, id, note, text string, mentioned bool) string {
+	format := T("From %s at %s: %s")
+	if mentioned {
+		format = T("Mention from %s at %s: %s")
+	}
+	line := fmt.Sprintf(format, nick, at.Format("15:04:05"), text)
+	var extra []string
+	if id != "" {
+		extra = append(extra, "#"+id)
+	}
+	if note != "" {
+		extra = append(extra, note)
+	}
+	if len(extra) > 0 {
+		line += " (" + strings.Join(extra, ", ") + ")"
+	}
+	return line
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	addrBookFile     = "addrbook.json"
+	addrBookMaxAge   = 30 * 24 * time.Hour // forget peers not seen for a month
+	addrBookInterval = 5 * time.Minute
+	addrBookDials    = 8 // known peers dialed at startup
+)
+
+// AddrBookEntry is what we remember about one peer.
+type AddrBookEntry struct {
+	Addrs    []string  `json:"addrs"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// AddrBook maps peer IDs to their last known addresses. It is persisted
+// between runs and can be exported to seed another machine's peerstore.
+type AddrBook map[peer.ID]AddrBookEntry
+
+// AddrBookPath is the default location of the persisted address book.
+func AddrBookPath() (string, error) { return statePath(addrBookFile) }
+
+// LoadAddrBook reads an address book file; a missing file is an empty book.
+func LoadAddrBook(path string) (AddrBook, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return AddrBook{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAddrBook(f)
+}
+
+// ReadAddrBook decodes an address book from r.
+func ReadAddrBook(r io.Reader) (AddrBook, error) {
+	b := AddrBook{}
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Write encodes the book as indented JSON.
+func (b AddrBook) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// Save writes the book to path.
+func (b AddrBook) Save(path string) error {
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes(), 0o600)
+}
+
+// Merge folds other into b, keeping the most recently seen entry per peer.
+// It returns how many peers were added or updated.
+func (b AddrBook) Merge(other AddrBook) int {
+	changed := 0
+	for id, e := range other {
+		if old, ok := b[id]; ok && !e.LastSeen.After(old.LastSeen) {
+			continue
+		}
+		b[id] = e
+		changed++
+	}
+	return changed
+}
+
+// prune drops entries not seen since before cutoff.
+func (b AddrBook) prune(cutoff time.Time) {
+	for id, e := range b {
+		if e.LastSeen.Before(cutoff) {
+			delete(b, id)
+		}
+	}
+}
+
+// recent returns up to max peers, most recently seen first.
+func (b AddrBook) recent(max int) []peer.AddrInfo {
+	ids := make([]peer.ID, 0, len(b))
+	for id := range b {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(x, y peer.ID) int {
+		return b[y].LastSeen.Compare(b[x].LastSeen)
+	})
+	var out []peer.AddrInfo
+	for _, id := range ids {
+		if len(out) == max {
+			break
+		}
+		if info, ok := b.addrInfo(id); ok {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+func (b AddrBook) addrInfo(id peer.ID) (peer.AddrInfo, bool) {
+	info := peer.AddrInfo{ID: id}
+	for _, s := range b[id].Addrs {
+		if a, err := ma.NewMultiaddr(s); err == nil {
+			info.Addrs = append(info.Addrs, a)
+		}
+	}
+	return info, len(info.Addrs) > 0
+}
+
+// addrBook persists the peerstore across restarts.
+type addrBook struct {
+	mu   sync.Mutex
+	path string
+	book AddrBook
+}
+
+// initAddrBook seeds the peerstore from disk, dials a few recently seen
+// peers in the background and keeps the file updated while we run.
+func (n *Node) initAddrBook() error {
+	path, err := AddrBookPath()
+	if err != nil {
+		return err
+	}
+	book, err := LoadAddrBook(path)
+	if err != nil {
+		return err
+	}
+	n.addrBook = &addrBook{path: path, book: book}
+
+	ps := n.Host.Peerstore()
+	for id := range book {
+		if info, ok := book.addrInfo(id); ok && id != n.Host.ID() {
+			ps.AddAddrs(id, info.Addrs, peerstore.AddressTTL)
+		}
+	}
+	go n.dialKnownPeers(book.recent(addrBookDials))
+	go n.persistAddrBook()
+	return nil
+}
+
+// dialKnownPeers connects to previously seen peers; failures are expected
+// (peers move or go offline) and silently ignored.
+func (n *Node) dialKnownPeers(infos []peer.AddrInfo) {
+	for _, info := range infos {
+		go func() {
+			ctx, cancel := context.WithTimeout(n.ctx, 20*time.Second)
+			defer cancel()
+			_ = n.Host.Connect(ctx, info)
+		}()
+	}
+}
+
+// persistAddrBook snapshots connected peers to disk periodically and once
+// more on shutdown.
+func (n *Node) persistAddrBook() {
+	ticker := time.NewTicker(addrBookInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			n.saveAddrBook()
+			return
+		case <-ticker.C:
+			n.saveAddrBook()
+		}
+	}
+}
+
+func (n *Node) saveAddrBook() {
+	ab := n.addrBook
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	now := time.Now().UTC()
+	ps := n.Host.Peerstore()
+	for _, id := range n.Host.Network().Peers() {
+		var addrs []string
+		for _, a := range ps.Addrs(id) {
+			addrs = append(addrs, a.String())
+		}
+		if len(addrs) > 0 {
+			ab.book[id] = AddrBookEntry{Addrs: addrs, LastSeen: now}
+		}
+	}
+	ab.book.prune(now.Add(-addrBookMaxAge))
+	_ = ab.book.Save(ab.path)
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// alias returns the lines an alias expands to.
+func (c *Config) alias(name string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines, ok := c.Aliases[name]
+	return lines, ok
+}
+
+// expandAlias turns "/name args…" into the alias' lines, appending args to
+// the last one so "/b 10.0.0.1" with b → "/banip" becomes "/banip 10.0.0.1".
+func expandAlias(lines, args []string) []string {
+	out := slices.Clone(lines)
+	if len(args) > 0 && len(out) > 0 {
+		out[len(out)-1] += " " + strings.Join(args, " ")
+	}
+	return out
+}
+
+// runAlias implements /alias: list, show or define.
+//
+//	/alias
+//	/alias brb be right back ; /list
+func (c *Chat) runAlias(args []string) {
+	cfg := c.n.cfg
+	if len(args) == 0 {
+		c.ui.Print(aliasReport(cfg))
+		return
+	}
+	name := strings.ToLower(strings.TrimPrefix(args[0], "/"))
+	if slices.Contains(builtinCommands, name) {
+		c.ui.Print(fmt.Sprintf(T("/%s is a built-in command"), name))
+		return
+	}
+	if len(args) == 1 {
+		if lines, ok := cfg.alias(name); ok {
+			c.ui.Print(fmt.Sprintf("/%s → %s", name, strings.Join(lines, " ; ")))
+		} else {
+			c.ui.Print(fmt.Sprintf(T("No alias /%s"), name))
+		}
+		return
+	}
+
+	var lines []string
+	for _, l := range strings.Split(strings.Join(args[1:], " "), ";") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	err := cfg.Update(func(cfg *Config) {
+		if cfg.Aliases == nil {
+			cfg.Aliases = make(map[string][]string)
+		}
+		cfg.Aliases[name] = lines
+	})
+	if err != nil {
+		c.ui.Print(fmt.Sprintf(T("Saving alias failed: %v"), err))
+		return
+	}
+	c.ui.Print(fmt.Sprintf("/%s → %s", name, strings.Join(lines, " ; ")))
+}
+
+// runUnalias implements /unalias <name>.
+func (c *Chat) runUnalias(args []string) {
+	if len(args) != 1 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/unalias <name>"))
+		return
+	}
+	name := strings.ToLower(strings.TrimPrefix(args[0], "/"))
+	if _, ok := c.n.cfg.alias(name); !ok {
+		c.ui.Print(fmt.Sprintf(T("No alias /%s"), name))
+		return
+	}
+	if err := c.n.cfg.Update(func(cfg *Config) { delete(cfg.Aliases, name) }); err != nil {
+		c.ui.Print(fmt.Sprintf(T("Removing alias failed: %v"), err))
+		return
+	}
+	c.ui.Print(fmt.Sprintf(T("Removed /%s"), name))
+}
+
+func aliasReport(cfg *Config) string {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	if len(cfg.Aliases) == 0 {
+		return T("No aliases defined")
+	}
+	names := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(T("Aliases:"))
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  /%s → %s", name, strings.Join(cfg.Aliases[name], " ; "))
+	}
+	return b.String()
+}
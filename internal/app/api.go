@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiMaxBody      = 64 << 10 // bytes per posted message
+	apiHistoryLimit = 100      // entries per history page unless ?limit= says otherwise
+	apiHistoryMax   = 1000
+)
+
+// APIConfig holds the bearer tokens accepted by the --api endpoint.
+type APIConfig struct {
+	Tokens []string `json:"tokens,omitempty"`
+}
+
+type apiPost struct {
+	Text string `json:"text"`
+}
+
+// apiHistoryEntry is one message in a history page.
+type apiHistoryEntry struct {
+	Seq  uint64    `json:"seq"`
+	At   time.Time `json:"at"`
+	Room string    `json:"room"`
+	From string    `json:"from"`
+	ID   string    `json:"id,omitempty"`
+	Nick string    `json:"nick"`
+	Text string    `json:"text"`
+}
+
+type apiHistoryPage struct {
+	Messages []apiHistoryEntry `json:"messages"`
+	// Next is passed back as ?after= for the following page; absent on the last.
+	Next uint64 `json:"next,omitempty"`
+}
+
+// serveAPI runs the HTTP endpoint that lets other systems post into the
+// room and read what the node recorded until ctx is cancelled:
+//
+//	POST /api/rooms/{room}/messages  {"text": "…"}  →  202 {"id": "…"}
+//	GET  /api/history?room=&since=&q=&after=&limit=  →  200 {"messages": […], "next": n}
+//
+// Requests need "Authorization: Bearer <token>" with one of api.tokens from
+// the config. Lines go out under this node's nick, like typed ones.
+func (c *Chat) serveAPI(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/rooms/{room}/messages", func(w http.ResponseWriter, r *http.Request) {
+		if !c.n.cfg.apiAuthorized(r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or unknown bearer token", http.StatusUnauthorized)
+			return
+		}
+		if room := r.PathValue("room"); room != c.n.room {
+			http.Error(w, fmt.Sprintf("this node is in room %q, not %q", c.n.room, room), http.StatusNotFound)
+			return
+		}
+		var post apiPost
+		if err := json.NewDecoder(io.LimitReader(r.Body, apiMaxBody)).Decode(&post); err != nil {
+			http.Error(w, "body must be JSON like {\"text\": \"…\"}", http.StatusBadRequest)
+			return
+		}
+		text := strings.TrimSpace(post.Text)
+		if text == "" {
+			http.Error(w, "text is empty", http.StatusBadRequest)
+			return
+		}
+		m, err := c.post(text)
+		switch {
+		case errors.Is(err, errInLobby):
+			http.Error(w, "this node is "+err.Error(), http.StatusForbidden)
+			return
+		case errors.Is(err, errTooLong):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"id": m.ID})
+	})
+	mux.HandleFunc("GET /api/history", func(w http.ResponseWriter, r *http.Request) {
+		if !c.n.cfg.apiAuthorized(r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or unknown bearer token", http.StatusUnauthorized)
+			return
+		}
+		if c.n.transcript == nil {
+			http.Error(w, "this node keeps no history: start it with --transcript <file>", http.StatusNotFound)
+			return
+		}
+		q, err := parseHistoryQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entries, more, err := c.n.transcript.history(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page := apiHistoryPage{Messages: make([]apiHistoryEntry, 0, len(entries))}
+		for _, e := range entries {
+			page.Messages = append(page.Messages, apiHistoryEntry{
+				Seq: e.Seq, At: e.At, Room: e.Room, From: e.From.String(), ID: e.ID, Nick: e.Nick, Text: e.Text,
+			})
+		}
+		if more {
+			page.Next = entries[len(entries)-1].Seq
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// parseHistoryQuery reads the history filters. since is an RFC 3339 time or
+// a duration back from now, such as 24h; q is matched word by word,
+// ignoring case, against nick and text.
+func parseHistoryQuery(v url.Values) (historyQuery, error) {
+	q := historyQuery{Room: v.Get("room"), Terms: strings.Fields(strings.ToLower(v.Get("q"))), Limit: apiHistoryLimit}
+	if s := v.Get("since"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil {
+			q.Since = time.Now().Add(-d)
+		} else if q.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, fmt.Errorf("since must be an RFC 3339 time or a duration like 24h")
+		}
+	}
+	if s := v.Get("after"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("after must be the next value of a previous page")
+		}
+		q.After = n
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return q, fmt.Errorf("limit must be a positive number")
+		}
+		q.Limit = min(n, apiHistoryMax)
+	}
+	return q, nil
+}
+
+// apiAuthorized reports whether an Authorization header carries one of the
+// configured tokens.
+func (c *Config) apiAuthorized(header string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range c.API.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
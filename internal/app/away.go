@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const awayKeep = 1000 // messages kept for /catchup; the count goes on past it
+
+// awayLog counts what arrives while no terminal is attached to the daemon,
+// so the next one to attach hears what it missed. The latest absence in
+// which anything arrived stays available to /catchup.
+type awayLog struct {
+	mu   sync.Mutex
+	away bool
+	cur  awaySpan // the absence under way, while away
+	last awaySpan // the latest absence that had messages
+}
+
+type awaySpan struct {
+	since, until time.Time
+	count        int
+	mentions     int
+	nicks        map[string]int
+	kept         []mention
+}
+
+// leave starts a new absence.
+func (a *awayLog) leave(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.away = true
+	a.cur = awaySpan{since: now, nicks: make(map[string]int)}
+}
+
+// add records a delivered chat line if we are away.
+func (a *awayLog) add(m mention, mentioned bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.away {
+		return
+	}
+	s := &a.cur
+	s.count++
+	s.nicks[m.Nick]++
+	if mentioned {
+		s.mentions++
+	}
+	if len(s.kept) < awayKeep {
+		s.kept = append(s.kept, m)
+	}
+}
+
+// back ends the absence and returns the summary to show, or "" if nothing
+// arrived.
+func (a *awayLog) back(room string, now time.Time) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.away {
+		return ""
+	}
+	a.away = false
+	s := a.cur
+	if s.count == 0 {
+		return ""
+	}
+	s.until = now
+	a.last = s
+
+	nicks := make([]string, 0, len(s.nicks))
+	for n := range s.nicks {
+		nicks = append(nicks, n)
+	}
+	slices.SortFunc(nicks, func(x, y string) int { return s.nicks[y] - s.nicks[x] })
+	if len(nicks) > 3 {
+		nicks = append(nicks[:3], "…")
+	}
+	return ansiMention + fmt.Sprintf(T("While you were away (%s): %d messages in %s from %s, %d mentions; /catchup to read them"),
+		now.Sub(s.since).Round(time.Minute), s.count, room, strings.Join(nicks, ", "), s.mentions) + ansiReset
+}
+
+// report lists the messages of the latest absence for /catchup.
+func (a *awayLog) report(nick string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.last
+	if s.count == 0 {
+		return T("Nothing to catch up on: /catchup covers the time no terminal was attached to quichat daemon")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Missed between %s and %s (%d):"),
+		s.since.Local().Format("2006-01-02 15:04"), s.until.Local().Format("15:04"), s.count)
+	for _, m := range s.kept {
+		line := fmt.Sprintf("[%s] <%s> %s", m.At.Local().Format("15:04:05"), m.Nick, m.Text)
+		if m.Nick != nick && mentions(m.Text, nick) {
+			line = ansiMention + line + ansiReset
+		}
+		b.WriteString("\n  " + line)
+	}
+	if more := s.count - len(s.kept); more > 0 {
+		b.WriteString("\n  " + fmt.Sprintf(T("…and %d more not kept"), more))
+	}
+	return b.String()
+}
@@ -0,0 +1,234 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// A backup is a gzipped tar of the state files, sealed with
+// XChaCha20-Poly1305 under a key derived from a passphrase with scrypt:
+//
+//	"QCBK1\n" | salt (16) | nonce (24) | ciphertext
+//
+// The header is authenticated along with the contents, so a backup that was
+// tampered with or given the wrong passphrase fails as a whole.
+const (
+	backupMagic    = "QCBK1\n"
+	backupSaltLen  = 16
+	backupMaxBytes = 256 << 20 // refuse to decrypt anything larger
+
+	transcriptDir = "transcripts" // where transcripts go inside a backup
+)
+
+// backupFiles are the state files a backup carries: settings and
+// identities, peers, reputations (verified and blocked peers included),
+// nick bindings, pending /schedule messages, feed state and the Nostr
+// bridge secret. The room keys kept with --keep-key come along from
+// keysDir. Crash reports and the daemon socket are left out.
+var backupFiles = []string{
+	configFile, addrBookFile, reputationFile, tofuFile, scheduleFile, feedsFile, nostrKeyFile,
+}
+
+// backupKey derives the sealing key for salt from pass.
+func backupKey(pass, salt []byte) ([]byte, error) {
+	return scrypt.Key(pass, salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+}
+
+// CreateBackup writes an encrypted backup of the state directory to w,
+// together with the transcripts at the given paths and their rotated
+// parts. It returns one line per item included.
+func CreateBackup(w io.Writer, pass []byte, transcripts []string) ([]string, error) {
+	var (
+		included []string
+		buf      bytes.Buffer
+	)
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(name, src string) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		included = append(included, fmt.Sprintf("%s (%s)", src, formatSize(int64(len(data)))))
+		return err
+	}
+	for _, name := range backupFiles {
+		src, err := statePath(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(name, src); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	keys, err := statePath(keysDir)
+	if err != nil {
+		return nil, err
+	}
+	found, _ := filepath.Glob(filepath.Join(keys, "*.key"))
+	for _, src := range found {
+		if err := add(path.Join(keysDir, filepath.Base(src)), src); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range transcripts {
+		for _, src := range append([]string{t}, transcriptParts(t)...) {
+			if err := add(path.Join(transcriptDir, filepath.Base(src)), src); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(backupMagic)+backupSaltLen+chacha20poly1305.NonceSizeX)
+	copy(header, backupMagic)
+	if _, err := rand.Read(header[len(backupMagic):]); err != nil {
+		return nil, err
+	}
+	salt := header[len(backupMagic) : len(backupMagic)+backupSaltLen]
+	nonce := header[len(backupMagic)+backupSaltLen:]
+	key, err := backupKey(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(aead.Seal(header, nonce, buf.Bytes(), header)); err != nil {
+		return nil, err
+	}
+	return included, nil
+}
+
+// RestoreOptions says where RestoreBackup puts what it unpacks.
+type RestoreOptions struct {
+	TranscriptDir string // where transcripts are written, "" for the current directory
+	Force         bool   // overwrite files that already exist
+}
+
+// RestoreBackup decrypts a backup made by CreateBackup and writes its files
+// back: state files into the state directory, transcripts into
+// opts.TranscriptDir. Unless opts.Force is set it refuses to replace
+// anything, and checks that before writing a single file. It returns one
+// line per file restored.
+func RestoreBackup(r io.Reader, pass []byte, opts RestoreOptions) ([]string, error) {
+	sealed, err := io.ReadAll(io.LimitReader(r, backupMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	hlen := len(backupMagic) + backupSaltLen + chacha20poly1305.NonceSizeX
+	switch {
+	case len(sealed) > backupMaxBytes:
+		return nil, fmt.Errorf("backup is larger than %s", formatSize(backupMaxBytes))
+	case len(sealed) < hlen || string(sealed[:len(backupMagic)]) != backupMagic:
+		return nil, errors.New("not a quichat backup")
+	}
+	header := sealed[:hlen]
+	key, err := backupKey(pass, header[len(backupMagic):len(backupMagic)+backupSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, header[len(backupMagic)+backupSaltLen:], sealed[hlen:], header)
+	if err != nil {
+		return nil, errors.New("wrong passphrase, or the backup is damaged")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		dst  string
+		data []byte
+	}
+	var files []file
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		dst, err := restorePath(hdr.Name, opts.TranscriptDir)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, backupMaxBytes))
+		if err != nil {
+			return nil, err
+		}
+		if !opts.Force {
+			if _, err := os.Stat(dst); err == nil {
+				return nil, fmt.Errorf("%s already exists; restore with --force to replace it", dst)
+			}
+		}
+		files = append(files, file{dst, data})
+	}
+
+	var restored []string
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.dst), 0o700); err != nil {
+			return restored, err
+		}
+		if err := writeFileAtomic(f.dst, f.data, 0o600); err != nil {
+			return restored, err
+		}
+		restored = append(restored, fmt.Sprintf("%s (%s)", f.dst, formatSize(int64(len(f.data)))))
+	}
+	return restored, nil
+}
+
+// restorePath maps a name inside a backup to where it is restored. Only
+// the names CreateBackup writes are accepted, so a crafted backup cannot
+// write anywhere else.
+func restorePath(name, transcripts string) (string, error) {
+	if dir, base, ok := strings.Cut(name, "/"); ok && (dir == transcriptDir || dir == keysDir) {
+		if base == "" || base != filepath.Base(base) || base == "." || base == ".." {
+			return "", fmt.Errorf("unexpected file %q in backup", name)
+		}
+		if dir == keysDir {
+			return statePath(filepath.Join(keysDir, base))
+		}
+		if transcripts == "" {
+			transcripts = "."
+		}
+		return filepath.Join(transcripts, base), nil
+	}
+	for _, f := range backupFiles {
+		if name == f {
+			return statePath(f)
+		}
+	}
+	return "", fmt.Errorf("unexpected file %q in backup", name)
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+const (
+	benchTopic   = "quichat:bench"
+	benchSettle  = 2 * time.Second  // let the gossipsub mesh form before publishing
+	benchTimeout = 30 * time.Second // per message size
+)
+
+// BenchOptions configures RunBench.
+type BenchOptions struct {
+	Nodes    int   // nodes in the mocknet, one of which publishes
+	Messages int   // messages published per size
+	Sizes    []int // message text sizes in bytes
+	Proto    bool  // use the protobuf envelope instead of JSON
+}
+
+// BenchResult is the outcome for one message size.
+type BenchResult struct {
+	Size       int
+	Delivered  int // deliveries across all receivers
+	Expected   int
+	Elapsed    time.Duration
+	P50, P99   time.Duration
+	WireBytes  int // encoded size of one message
+	MsgsPerSec float64
+}
+
+// RunBench publishes Messages chat messages of each size from one node of an
+// in-process mocknet and measures how fast and how late they reach the
+// others. It exercises the real gossipsub stack and message envelope, minus
+// the network.
+func RunBench(ctx context.Context, opts BenchOptions, out io.Writer) ([]BenchResult, error) {
+	if opts.Nodes < 2 {
+		return nil, fmt.Errorf("need at least 2 nodes, got %d", opts.Nodes)
+	}
+	mn, err := mocknet.FullMeshConnected(opts.Nodes)
+	if err != nil {
+		return nil, fmt.Errorf("mocknet: %w", err)
+	}
+	defer mn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	topics := make([]*pubsub.Topic, 0, opts.Nodes)
+	subs := make([]*pubsub.Subscription, 0, opts.Nodes)
+	for _, h := range mn.Hosts() {
+		ps, err := pubsub.NewGossipSub(ctx, h,
+			pubsub.WithPeerOutboundQueueSize(4*opts.Messages),
+			pubsub.WithValidateQueueSize(4*opts.Messages))
+		if err != nil {
+			return nil, err
+		}
+		t, err := ps.Join(benchTopic)
+		if err != nil {
+			return nil, err
+		}
+		s, err := t.Subscribe(pubsub.WithBufferSize(opts.Messages))
+		if err != nil {
+			return nil, err
+		}
+		topics, subs = append(topics, t), append(subs, s)
+	}
+	envelope := "JSON"
+	if opts.Proto {
+		envelope = "protobuf"
+	}
+	fmt.Fprintf(out, "%d nodes, %d messages per size, %s envelope\n", opts.Nodes, opts.Messages, envelope)
+	select {
+	case <-time.After(benchSettle):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var results []BenchResult
+	for _, size := range opts.Sizes {
+		r, err := benchSize(ctx, topics[0], subs[1:], size, opts)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r)
+		fmt.Fprintf(out, "%7d B (%7d on the wire): %8.0f msg/s, p50 %v, p99 %v, delivered %d/%d\n",
+			r.Size, r.WireBytes, r.MsgsPerSec, r.P50.Round(time.Microsecond), r.P99.Round(time.Microsecond), r.Delivered, r.Expected)
+	}
+	return results, nil
+}
+
+// benchSize runs one round: pub sends, every sub counts and times deliveries.
+func benchSize(ctx context.Context, pub *pubsub.Topic, subs []*pubsub.Subscription, size int, opts BenchOptions) (BenchResult, error) {
+	r := BenchResult{Size: size, Expected: opts.Messages * len(subs)}
+	text := strings.Repeat("x", size)
+	run := makeID() // tells this round's messages from stragglers of the last
+
+	ctx, cancel := context.WithTimeout(ctx, benchTimeout)
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, r.Expected)
+		last      time.Time
+		wg        sync.WaitGroup
+	)
+	for _, s := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for got := 0; got < opts.Messages; {
+				msg, err := s.Next(ctx)
+				if err != nil {
+					return
+				}
+				m, err := decodeMessage(msg.Data)
+				if err != nil || m.ID != run {
+					continue
+				}
+				now := time.Now()
+				mu.Lock()
+				latencies = append(latencies, now.Sub(m.Ts))
+				last = now
+				mu.Unlock()
+				got++
+			}
+		}()
+	}
+
+	start := time.Now()
+	for i := 0; i < opts.Messages; i++ {
+		m := Message{ID: run, Nick: "bench", Text: text, Ts: time.Now().UTC(), Seq: uint64(i + 1)}
+		b, err := encodeMessage(m, opts.Proto)
+		if err != nil {
+			return r, err
+		}
+		r.WireBytes = len(b)
+		if err := pub.Publish(ctx, b); err != nil {
+			return r, err
+		}
+	}
+	wg.Wait()
+
+	r.Delivered = len(latencies)
+	if r.Delivered == 0 {
+		return r, fmt.Errorf("no messages of %d bytes delivered within %v", size, benchTimeout)
+	}
+	r.Elapsed = last.Sub(start)
+	r.MsgsPerSec = float64(opts.Messages) / r.Elapsed.Seconds()
+	slices.Sort(latencies)
+	r.P50 = latencies[len(latencies)*50/100]
+	r.P99 = latencies[len(latencies)*99/100]
+	return r, nil
+}
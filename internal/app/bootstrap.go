@@ -0,0 +1,153 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+const (
+	dnsaddrDepth   = 4                // dnsaddr records may point at further dnsaddr records
+	resolveTimeout = 30 * time.Second // for all TXT lookups of one bootstrap address
+)
+
+// resolveBootstrap turns a --bootstrap multiaddr into the peers to try, in
+// random order so that nodes sharing one hostname spread over its peers.
+//
+// A /dnsaddr/ address is looked up here, since it names peers rather than a
+// host; its records may list several peers and need no /p2p/ suffix. /dns4/,
+// /dns6/ and /dns/ addresses need a /p2p/ suffix and are left to libp2p,
+// which resolves them when dialing and tries every address returned.
+func resolveBootstrap(ctx context.Context, s string) ([]peer.AddrInfo, error) {
+	maddr, err := ma.NewMultiaddr(s)
+	if err != nil {
+		return nil, invalidAddr("bootstrap", s, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs := []ma.Multiaddr{maddr}
+	for depth := 0; depth < dnsaddrDepth; depth++ {
+		var next []ma.Multiaddr
+		resolved := false
+		for _, a := range addrs {
+			if !isDNSAddr(a) {
+				next = append(next, a)
+				continue
+			}
+			rs, err := madns.DefaultResolver.Resolve(ctx, a)
+			if err != nil {
+				return nil, fmt.Errorf("resolve bootstrap %q: %w", s, err)
+			}
+			next = append(next, rs...)
+			resolved = true
+		}
+		addrs = next
+		if !resolved {
+			break
+		}
+	}
+
+	var withID []ma.Multiaddr
+	for _, a := range addrs {
+		if _, err := peer.IDFromP2PAddr(a); err == nil && !isDNSAddr(a) {
+			withID = append(withID, a)
+		}
+	}
+	if len(withID) == 0 {
+		if isDNSAddr(maddr) {
+			return nil, fmt.Errorf("bootstrap %q: no dnsaddr records with a /p2p/ peer ID", s)
+		}
+		return nil, invalidAddr("bootstrap", s, errors.New("missing /p2p/ peer ID"))
+	}
+	infos, err := peer.AddrInfosFromP2pAddrs(withID...)
+	if err != nil {
+		return nil, invalidAddr("bootstrap", s, err)
+	}
+	rand.Shuffle(len(infos), func(i, j int) { infos[i], infos[j] = infos[j], infos[i] })
+	return infos, nil
+}
+
+func isDNSAddr(a ma.Multiaddr) bool {
+	c, _ := ma.SplitFirst(a)
+	return c != nil && c.Protocol().Code == ma.P_DNSADDR
+}
+
+// resolveBootstraps resolves every --bootstrap address into one list of
+// peers. An address that fails to resolve is skipped while others remain.
+func (n *Node) resolveBootstraps() ([]peer.AddrInfo, error) {
+	var (
+		infos    []peer.AddrInfo
+		seen     = make(map[peer.ID]bool)
+		firstErr error
+	)
+	for _, s := range n.bootstrapAddrs {
+		found, err := resolveBootstrap(n.ctx, s)
+		if err != nil {
+			if errors.Is(err, ErrInvalidAddr) {
+				return nil, err // a typo, not a lookup that may work next time
+			}
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		for _, info := range found {
+			if !seen[info.ID] {
+				seen[info.ID] = true
+				infos = append(infos, info)
+			}
+		}
+	}
+	if len(infos) == 0 {
+		return nil, firstErr
+	}
+	return infos, nil
+}
+
+// dialAll dials each of infos not yet connected, all at once, and returns
+// when need of them are connected or every dial has finished. Dials still
+// running when dialAll returns carry on and simply add connections. err is
+// the last dial error.
+func (n *Node) dialAll(ctx context.Context, infos []peer.AddrInfo, need int, timeout time.Duration) (connected int, err error) {
+	results := make(chan error, len(infos)) // never blocks the dials left behind
+	dialing := 0
+	for _, info := range infos {
+		if n.Host.Network().Connectedness(info.ID) == network.Connected {
+			connected++
+			continue
+		}
+		dialing++
+		go func() {
+			dialCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results <- n.Host.Connect(dialCtx, info)
+		}()
+	}
+	for ; dialing > 0 && connected < need; dialing-- {
+		if e := <-results; e != nil {
+			err = e
+			continue
+		}
+		connected++
+	}
+	return connected, err
+}
+
+// firstConnected returns the first of infos, in the order given, that we
+// are connected to. It names our bootstrap peer, so that which one that is
+// doesn't depend on which dial happened to finish first.
+func (n *Node) firstConnected(infos []peer.AddrInfo) peer.ID {
+	for _, info := range infos {
+		if n.Host.Network().Connectedness(info.ID) == network.Connected {
+			return info.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	bridgeMaxText    = 4000             // bytes of an MQTT payload posted to the room
+	bridgeQueue      = 256              // room lines waiting to be published to MQTT
+	bridgeRetry      = 2 * time.Second  // first reconnect delay, doubled after each failure
+	bridgeMaxBackoff = 60 * time.Second // reconnect delay cap
+)
+
+// MQTTBridgeOptions configures RunMQTTBridge.
+type MQTTBridgeOptions struct {
+	Broker    string   // mqtt:// or mqtts:// URL, with user:password@ if needed
+	Subscribe []string // MQTT topic filters whose messages are posted to the room
+	Publish   string   // MQTT topic the room's chat lines go to; "" keeps them in the room
+	ClientID  string
+}
+
+// lineUI writes each block to w; the bridge has no prompt to draw around.
+type lineUI struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (u *lineUI) Print(s string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	fmt.Fprintln(u.w, termText(s))
+}
+
+// RunMQTTBridge joins the room as nick and relays between it and an MQTT
+// broker until ctx is cancelled: payloads on the subscribed topics are
+// posted to the room as "[topic] payload", and other peers' chat lines are
+// published to opts.Publish as JSON, in the webhook format. The broker
+// connection is retried with backoff whenever it drops.
+func RunMQTTBridge(ctx context.Context, n *Node, nick string, opts MQTTBridgeOptions, out io.Writer) error {
+	if opts.ClientID == "" {
+		id := n.Host.ID().String()
+		opts.ClientID = "quichat-" + id[len(id)-8:]
+	}
+	ui := &lineUI{w: out}
+	chat := NewChat(n, nick, ui)
+	lines := make(chan webhookEvent, bridgeQueue)
+	if opts.Publish != "" {
+		self := n.Host.ID()
+		chat.tap = func(a arrival) {
+			if a.src == nil || a.src.GetFrom() == self {
+				return // our own lines came from MQTT in the first place
+			}
+			select {
+			case lines <- webhookEvent{Room: n.room, ID: a.m.ID, From: a.src.GetFrom().String(), Nick: a.m.Nick, Text: a.m.Text, Ts: a.m.Ts}:
+			default:
+			}
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error { return chat.Run(ctx) })
+	g.Go(guard("mqtt", func() error {
+		backoff := bridgeRetry
+		for {
+			err := chat.bridgeMQTT(ctx, opts, lines)
+			if ctx.Err() != nil {
+				return nil
+			}
+			ui.Print(ansiWarn + fmt.Sprintf(T("MQTT: %v; reconnecting in %s"), err, backoff) + ansiReset)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, bridgeMaxBackoff)
+		}
+	}))
+	return g.Wait()
+}
+
+// bridgeMQTT runs one broker session.
+func (c *Chat) bridgeMQTT(ctx context.Context, opts MQTTBridgeOptions, lines <-chan webhookEvent) error {
+	client, err := dialMQTT(ctx, opts.Broker, opts.ClientID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.subscribe(opts.Subscribe); err != nil {
+		return err
+	}
+	c.ui.Print(fmt.Sprintf(T("MQTT: connected to %s"), brokerHost(opts.Broker)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-lines:
+				b, _ := json.Marshal(ev)
+				if client.publish(opts.Publish, b) != nil {
+					return // run notices the broken connection
+				}
+			}
+		}
+	}()
+	return client.run(ctx, func(m mqttMessage) {
+		text := strings.TrimSpace(string(m.Payload))
+		if len(text) > bridgeMaxText {
+			text = text[:bridgeMaxText]
+			for !utf8.ValidString(text) {
+				text = text[:len(text)-1]
+			}
+			text += "…"
+		}
+		if text == "" {
+			return
+		}
+		if _, err := c.post(fmt.Sprintf("[%s] %s", m.Topic, text)); err != nil {
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("MQTT: message on %s not posted: %v"), m.Topic, err) + ansiReset)
+		}
+	})
+}
+
+// brokerHost names a broker in notices without its credentials.
+func brokerHost(broker string) string {
+	if _, rest, ok := strings.Cut(broker, "@"); ok {
+		return rest
+	}
+	if _, rest, ok := strings.Cut(broker, "://"); ok {
+		return rest
+	}
+	return broker
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Capability bits announced in JOIN heartbeats, so peers can tell what a
+// client understands before relying on it. Bits are only ever added.
+const (
+	capProtobuf  uint64 = 1 << iota // reads the protobuf envelope
+	capPins                         // merges __PIN__ announcements
+	capLobby                        // follows room rosters (--max-members)
+	capFiles                        // receives file transfers
+	capDMs                          // end-to-end encrypted direct messages
+	capReactions                    // shows reactions to messages
+	capCover                        // drops cover traffic dummies (--cover)
+	capEdits                        // shows /s corrections as edits
+	capStableKey                    // keeps its key between runs (--keep-key), so its nick can be bound
+	capLeave                        // says __LEAVE__ when it quits
+)
+
+// ourCaps is what this client supports.
+const ourCaps = capProtobuf | capPins | capLobby | capCover | capEdits | capLeave
+
+// protocolVersion is the room protocol this client speaks, announced in
+// JOINs next to the capability bits.
+const protocolVersion = "1.0"
+
+var capNames = []struct {
+	bit  uint64
+	name string
+}{
+	{capProtobuf, "protobuf envelope"},
+	{capPins, "pins"},
+	{capLobby, "waiting room"},
+	{capFiles, "files"},
+	{capDMs, "e2e DMs"},
+	{capReactions, "reactions"},
+	{capCover, "cover traffic"},
+	{capEdits, "message corrections"},
+	{capStableKey, "stable key"},
+	{capLeave, "leave notices"},
+}
+
+// caps is what this node announces: ourCaps, and capStableKey with
+// --keep-key.
+func (n *Node) caps() uint64 {
+	if n.keepKey {
+		return ourCaps | capStableKey
+	}
+	return ourCaps
+}
+
+// supports reports whether p announced every bit of want. Peers we have not
+// heard from, or that predate capability announcements, support nothing.
+func (pr *presence) supports(p peer.ID, want uint64) bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	e, ok := pr.seen[p]
+	return ok && e.caps&want == want
+}
+
+// resolve finds the one announced peer whose nick, nick~tag or peer ID
+// (prefix) is name, and returns it with its label.
+func (pr *presence) resolve(name string) (peer.ID, string, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return pr.resolveLocked(name)
+}
+
+func (pr *presence) resolveLocked(name string) (peer.ID, string, error) {
+	var found []peer.ID
+	var labels []string
+	for p, e := range pr.seen {
+		if named(name, p, e.nick) || slices.Contains(e.former, name) {
+			found = append(found, p)
+			labels = append(labels, pr.labelLocked(p, e.nick, ""))
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", "", fmt.Errorf(T("No peer called %s has announced itself"), name)
+	case 1:
+		return found[0], labels[0], nil
+	}
+	return "", "", ambiguous(name, labels)
+}
+
+// whois renders what we know about the peer whose nick or peer ID (prefix)
+// is name, for /whois.
+func (pr *presence) whois(name string) (string, peer.ID) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	p, label, err := pr.resolveLocked(name)
+	if err != nil {
+		return err.Error(), ""
+	}
+
+	e := pr.seen[p]
+	var b strings.Builder
+	fmt.Fprintf(&b, T("%s is %s, last heard %s ago"), label, p, time.Since(e.at).Round(time.Second))
+	if len(e.former) > 0 {
+		fmt.Fprintf(&b, "\n  "+T("Formerly known as: %s"), strings.Join(e.former, ", "))
+	}
+	if e.version == "" {
+		b.WriteString("\n  " + T("Capabilities: unknown (client predates capability announcements)"))
+		return b.String(), p
+	}
+	fmt.Fprintf(&b, "\n  "+T("Protocol: %s"), e.version)
+	b.WriteString("\n  " + T("Capabilities:"))
+	for _, c := range capNames {
+		mark := "✗"
+		if e.caps&c.bit != 0 {
+			mark = "✓"
+		}
+		fmt.Fprintf(&b, " %s %s", mark, T(c.name))
+	}
+	return b.String(), p
+}
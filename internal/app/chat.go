@@ -4,216 +4,632 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/chzyer/readline"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+// inboxSize is how many received messages may wait for the renderer.
+const inboxSize = 256
+
 type Message struct {
-	Nick string    `json:"nick"`
-	Text string    `json:"text"`
-	Ts   time.Time `json:"ts"`
+	ID      string    `json:"id,omitempty"`
+	Nick    string    `json:"nick"`
+	Text    string    `json:"text"`
+	Ts      time.Time `json:"ts"`
+	Seq     uint64    `json:"seq,omitempty"`   // per-sender chat line counter, from 1
+	Clock   uint64    `json:"clock,omitempty"` // sender's Lamport time
+	Wire    string    `json:"wire,omitempty"`  // on JOINs: "pb" if the sender reads protobuf
+	Caps    uint64    `json:"caps,omitempty"`  // on JOINs: the sender's capability bits
+	Version string    `json:"ver,omitempty"`   // sender's protocol version, "major.minor"
+	Pad     string    `json:"pad,omitempty"`   // filler that hides the length with --cover
+	Edit    string    `json:"edit,omitempty"`  // ID of our earlier message this one corrects
+
+	// Trace is the W3C traceparent of the publish span, set only when the
+	// sender exports OpenTelemetry traces.
+	Trace string `json:"trace,omitempty"`
 }
 
-const helpText = `Available commands:
-/help           Show this help
-/quit           Leave the chat
-/list           Show peers currently in the room
-/ping           Measure round-trip latency to all peers`
-
-var pingOutstanding = make(map[string]time.Time) // id → timestamp
-
 func makeID() string { // tiny UUID
 	b := make([]byte, 8)
 	rand.Read(b)
 	return hex.EncodeToString(b)
 }
 
-// ChatLoop runs two goroutines: one to receive messages and one to send.
-func ChatLoop(ctx context.Context, n *Node, nick string) error {
-	// single shared readline instance
-	rl, err := readline.New("> ")
-	if err != nil {
-		return fmt.Errorf("init readline: %w", err)
+// UI is where the chat engine sends everything the user should see.
+// The terminal and attached daemon clients are the two implementations.
+type UI interface {
+	// Print shows a block of text above the input prompt.
+	Print(s string)
+}
+
+// Chat is the chat engine: it renders room traffic to a UI and turns user
+// input into published messages and slash commands.
+type Chat struct {
+	n        *Node
+	own      ownNick
+	ui       UI
+	mentions mentionIndex
+	pins     pinBoard
+	filters  filters
+	activity activity
+	presence presence
+	lobby    lobby
+	pings    pingTracker
+	order    reorderer
+	versions versionNotices
+	seq      atomic.Uint64
+	clock    lamport
+	inbox    chan *pubsub.Message // received, waiting to be rendered
+	dropped  atomic.Int64         // messages dropped from a full inbox
+	outbox   chan Message         // typed, waiting to be published
+	control  chan Message         // with --cover: control messages waiting for a frame
+	held     atomic.Int64         // lines runOutbox holds back until someone is in the room
+	unsent   unsentList
+	last     lastSent
+	sched    schedule
+	away     awayLog
+	storm    storm
+
+	transcriptFailed atomic.Bool       // reported the first failed transcript write
+	webhooks         chan webhookEvent // delivered lines waiting to be posted; nil without webhooks
+	tap              func(arrival)     // if set, sees every delivered chat line (bridges)
+	translations     chan translateJob // waiting for the translation backend; nil without one
+	autoTranslate    atomic.Bool       // translate every incoming message (/translate auto)
+	speech           chan string       // lines waiting to be read aloud; nil without --tts
+	unread           atomic.Int64      // messages shown since the user last typed, for the status bar
+	unreadMentions   atomic.Int64
+	statusWake       chan struct{}
+}
+
+// NewChat creates a chat engine for n that reports to ui.
+func NewChat(n *Node, nick string, ui UI) *Chat {
+	c := &Chat{
+		n: n, ui: ui,
+		inbox:  make(chan *pubsub.Message, inboxSize),
+		outbox: make(chan Message, outboxSize),
+	}
+	if n.cover > 0 {
+		c.control = make(chan Message, controlQueue)
+	}
+	c.own.set(nick)
+	if n.record != nil {
+		c.ui = recordUI{ui, n.record}
 	}
-	ctx, cancel := context.WithCancel(ctx)
-	defer rl.Close()
+	c.sched.wake = make(chan struct{}, 1)
+	c.statusWake = make(chan struct{}, 1)
+	c.filters.hideJoins, c.filters.hideNotices = n.quiet, n.quiet
+	if len(n.cfg.webhookList()) > 0 {
+		c.webhooks = make(chan webhookEvent, webhookQueue)
+	}
+	if n.maxMembers > 0 {
+		c.lobby.open(n.Host.ID(), n.maxMembers, n.welcome)
+	}
+	if tc := n.cfg.translation(); tc.URL != "" {
+		c.translations = make(chan translateJob, translateQueue)
+		c.autoTranslate.Store(slices.Contains(tc.Auto, n.room))
+	}
+	if n.tts != "" {
+		c.speech = make(chan string, ttsQueue)
+	}
+	return c
+}
 
+// Run announces us to the room and renders incoming messages and node
+// notices until ctx is cancelled.
+func (c *Chat) Run(ctx context.Context) error {
+	if err := c.n.PubSub.RegisterTopicValidator(c.n.topic, c.validate); err != nil {
+		return err
+	}
+	defer c.n.PubSub.UnregisterTopicValidator(c.n.topic)
 	g, ctx := errgroup.WithContext(ctx)
 
-	announceJoinWhenReady(ctx, n, nick) // ← new
-	fmt.Printf("\r\033[1;32m*** %s joined the chat ***\033[0m\n> ", nick)
+	c.ui.Print("\033[1;32m" + fmt.Sprintf(T("*** %s joined the chat ***"), c.myNick()) + "\033[0m")
 
 	// ─── Receiver ───────────────────────────────────────────────────────────────
-	g.Go(func() error {
+	// The reader never waits for the renderer, so a stalled terminal can't
+	// back up gossipsub; when the inbox is full the oldest message goes.
+	g.Go(guard("receiver", func() error {
+		defer close(c.inbox)
 		for {
-			msg, err := n.Sub.Next(ctx)
+			msg, err := c.n.Sub.Next(ctx)
 			if err != nil {
+				if ctx.Err() != nil {
+					return nil // leaving, not failing
+				}
 				return err
 			}
-			var m Message
-			if err := json.Unmarshal(msg.Data, &m); err != nil {
-				continue
-			}
-
-			if strings.HasPrefix(m.Text, "__PING__") {
-				if m.Nick == nick { // ← ignore your own ping
-					continue
-				}
-				// reply with PONG
-				resp := Message{
-					Nick: nick,
-					Text: "__PONG__" + m.Text[8:], // copy the ID
-					Ts:   time.Now().UTC(),
+			select {
+			case c.inbox <- msg:
+			default:
+				select {
+				case <-c.inbox:
+					c.dropped.Add(1)
+				default:
 				}
-				b, _ := json.Marshal(resp)
-				_ = n.Topic.Publish(ctx, b)
-				continue // swallow; don’t print as chat
+				c.inbox <- msg // we are the only sender, so there is room now
 			}
+		}
+	}))
+	g.Go(guard("renderer", func() error {
+		for msg := range c.inbox {
+			c.deliver(ctx, msg)
+		}
+		return nil
+	}))
 
-			// 2. PONG  ──────────────────────────────────────────────────────────────
-			if strings.HasPrefix(m.Text, "__PONG__") {
-				if m.Nick == nick {
-					continue
-				} // ignore your own PONG
+	// ─── Sender ────────────────────────────────────────────────────────────────
+	outbox := c.runOutbox
+	if c.n.cover > 0 {
+		outbox = c.runCoverOutbox
+	}
+	g.Go(guard("outbox", func() error { return outbox(ctx) }))
 
-				id := m.Text[8:]
-				if t0, ok := pingOutstanding[id]; ok {
-					dur := time.Since(t0).Milliseconds()
-					delete(pingOutstanding, id)
+	if c.n.apiAddr != "" {
+		g.Go(guard("api", func() error { return c.serveAPI(ctx, c.n.apiAddr) }))
+		c.ui.Print(fmt.Sprintf(T("Accepting messages at http://%s/api/rooms/%s/messages"), c.n.apiAddr, c.n.room))
+		if c.n.transcript != nil {
+			c.ui.Print(fmt.Sprintf(T("Serving the transcript at http://%s/api/history"), c.n.apiAddr))
+		}
+	}
+	g.Go(guard("scheduler", func() error { return c.runScheduler(ctx) }))
+	g.Go(guard("storm", func() error { return c.runStorm(ctx) }))
+	if feeds := c.n.cfg.feedList(); len(feeds) > 0 {
+		g.Go(guard("feeds", func() error { return c.runFeeds(ctx, feeds) }))
+	}
+	if c.translations != nil {
+		g.Go(guard("translate", func() error { return c.runTranslations(ctx) }))
+	}
+	if ui, ok := c.ui.(statusUI); ok && c.n.statusBar {
+		g.Go(guard("status", func() error { return c.runStatus(ctx, ui) }))
+	}
+	if c.speech != nil {
+		if argv, err := speaker(); err != nil {
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("Text to speech is off: %v"), err) + ansiReset)
+		} else {
+			g.Go(guard("tts", func() error { return c.runSpeech(ctx, argv) }))
+		}
+	}
+	if hooks := c.n.cfg.webhookList(); len(hooks) > 0 {
+		g.Go(guard("webhooks", func() error { return c.runWebhooks(ctx, hooks) }))
+	}
 
-					rl.Write([]byte(fmt.Sprintf(
-						"\r\033[36mPong from %s: %d ms\033[0m\n> ", m.Nick, dur)))
-				}
-				continue // swallow even if no match
-			}
+	// ─── Presence ──────────────────────────────────────────────────────────────
+	g.Go(guard("presence", func() error { return c.announcePresence(ctx) }))
+	g.Go(guard("alone", func() error { return c.warnAlone(ctx) }))
 
-			if m.Text == "__JOIN__" {
-				if m.Nick != nick { // skip your own copy
-					rl.Write([]byte(
-						fmt.Sprintf("\r\033[1;32m*** %s joined the chat ***\033[0m\n> ", m.Nick)))
+	// ─── Notices ────────────────────────────────────────────────────────────────
+	g.Go(guard("notices", func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case line := <-c.n.Notices():
+				if !c.filters.showNotice() {
+					continue
 				}
-				continue
+				c.ui.Print(fmt.Sprintf("\033[33m%s\033[0m", line))
 			}
+		}
+	}))
 
-			// Replace newlines with \n
-			m.Text = strings.ReplaceAll(m.Text, "\n", "\n» ")
+	err := g.Wait()
+	c.sayGoodbye(ctx)
+	return err
+}
 
-			// Erase the current input line ("> <whatever>")
-			rl.Write([]byte("\x1b[2K\r"))
+// shutdownTimeout bounds what the chat still sends once it is over: the
+// lines left in the outbox and the LEAVE.
+const shutdownTimeout = 2 * time.Second
+
+// sayGoodbye tells the room we are leaving, so the others see it now rather
+// than when our heartbeats stop counting. ctx is the chat's, already done.
+func (c *Chat) sayGoodbye(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if len(c.n.Topic.ListPeers()) > 0 {
+		_ = c.send(ctx, Message{Nick: c.myNick(), Text: leaveText, Ts: time.Now().UTC()})
+	}
+}
 
-			// Print chip-stack message with leading "> "
-			delivered := time.Now()
-			outStr := fmt.Sprintf(
-				"> [%s] [%s]\n» %s\n\n",
-				delivered.Format("2006-01-02 15:04:05"),
-				"\033[32m"+m.Nick+"\033[0m",
-				m.Text,
-			)
-			rl.Write([]byte(outStr))
+// noPeersAfter is how long an empty room is left before warnAlone says so.
+const noPeersAfter = 30 * time.Second
 
-			// Re-draw the prompt
-			rl.Write([]byte(rl.Config.Prompt))
-		}
-	})
+// warnAlone prints a hint if nobody else has joined the room noPeersAfter
+// after we did.
+func (c *Chat) warnAlone(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(noPeersAfter):
+	}
+	if len(c.n.Topic.ListPeers()) == 0 {
+		c.ui.Print(ansiWarn + Explain(c.n.noPeers(noPeersAfter)) + ansiReset)
+	}
+	return nil
+}
 
-	// ─── Sender ─────────────────────────────────────────────────────────────────
-	g.Go(func() error {
-		for {
-			line, err := rl.Readline()
-			if err == readline.ErrInterrupt {
-				continue // re-prompt on Ctrl+C
-			} else if err != nil {
-				// Exit on EOF or other errors
-				return err
-			}
+// deliver decodes and handles one pubsub message; a panic while doing so
+// costs only that message.
+func (c *Chat) deliver(ctx context.Context, msg *pubsub.Message) {
+	defer c.recoverPanic("incoming message")
 
-			if strings.HasPrefix(line, "/") {
-				cmd := strings.ToLower(strings.TrimSpace(line[1:]))
+	if c.n.reputation.blocked(msg.GetFrom()) {
+		return // banned after pubsub validated it
+	}
+	m, err := decodeMessage(msg.Data)
+	if errors.Is(err, errWireVersion) && c.versions.refuse(msg.GetFrom()) {
+		c.ui.Print("\033[31m" + fmt.Sprintf(T("Can't read messages from %s: they use a newer message format; run quichat update"),
+			shortID(msg.GetFrom())) + ansiReset)
+	}
+	if err != nil {
+		return
+	}
+	// every field that may end up on screen, before anything looks at them
+	m.Nick = sanitize(m.Nick, false)
+	m.ID, m.Edit = sanitize(m.ID, false), sanitize(m.Edit, false)
+	m.Version, m.Wire = sanitize(m.Version, false), sanitize(m.Wire, false)
+	if c.refuseIncompatible(m, msg.GetFrom()) {
+		return
+	}
+	c.n.record.add(recEvent{Kind: recRecv, From: msg.GetFrom().String(), Msg: &m})
+	_, span := tracer.Start(withTraceParent(ctx, m.Trace), "chat.receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("msg.id", m.ID),
+			attribute.String("peer.from", msg.GetFrom().String()),
+			attribute.Int("msg.bytes", len(msg.Data)),
+		))
+	defer span.End()
+	c.receive(ctx, m, msg)
+}
 
-				switch cmd {
-				case "list":
-					rl.Write([]byte("\x1b[2K\r"))
-					peers := n.Topic.ListPeers()
-					fmt.Fprintf(rl.Stdout(), "Peers (%d): %v\n", len(peers), peers)
-					rl.Write([]byte(rl.Config.Prompt))
-					continue
+// receive handles one decoded room message.
+func (c *Chat) receive(ctx context.Context, m Message, msg *pubsub.Message) {
+	from := msg.GetFrom()
+	if m.Text == coverText {
+		return
+	}
+	if !c.lobby.allows(from) && !lobbyMayPost(m) {
+		return // validated before the roster changed
+	}
+	mine := from == c.n.Host.ID() // by peer ID: someone else may share our nick
+	if old, ok := c.presence.renamed(from, m.Nick); ok && !mine {
+		c.n.reputation.seen(from, m.Nick, time.Now())
+		if c.filters.showJoin() {
+			c.ui.Print("\033[1;32m" + fmt.Sprintf(T("*** %s is now known as %s ***"), old, c.presence.label(from, m.Nick, c.myNick())) + ansiReset)
+		}
+	}
+	if strings.HasPrefix(m.Text, "__PING__") {
+		id, ok := controlArg(m.Text, "__PING__", maxIDLen)
+		if !ok || mine { // ← ignore your own ping
+			return
+		}
+		c.publish(ctx, "__PONG__"+id) // reply with PONG, copying the ID
+		return                        // swallow; don’t print as chat
+	}
 
-				case "ping":
-					id := makeID()
-					pingOutstanding[id] = time.Now()
+	// 2. PONG  ──────────────────────────────────────────────────────────────
+	if strings.HasPrefix(m.Text, "__PONG__") {
+		id, ok := controlArg(m.Text, "__PONG__", maxIDLen)
+		if !ok || mine {
+			return
+		} // ignore your own PONG
+
+		if rtt, ok, done := c.pings.reply(id, from, time.Now()); ok {
+			c.ui.Print("\033[36m" + fmt.Sprintf(T("Pong from %s: %d ms"), c.presence.label(from, m.Nick, c.myNick()), rtt.Milliseconds()) + "\033[0m")
+			if done {
+				c.finishPing(id)
+			}
+		}
+		return // swallow even if no match
+	}
 
-					payload, _ := json.Marshal(Message{
-						Nick: nick, Text: "__PING__" + id, Ts: time.Now().UTC(),
-					})
-					_ = n.Topic.Publish(ctx, payload)
-					continue
+	if strings.HasPrefix(m.Text, pinPrefix) {
+		pins, err := decodePins(m.Text)
+		if err != nil {
+			return
+		}
+		if added := c.pins.merge(pins); added > 0 {
+			c.ui.Print("\033[1;33m" + fmt.Sprintf(T("*** %s pinned %d message(s), /pins to list ***"), m.Nick, added) + "\033[0m")
+		}
+		return
+	}
 
-				case "help", "h", "?":
-					// Print help without killing the prompt
-					rl.Write([]byte("\x1b[2K\r")) // clear current line
-					fmt.Fprintln(rl.Stdout(), helpText)
-					rl.Write([]byte(rl.Config.Prompt))
-					continue
+	if strings.HasPrefix(m.Text, rosterPrefix) {
+		c.rosterFrom(from, m.Text)
+		return
+	}
 
-				case "quit", "exit", "q":
-					fmt.Fprintln(rl.Stdout(), "👋  Bye!")
-					cancel()   // cancel ctx → both goroutines exit
-					return nil // close sender
+	if m.Text == leaveText {
+		if mine {
+			return
+		}
+		if who, ok := c.presence.left(from); ok && c.filters.showJoin() {
+			c.ui.Print("\033[1;32m" + fmt.Sprintf(T("*** %s left the chat ***"), who) + "\033[0m")
+		}
+		return
+	}
 
-				default:
-					rl.Write([]byte("\x1b[2K\r"))
-					fmt.Fprintf(rl.Stdout(), "Unknown command: %s\n", cmd)
-					rl.Write([]byte(rl.Config.Prompt))
-					continue
-				}
+	if m.Text == joinText {
+		// skip your own copy and heartbeats from peers we already know
+		if !mine {
+			c.n.reputation.seen(from, m.Nick, time.Now())
+		}
+		if !mine && c.presence.arrived(from, m, time.Now()) {
+			who := c.presence.label(from, m.Nick, c.myNick())
+			if who != m.Nick {
+				c.ui.Print(ansiWarn + fmt.Sprintf(T("Several peers go by %s; this one is shown as %s"), m.Nick, who) + ansiReset)
 			}
-
-			rl.Write([]byte("\x1b[1A\x1b[2K\r"))
-
-			payload, err := json.Marshal(
-				Message{Nick: nick, Text: line, Ts: time.Now().UTC()},
-			)
-			if err != nil {
-				return err
+			if c.filters.showJoin() {
+				c.ui.Print("\033[1;32m" + fmt.Sprintf(T("*** %s joined the chat ***"), who) + "\033[0m")
 			}
-			if err := n.Topic.Publish(ctx, payload); err != nil {
-				return err
+			c.suggestUpgrade()
+			if c.lobby.arrived(from, m.Nick) {
+				c.ui.Print("\033[1;33m" + fmt.Sprintf(T("*** %s is waiting in the lobby (/admit %s) ***"), who, who) + ansiReset)
+				if !c.presence.supports(from, capLobby) {
+					// its own client won't hold its messages back; ours still drop them
+					c.ui.Print(ansiWarn + fmt.Sprintf(T("%s runs a client without waiting rooms and won't be told to wait"), who) + ansiReset)
+				}
+			}
+			// tell the newcomer whether it may speak
+			c.announceRoster(ctx)
+			// bring the newcomer's pin board up to date
+			if pins := c.pins.all(); len(pins) > 0 {
+				c.publish(ctx, encodePins(pins))
 			}
 		}
-	})
+		if !mine {
+			c.impostor(from, m.Nick) // binds the nick once the JOIN told us about the key
+		}
+		return
+	}
+
+	if !mine {
+		throttled, started := c.n.reputation.message(from, m.Nick, time.Now())
+		if started {
+			until, _ := c.n.reputation.throttled(from, time.Now())
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("Hiding messages from %s until %s: too many too fast"),
+				c.presence.label(from, m.Nick, c.myNick()), until.Local().Format("15:04")) + ansiReset)
+		}
+		if throttled {
+			return
+		}
+	}
 
-	return g.Wait()
+	c.clock.witness(m.Clock)
+	m.Text = sanitize(m.Text, true)
+	c.order.push(from, arrival{m: m, at: time.Now(), src: msg}, func(a arrival) {
+		defer c.recoverPanic("incoming message") // may run on the reorder timer
+		c.show(a, from)
+	})
 }
 
-func announceJoinWhenReady(ctx context.Context, n *Node, nick string) {
-	// fire exactly once
-	var once sync.Once
-	go func() {
-		ticker := time.NewTicker(250 * time.Millisecond)
-		defer ticker.Stop()
+// show renders one chat message once the reorderer releases it.
+func (c *Chat) show(a arrival, from peer.ID) {
+	m, delivered := a.m, a.at
+	if c.n.transcript != nil {
+		if err := c.n.transcript.record(c.n.room, a); err != nil && !c.transcriptFailed.Swap(true) {
+			c.ui.Print("\033[31m" + fmt.Sprintf(T("Transcript not written: %v"), err) + ansiReset)
+		}
+	}
+	c.forwardWebhook(a)
+	if c.tap != nil {
+		c.tap(a)
+	}
+	c.pins.remember(m)
+	// shown is m as displayed: under a nick~tag label when the nick is shared
+	mine, shown := from == c.n.Host.ID(), m
+	if !mine {
+		shown.Nick = c.presence.label(from, m.Nick, c.myNick())
+	}
+	c.activity.add(shown.Nick, delivered)
+	nickColor := ansiNick
+	mentioned := !mine && c.mentionsMe(m.Text)
+	if mentioned {
+		c.mentions.add(mention{At: delivered, Room: c.n.room, From: from, Nick: shown.Nick, Text: m.Text})
+		nickColor = ansiMention // highlight messages that name us
+	}
+	if !mine {
+		c.away.add(mention{At: delivered, Room: c.n.room, From: from, Nick: shown.Nick, Text: m.Text}, mentioned)
+	}
+	if !mine && !c.filters.showMessage(m.Nick, from, mentioned) {
+		return
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				// Wait until we see at least one other peer in this topic
-				if len(n.Topic.ListPeers()) > 0 {
-					once.Do(func() {
-						join := Message{Nick: nick, Text: "__JOIN__", Ts: time.Now().UTC()}
-						if b, _ := json.Marshal(join); b != nil {
-							_ = n.Topic.Publish(ctx, b)
-						}
-					})
-					return
+	// keep RTL text from dragging the chip-stack chrome along with it;
+	// both return their argument untouched for everything else
+	id := shortMsgID(m.ID)
+	if m.Edit != "" {
+		id += " " + fmt.Sprintf(T("corrects #%s"), shortMsgID(m.Edit))
+	}
+	text := capShown(m.Text)
+	note := skewNote(m.Ts, delivered)
+	if !mine && c.impostor(from, m.Nick) {
+		note = strings.TrimSuffix(fmt.Sprintf(T("not the %s first seen here"), m.Nick)+"; "+note, "; ")
+	}
+	if !mine && !c.storm.admit(delivered) {
+		c.storm.fold(foldedMsg{at: delivered, nick: shown.Nick, nickColor: nickColor,
+			id: id, note: note, text: text, mentioned: mentioned})
+		c.unread.Add(1)
+		if mentioned {
+			c.unreadMentions.Add(1)
+		}
+		c.refreshStatus()
+		return
+	}
+	if accessible {
+		c.ui.Print(plainMessage(delivered, shown.Nick, id, note, text, mentioned))
+	} else {
+		bp := renderBufs.Get().(*[]byte)
+		*bp = appendMessage((*bp)[:0], delivered, nickColor, isolateRTL(shown.Nick),
+			id, note, isolateRTL(text))
+		c.ui.Print(string(*bp))
+		renderBufs.Put(bp)
+	}
+	if !mine {
+		c.unread.Add(1)
+		if mentioned {
+			c.unreadMentions.Add(1)
+		}
+		c.refreshStatus()
+	}
+	c.autoTranslateMessage(shown)
+	c.speak(shown, mentioned)
+}
+
+// Handle processes one line of user input: a slash command, an alias or a
+// chat message. It reports quit when the user asked to leave.
+func (c *Chat) Handle(ctx context.Context, line string) (quit bool, err error) {
+	defer c.recoverPanic("command")
+	c.n.record.add(recEvent{Kind: recInput, Text: line})
+	c.markRead()
+
+	if fields := strings.Fields(line); len(fields) > 0 && strings.HasPrefix(fields[0], "/") {
+		name := strings.ToLower(fields[0][1:])
+		if lines, ok := c.n.cfg.alias(name); ok && !slices.Contains(builtinCommands, name) {
+			// expansions run as typed; aliases don't nest
+			for _, l := range expandAlias(lines, fields[1:]) {
+				if quit, err := c.handle(ctx, l); quit || err != nil {
+					return quit, err
 				}
 			}
+			return false, nil
+		}
+	}
+	return c.handle(ctx, line)
+}
+
+func (c *Chat) handle(ctx context.Context, line string) (quit bool, err error) {
+	if !strings.HasPrefix(line, "/") {
+		if !c.lobby.allows(c.n.Host.ID()) {
+			c.ui.Print(ansiWarn + T("Not sent: you are in the lobby until the room operator admits you") + ansiReset)
+			return false, nil
+		}
+		c.sendTyped(line, "")
+		return false, nil
+	}
+	if isCorrection(line) {
+		if !c.lobby.allows(c.n.Host.ID()) {
+			c.ui.Print(ansiWarn + T("Not sent: you are in the lobby until the room operator admits you") + ansiReset)
+			return false, nil
+		}
+		c.runCorrection(line)
+		return false, nil
+	}
+
+	fields := strings.Fields(line[1:])
+	name, args := "", []string(nil)
+	if len(fields) > 0 {
+		name, args = strings.ToLower(fields[0]), fields[1:]
+	}
+
+	cmd, ok := lookupCommand(name)
+	switch {
+	case !ok:
+		c.ui.Print(fmt.Sprintf(T("Unknown command: %s"), name))
+	case cmd.run == nil:
+		c.ui.Print(fmt.Sprintf(T("/%s isn't available here"), name))
+	default:
+		return cmd.run(c, ctx, name, line[1:], args), nil
+	}
+	return false, nil
+}
+
+// publish sends a control message from us to the room.
+func (c *Chat) publish(ctx context.Context, text string) {
+	err := c.sendControl(ctx, Message{Nick: c.myNick(), Text: text, Ts: time.Now().UTC()})
+	if err != nil && ctx.Err() == nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Publish failed: %v"), err) + ansiReset)
+	}
+}
+
+// send publishes m to the room inside a chat.publish span, which receivers
+// continue through m.Trace.
+func (c *Chat) send(ctx context.Context, m Message) error {
+	ctx, span := tracer.Start(ctx, "chat.publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("msg.id", m.ID)))
+	defer span.End()
+
+	m.Trace = traceParent(ctx)
+	m.Version = protocolVersion
+	if m.ID != "" {
+		m.Clock = c.clock.tick()
+	}
+	// JOINs stay JSON so clients that only read JSON still learn about us
+	proto := c.n.wire == WireAuto && m.Text != joinText &&
+		c.presence.allSpeak(c.n.Topic.ListPeers(), wireProto)
+	span.SetAttributes(attribute.Bool("msg.protobuf", proto))
+	encode := encodeMessage
+	if c.n.cover > 0 {
+		encode = padPayload
+	}
+	payload, err := encode(m, proto)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		return errTooLong // peers would drop it
+	}
+	if err := c.n.Topic.Publish(ctx, payload); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	c.n.record.add(recEvent{Kind: recSend, Msg: &m})
+	return nil
+}
+
+// finishPing prints the summary of a /ping session once.
+func (c *Chat) finishPing(id string) {
+	if summary := c.pings.finish(id); summary != "" {
+		c.ui.Print("\033[36m" + summary + "\033[0m")
+	}
+}
+
+// runVerify implements /verify and /unverify: mark a peer whose ID you have
+// confirmed out of band, or take that back.
+func (c *Chat) runVerify(verified bool, args []string) {
+	if len(args) != 1 {
+		usage := "/verify <nick|peer-id>"
+		if !verified {
+			usage = "/unverify <nick|peer-id>"
 		}
-	}()
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), usage))
+		return
+	}
+	p, label, err := c.presence.resolve(args[0])
+	if err != nil {
+		c.ui.Print(err.Error())
+		return
+	}
+	c.n.reputation.update(p, func(r *Reputation) { r.Verified = verified })
+	if verified {
+		c.ui.Print(fmt.Sprintf(T("%s (%s) is marked as verified"), label, p))
+	} else {
+		c.ui.Print(fmt.Sprintf(T("%s (%s) is no longer marked as verified"), label, p))
+	}
+}
+
+// runBan applies a /banip or /banpeer command and reports the outcome.
+func (c *Chat) runBan(ban func(string) error, args []string, usage string) {
+	if len(args) != 1 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), usage))
+		return
+	}
+	if err := ban(args[0]); err != nil {
+		c.ui.Print(fmt.Sprintf(T("Ban failed: %v"), err))
+		return
+	}
+	c.ui.Print(fmt.Sprintf(T("Banned %s"), args[0]))
 }
@@ -0,0 +1,351 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// command is one slash command: how /help lists it and what running it
+// does. handle dispatches through the registry and /help is generated
+// from it, so a command added here is documented at the same time.
+type command struct {
+	names    []string // the name, then aliases
+	usage    string   // as shown in /help, e.g. "/pin <id>"
+	category string
+	summary  string // one line for /help
+	detail   string // more for /help <command>; may be empty
+
+	// run carries the command out and reports whether the user asked to
+	// leave. name is what was typed, line the whole line without the
+	// slash. Commands handled before the chat sees them (/compose by the
+	// terminal, /shutdown by the daemon) have none.
+	run func(c *Chat, ctx context.Context, name, line string, args []string) (quit bool)
+}
+
+// Command categories, in the order /help lists them.
+const (
+	catSession     = "Session"
+	catMessages    = "Messages"
+	catRoom        = "Room"
+	catPeers       = "Peers"
+	catModeration  = "Moderation"
+	catSettings    = "Settings"
+	catDiagnostics = "Diagnostics"
+)
+
+var categories = []string{catSession, catMessages, catRoom, catPeers, catModeration, catSettings, catDiagnostics}
+
+// commands is the registry, in the order /help lists each category. It is
+// filled in by init: the /help entry refers back to it.
+var commands []command
+
+// builtinCommands can't be shadowed by an alias.
+var builtinCommands []string
+
+func init() {
+	commands = []command{
+		{names: []string{"help", "h", "?"}, usage: "/help [command]", category: catSession,
+			summary: "Show this help",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.ui.Print(helpReport(args))
+				return false
+			}},
+		{names: []string{"quit", "exit", "q"}, usage: "/quit", category: catSession,
+			summary: "Leave the chat",
+			run:     func(*Chat, context.Context, string, string, []string) bool { return true }},
+		{names: []string{"compose"}, usage: "/compose", category: catSession,
+			summary: "Write a multi-line message in $EDITOR"},
+		{names: []string{"catchup"}, usage: "/catchup", category: catSession,
+			summary: "Read what arrived while no terminal was attached (daemon)",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.ui.Print(c.away.report(c.myNick()))
+				return false
+			}},
+		{names: []string{"shutdown"}, usage: "/shutdown", category: catSession,
+			summary: "Stop quichat daemon and leave the room (attached terminals)"},
+
+		{names: []string{"s"}, usage: "/s/old/new/[g]", category: catMessages,
+			summary: "Correct your last message"},
+		{names: []string{"mentions"}, usage: "/mentions", category: catMessages,
+			summary: "List recent messages that mention me",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.ui.Print(c.mentions.report(c.presence.nickOf))
+				return false
+			}},
+		{names: []string{"more"}, usage: "/more [n|all]", category: catMessages,
+			summary: "Show messages folded away during a burst",
+			detail:  "During a burst only the first 30 messages of each second are printed. /more shows the rest oldest first, 50 at a time; /more 200 or /more all shows more at once.",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runMore(args)
+				return false
+			}},
+		{names: []string{"pin"}, usage: "/pin <id>", category: catMessages,
+			summary: "Pin a message for everyone in the room",
+			run: func(c *Chat, ctx context.Context, _, _ string, args []string) bool {
+				c.runPin(ctx, args)
+				return false
+			}},
+		{names: []string{"pins"}, usage: "/pins", category: catMessages,
+			summary: "List pinned messages",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.ui.Print(c.pins.report())
+				return false
+			}},
+		{names: []string{"retry"}, usage: "/retry", category: catMessages,
+			summary: "Resend messages that failed to publish",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.runRetry()
+				return false
+			}},
+		{names: []string{"schedule"}, usage: "/schedule <when> <text>", category: catMessages,
+			summary: "Send a message later: 09:00, 2026-10-17 09:00 or 15m",
+			detail:  "Scheduled messages are kept on disk and sent once you are back in the room. One more than ten minutes overdue by then is dropped with a notice; your own reminders are shown late instead.",
+			run: func(c *Chat, _ context.Context, _, line string, args []string) bool {
+				c.runSchedule(line, args)
+				return false
+			}},
+		{names: []string{"scheduled"}, usage: "/scheduled [cancel <id>]", category: catMessages,
+			summary: "List or cancel this room's scheduled messages",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runScheduled(args)
+				return false
+			}},
+		{names: []string{"remind"}, usage: "/remind <me|nick> in <20m> <text>", category: catMessages,
+			summary: "Remind yourself, or someone in the room, later",
+			detail:  "\"at 09:00\" works as well as \"in 20m\". A reminder for yourself is shown only to you; one for someone else is posted to the room.",
+			run: func(c *Chat, _ context.Context, _, line string, args []string) bool {
+				c.runRemind(line, args)
+				return false
+			}},
+		{names: []string{"translate"}, usage: "/translate <id> [lang]", category: catMessages,
+			summary: "Translate a message; /translate auto on|off for every message",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runTranslate(args)
+				return false
+			}},
+
+		{names: []string{"list"}, usage: "/list", category: catRoom,
+			summary: "Show peers currently in the room",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				peers := c.n.Topic.ListPeers()
+				c.ui.Print(fmt.Sprintf(T("Peers (%d): %v"), len(peers), peers))
+				return false
+			}},
+		{names: []string{"invite"}, usage: "/invite", category: catRoom,
+			summary: "Print an invite code and QR for this room",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.runInvite()
+				return false
+			}},
+		{names: []string{"admit"}, usage: "/admit [nick]", category: catRoom,
+			summary: "List the lobby or let a waiting peer in (room operator)",
+			run: func(c *Chat, ctx context.Context, _, _ string, args []string) bool {
+				c.runAdmit(ctx, args)
+				return false
+			}},
+		{names: []string{"roomstats"}, usage: "/roomstats", category: catRoom,
+			summary: "Show room activity and gossip mesh health",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.ui.Print(c.roomStats())
+				return false
+			}},
+
+		{names: []string{"nick"}, usage: "/nick [name]", category: catPeers,
+			summary: "Show or change your nick",
+			run: func(c *Chat, ctx context.Context, _, _ string, args []string) bool {
+				c.runNick(ctx, args)
+				return false
+			}},
+		{names: []string{"whois"}, usage: "/whois <nick>", category: catPeers,
+			summary: "Show a peer's ID, protocol version and supported features",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runWhois(args)
+				return false
+			}},
+		{names: []string{"trust"}, usage: "/trust <nick|id>", category: catPeers,
+			summary: "Accept a peer as the owner of its nick after a key change warning",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runTrust(args)
+				return false
+			}},
+		{names: []string{"verify", "unverify"}, usage: "/verify <nick|id>", category: catPeers,
+			summary: "Mark a peer whose ID you checked in person; /unverify undoes it",
+			run: func(c *Chat, _ context.Context, name, _ string, args []string) bool {
+				c.runVerify(name == "verify", args)
+				return false
+			}},
+
+		{names: []string{"banip"}, usage: "/banip <ip|cidr>", category: catModeration,
+			summary: "Block an address range (saved to config)",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runBan(c.n.BanIP, args, "/banip <ip|cidr>")
+				return false
+			}},
+		{names: []string{"banpeer"}, usage: "/banpeer <id>", category: catModeration,
+			summary: "Block a peer ID (saved to config)",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runBan(c.n.BanPeer, args, "/banpeer <peer-id>")
+				return false
+			}},
+
+		{names: []string{"filter"}, usage: "/filter [rule]", category: catSettings,
+			summary: "Show or change display filters (joins, notices, mentions, mute)",
+			detail:  "Rules: joins on|off, notices on|off, mentions on|off (show only messages naming you), mute <nick|peer-id>, unmute <nick|peer-id>, clear.",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runFilter(args)
+				return false
+			}},
+		{names: []string{"alias"}, usage: "/alias [name [line ; line…]]", category: catSettings,
+			summary: "List or define aliases (saved to config)",
+			detail:  "An alias runs its lines in order, with its arguments appended to the last one; aliases don't nest. Built-in commands can't be redefined.",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runAlias(args)
+				return false
+			}},
+		{names: []string{"unalias"}, usage: "/unalias <name>", category: catSettings,
+			summary: "Remove an alias",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runUnalias(args)
+				return false
+			}},
+		{names: []string{"throttle"}, usage: "/throttle [up|down <rate>|off]", category: catSettings,
+			summary: "Show or change the bandwidth limits, e.g. /throttle up 500K",
+			run: func(c *Chat, _ context.Context, _, _ string, args []string) bool {
+				c.runThrottle(args)
+				return false
+			}},
+
+		{names: []string{"ping"}, usage: "/ping", category: catDiagnostics,
+			summary: "Measure round-trip latency to all peers",
+			run: func(c *Chat, ctx context.Context, _, _ string, _ []string) bool {
+				c.runPing(ctx)
+				return false
+			}},
+		{names: []string{"relays"}, usage: "/relays", category: catDiagnostics,
+			summary: "Show which relays carry my traffic",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.ui.Print(c.n.RelayReport())
+				return false
+			}},
+		{names: []string{"debug"}, usage: "/debug", category: catDiagnostics,
+			summary: "Show goroutines, memory, GC and open streams",
+			run: func(c *Chat, _ context.Context, _, _ string, _ []string) bool {
+				c.ui.Print(c.n.debugReport())
+				return false
+			}},
+	}
+	for _, cmd := range commands {
+		builtinCommands = append(builtinCommands, cmd.names...)
+	}
+}
+
+// lookupCommand finds the command called name, or by one of its aliases.
+func lookupCommand(name string) (command, bool) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	for _, cmd := range commands {
+		if slices.Contains(cmd.names, name) {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// helpReport renders /help: every command by category, or with an
+// argument, the one command named.
+func helpReport(args []string) string {
+	var b strings.Builder
+	if len(args) > 0 {
+		cmd, ok := lookupCommand(args[0])
+		if !ok {
+			return fmt.Sprintf(T("Unknown command: %s"), args[0])
+		}
+		fmt.Fprintf(&b, T("Usage: %s"), T(cmd.usage))
+		b.WriteString("\n  " + T(cmd.summary))
+		if cmd.detail != "" {
+			b.WriteString("\n  " + T(cmd.detail))
+		}
+		if len(cmd.names) > 1 {
+			also := make([]string, len(cmd.names)-1)
+			for i, name := range cmd.names[1:] {
+				also[i] = "/" + name
+			}
+			b.WriteString("\n  " + fmt.Sprintf(T("Also: %s"), strings.Join(also, ", ")))
+		}
+		return b.String()
+	}
+
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, displayWidth(T(cmd.usage)))
+	}
+	width = min(width, 24) // longer usages push their summary along instead
+	b.WriteString(T("Available commands:"))
+	for _, cat := range categories {
+		fmt.Fprintf(&b, "\n\n%s", T(cat))
+		for _, cmd := range commands {
+			if cmd.category != cat {
+				continue
+			}
+			usage := T(cmd.usage)
+			pad := max(width-displayWidth(usage), 0) + 2
+			fmt.Fprintf(&b, "\n  %s%s%s", usage, strings.Repeat(" ", pad), T(cmd.summary))
+		}
+	}
+	b.WriteString("\n\n" + T("/help <command> shows more about one"))
+	return b.String()
+}
+
+// runPing implements /ping.
+func (c *Chat) runPing(ctx context.Context) {
+	peers := len(c.n.Topic.ListPeers())
+	if peers == 0 {
+		c.ui.Print(T("Nobody to ping yet"))
+		return
+	}
+	id := makeID()
+	c.pings.start(id, peers, time.Now())
+	time.AfterFunc(pingTTL, func() { c.finishPing(id) })
+	c.publish(ctx, "__PING__"+id)
+}
+
+// runInvite implements /invite.
+func (c *Chat) runInvite() {
+	code := c.n.Invite().Encode()
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Invite code: %s")+"\n", code)
+	PrintQR(&b, code)
+	fmt.Fprintf(&b, T("Join with: quichat run --invite %s"), code)
+	c.ui.Print(b.String())
+}
+
+// runPin implements /pin <id>.
+func (c *Chat) runPin(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/pin <message-id>"))
+		return
+	}
+	m, err := c.pins.lookup(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		c.ui.Print(err.Error())
+		return
+	}
+	c.pins.merge([]Message{m})
+	c.publish(ctx, encodePins([]Message{m}))
+	c.ui.Print(fmt.Sprintf(T("Pinned #%s"), shortMsgID(m.ID)))
+}
+
+// runWhois implements /whois <nick|peer-id>.
+func (c *Chat) runWhois(args []string) {
+	if len(args) != 1 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/whois <nick|peer-id>"))
+		return
+	}
+	report, p := c.presence.whois(args[0])
+	if rep, ok := c.n.reputation.get(p); ok {
+		report += "\n  " + rep.Report(time.Now())
+	}
+	c.ui.Print(report)
+}
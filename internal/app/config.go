@@ -0,0 +1,128 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+const configFile = "config.json"
+
+// Config is the user's persistent configuration, stored as JSON.
+// Commands that change settings at runtime (e.g. /banip) write it back.
+type Config struct {
+	mu   sync.Mutex
+	path string
+
+	Gater   GaterConfig  `json:"gater"`
+	Relays  RelayConfig  `json:"relays"`
+	Updates UpdateConfig `json:"updates"`
+
+	// API holds the tokens accepted by --api.
+	API APIConfig `json:"api"`
+
+	// Webhooks receive the room's chat lines over HTTP.
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+
+	// Feeds are RSS or Atom feeds announced in the room.
+	Feeds []FeedConfig `json:"feeds,omitempty"`
+
+	// Translate configures the backend behind /translate.
+	Translate TranslateConfig `json:"translate"`
+
+	// Identities maps a room name (or "default" for any room not listed) to
+	// the identity used in it.
+	Identities map[string]Identity `json:"identities,omitempty"`
+
+	// Operators maps a room to the peer ID of its operator, whose rosters
+	// cap it. It overrides the operator an invite names.
+	Operators map[string]string `json:"operators,omitempty"`
+
+	// Aliases maps a command name (without the slash) to the lines it runs.
+	Aliases map[string][]string `json:"aliases,omitempty"`
+
+	// Transforms maps a room (or "default") to the rewriting applied to
+	// lines typed in it.
+	Transforms map[string]Transform `json:"transforms,omitempty"`
+}
+
+// Identity is how we appear in a room. An identity with a Key has its own
+// keypair, kept under that name; rooms whose identities name the same key
+// share a peer ID and no other. Without one, every start generates a fresh
+// keypair unless KeepKey is set, and kept keys are per room, so peer IDs
+// never link one room to another.
+type Identity struct {
+	Nick string `json:"nick"`
+
+	// Key names the identity's keypair, created on first use.
+	Key string `json:"key,omitempty"`
+
+	// KeepKey keeps the room's keypair between runs, as --keep-key does.
+	KeepKey bool `json:"keep_key,omitempty"`
+}
+
+// identity returns the configured identity for room, falling back to the
+// "default" entry and then to "anon".
+func (c *Config) identity(room string) Identity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []string{room, "default"} {
+		if id, ok := c.Identities[key]; ok && id.Nick != "" {
+			return id
+		}
+	}
+	return Identity{Nick: "anon"}
+}
+
+// operator returns the configured operator of room, or "".
+func (c *Config) operator(room string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Operators[room]
+}
+
+// GaterConfig lists peers and address ranges we refuse to talk to.
+type GaterConfig struct {
+	DenyPeers []string `json:"deny_peers,omitempty"`
+	DenyCIDRs []string `json:"deny_cidrs,omitempty"`
+}
+
+// ConfigPath is the default config location, ~/.quichat/config.json.
+func ConfigPath() (string, error) { return statePath(configFile) }
+
+// LoadConfig reads the config at path (the default one if empty).
+// A missing file yields an empty config that will be created on Save.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		var err error
+		if path, err = ConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+	c := &Config{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update applies fn under the config lock and saves the result.
+func (c *Config) Update(fn func(c *Config)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(c)
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cover traffic (--cover): every frame we publish is padded to a multiple of
+// coverFrame bytes, and the outbox publishes exactly one frame per interval:
+// a chat line due for another try, a control message (JOIN, PING, PONG,
+// pins, rosters), a queued chat line, or a dummy if there is none. Transports
+// already encrypt each frame, so an observer of the links sees neither when
+// we type nor how much. Only the LEAVE on the way out skips the queue.
+const (
+	coverText    = "__COVER__"
+	coverFrame   = 1024 // bytes; longer messages take whole multiples
+	controlQueue = 32   // control messages waiting for a frame
+)
+
+// padPayload grows m's padding until its encoding is a whole number of
+// cover frames.
+func padPayload(m Message, proto bool) ([]byte, error) {
+	m.Pad = ""
+	b, err := encodeMessage(m, proto)
+	if err != nil || len(b)%coverFrame == 0 {
+		return b, err
+	}
+	target := (len(b)/coverFrame + 1) * coverFrame
+	pad := target - len(b)
+	for {
+		// the pad field's own tag and length take a few bytes, and more
+		// near varint boundaries, so converge on the target
+		for range 4 {
+			m.Pad = strings.Repeat(".", max(pad, 0))
+			if b, err = encodeMessage(m, proto); err != nil || len(b) == target {
+				return b, err
+			}
+			pad -= len(b) - target
+		}
+		target += coverFrame
+		pad += coverFrame
+	}
+}
+
+// retryLine is a chat line whose publish failed, waiting for a later frame.
+type retryLine struct {
+	m     Message
+	tries int
+	due   time.Time
+}
+
+// runCoverOutbox is runOutbox for --cover: one frame per tick, whether or
+// not anything was typed. Nothing is sent while the room is empty. A failed
+// chat line is tried again in a later frame after publishBackoff, doubled
+// each time, so retries never stretch the interval.
+func (c *Chat) runCoverOutbox(ctx context.Context) error {
+	tick := time.NewTicker(c.n.cover)
+	defer tick.Stop()
+
+	var retries []retryLine
+	for {
+		select {
+		case <-ctx.Done():
+			var waiting []Message
+			for _, r := range retries {
+				waiting = append(waiting, r.m)
+			}
+			c.flushOutbox(ctx, waiting)
+			return nil
+		case <-tick.C:
+		}
+		if len(c.n.Topic.ListPeers()) == 0 {
+			continue
+		}
+		r, ok := retryLine{}, false
+		if len(retries) > 0 && !time.Now().Before(retries[0].due) {
+			r, ok, retries = retries[0], true, retries[1:]
+		}
+		if !ok {
+			select {
+			case m := <-c.control:
+				if err := c.send(ctx, m); err != nil && ctx.Err() == nil && m.Text != joinText {
+					c.ui.Print(ansiWarn + fmt.Sprintf(T("Publish failed: %v"), err) + ansiReset)
+				}
+				continue
+			case m := <-c.outbox:
+				r = retryLine{m: m}
+			default:
+				_ = c.send(ctx, Message{Nick: c.myNick(), Text: coverText, Ts: time.Now().UTC()})
+				continue
+			}
+		}
+		err := c.send(ctx, r.m)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		r.tries++
+		switch {
+		case r.tries == publishTries:
+			c.unsent.add(r.m)
+			c.reportUnsent(r.m, err)
+			continue
+		case r.tries == 1:
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("Sending #%s failed (%v), retrying…"), shortMsgID(r.m.ID), err) + ansiReset)
+		}
+		r.due = time.Now().Add(publishBackoff << (r.tries - 1))
+		retries = append(retries, r)
+	}
+}
+
+// sendControl publishes a control message: at once, or with --cover in the
+// next frame the outbox has free, so that it keeps the cadence too.
+func (c *Chat) sendControl(ctx context.Context, m Message) error {
+	if c.n.cover == 0 {
+		return c.send(ctx, m)
+	}
+	select {
+	case c.control <- m:
+		return nil
+	default:
+		return errOutboxFull
+	}
+}
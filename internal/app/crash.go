@@ -0,0 +1,58 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// writeCrashReport saves a panic and the current goroutine's stack under
+// the state directory and returns the file's path for the user to attach
+// to a bug report.
+func writeCrashReport(where string, r any) string {
+	report := fmt.Sprintf("quichat %s (%s/%s, %s)\n%s\npanic in %s: %v\n\n%s",
+		Version, runtime.GOOS, runtime.GOARCH, runtime.Version(),
+		time.Now().Format(time.RFC3339), where, r, debug.Stack())
+
+	path, err := statePath(filepath.Join("crashes", time.Now().Format("20060102-150405.000")+".log"))
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(path, []byte(report), 0o600)
+	}
+	if err != nil {
+		fmt.Fprint(os.Stderr, report)
+		return "stderr"
+	}
+	return path
+}
+
+// guard wraps an errgroup goroutine so a panic becomes an error instead of
+// killing the process; the group then shuts down normally and deferred
+// cleanup, such as putting the terminal back into cooked mode, still runs.
+func guard(where string, fn func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%s crashed: %v (crash report: %s)", where, r, writeCrashReport(where, r))
+			}
+		}()
+		return fn()
+	}
+}
+
+// recoverPanic is deferred around one unit of chat work (a received message,
+// a typed line). A panic there is reported and the session carries on with
+// the next one.
+func (c *Chat) recoverPanic(where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	path := writeCrashReport(where, r)
+	c.ui.Print("\033[31m" + fmt.Sprintf(T("Internal error while handling %s: %v (crash report: %s); carrying on"), T(where), r, path) + "\033[0m")
+}
@@ -0,0 +1,476 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	daemonBacklog      = 200               // blocks replayed to a freshly attached client
+	daemonQueue        = 2 * daemonBacklog // frames waiting for one client; a client further behind is dropped
+	daemonWriteTimeout = 5 * time.Second   // slow clients are dropped, not waited on
+)
+
+// frame is one newline-delimited JSON message on the daemon socket.
+// Clients send input lines; the daemon sends blocks of output. A client's
+// first frame is a query: queryAttach to join the session, queryStatus for
+// a single frame carrying Node, after which the daemon hangs up. Any other
+// query is answered with Error and the daemon hangs up.
+type frame struct {
+	Text   string        `json:"text"`
+	Status string        `json:"status,omitempty"` // replaces the status bar; Text is empty
+	Query  string        `json:"query,omitempty"`
+	Node   *DaemonStatus `json:"node,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+const (
+	queryAttach = "attach"
+	queryStatus = "status"
+)
+
+// Uptime is how long a node has been running. It prints as a duration,
+// 1h2m3s, and is whole seconds in JSON, where time.Duration's nanoseconds
+// would mean nothing to jq or a dashboard.
+type Uptime time.Duration
+
+func (u Uptime) String() string { return time.Duration(u).String() }
+
+func (u Uptime) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(time.Duration(u)/time.Second), 10), nil
+}
+
+func (u *Uptime) UnmarshalJSON(b []byte) error {
+	var s int64
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*u = Uptime(time.Duration(s) * time.Second)
+	return nil
+}
+
+// DaemonStatus is a snapshot of a daemon's node and chat session, for
+// quichat status.
+type DaemonStatus struct {
+	PeerID       string             `json:"peer_id"`
+	Nick         string             `json:"nick"`
+	Uptime       Uptime             `json:"uptime"` // seconds in JSON
+	Room         string             `json:"room"`
+	Network      string             `json:"network,omitempty"`
+	Members      int                `json:"members"`         // other peers in the room
+	Nicks        map[peer.ID]string `json:"nicks,omitempty"` // each member's label
+	Peers        int                `json:"peers"`           // connected, in the room or not
+	Reachability string             `json:"reachability"`
+	Relays       int                `json:"relays"` // holding a reservation for us
+	BytesIn      int64              `json:"bytes_in"`
+	BytesOut     int64              `json:"bytes_out"`
+	RateIn       float64            `json:"rate_in"` // bytes per second
+	RateOut      float64            `json:"rate_out"`
+	Queued       int                `json:"queued"` // chat lines waiting to be published
+	Unsent       int                `json:"unsent"` // lines that failed, kept for /retry
+	Terminals    int                `json:"terminals"`
+}
+
+// DaemonSocketPath is where the daemon listens unless told otherwise.
+func DaemonSocketPath() (string, error) {
+	return statePath("daemon.sock")
+}
+
+// hub is the daemon's UI: it fans output out to every attached client and
+// keeps a short backlog for the next one to attach. Each client has its own
+// queue and writer, so a slow or stuck terminal holds up neither the chat
+// nor the other terminals.
+type hub struct {
+	mu      sync.Mutex
+	clients map[net.Conn]chan frame
+	backlog []string
+	status  string
+}
+
+// SetStatus passes the status bar on to every client, and to each new one
+// when it attaches.
+func (h *hub) SetStatus(s string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status = s
+	h.send(frame{Status: s})
+}
+
+func (h *hub) Print(s string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.backlog = append(h.backlog, s)
+	if len(h.backlog) > daemonBacklog {
+		h.backlog = h.backlog[len(h.backlog)-daemonBacklog:]
+	}
+	h.send(frame{Text: s})
+}
+
+// send queues f for every client, dropping those whose queue is full. h.mu
+// must be held.
+func (h *hub) send(f frame) {
+	for c, out := range h.clients {
+		select {
+		case out <- f:
+		default:
+			h.drop(c)
+		}
+	}
+}
+
+// drop detaches c, ending its writer. h.mu must be held.
+func (h *hub) drop(c net.Conn) {
+	if out, ok := h.clients[c]; ok {
+		close(out)
+		delete(h.clients, c)
+		c.Close()
+	}
+}
+
+// add queues the backlog for c, followed by greeting if it is the first
+// client attached, subscribes it to future output and starts its writer.
+func (h *hub) add(c net.Conn, greeting func() string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(chan frame, daemonQueue)
+	for _, s := range h.backlog { // never more than daemonBacklog
+		out <- frame{Text: s}
+	}
+	if h.status != "" {
+		out <- frame{Status: h.status}
+	}
+	if len(h.clients) == 0 {
+		if s := greeting(); s != "" {
+			out <- frame{Text: s}
+		}
+	}
+	h.clients[c] = out
+	go writeFrames(c, out)
+}
+
+// writeFrames writes what is queued for c until the queue is closed. A
+// write that doesn't finish within daemonWriteTimeout closes c, which
+// detaches the client.
+func writeFrames(c net.Conn, out <-chan frame) {
+	enc := json.NewEncoder(c)
+	for f := range out {
+		c.SetWriteDeadline(time.Now().Add(daemonWriteTimeout))
+		if err := enc.Encode(f); err != nil {
+			c.Close()
+			for range out { // until remove or drop closes it
+			}
+			return
+		}
+	}
+}
+
+func (h *hub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// remove detaches c and reports whether it was the last client.
+func (h *hub) remove(c net.Conn) (last bool) {
+	h.mu.Lock()
+	_, ok := h.clients[c]
+	h.drop(c)
+	last = ok && len(h.clients) == 0
+	h.mu.Unlock()
+	c.Close()
+	return last
+}
+
+// daemonStatus takes a DaemonStatus of the session with terminals attached.
+func (c *Chat) daemonStatus(terminals int) DaemonStatus {
+	n := c.n
+	members := c.presence.members()
+	bw := n.relay.bw.GetBandwidthTotals()
+	reach := strings.ToLower(network.Reachability(n.reach.Load()).String())
+	return DaemonStatus{
+		PeerID:       n.Host.ID().String(),
+		Nick:         c.myNick(),
+		Uptime:       Uptime(time.Since(n.started).Round(time.Second)),
+		Room:         n.room,
+		Network:      n.network,
+		Members:      len(members),
+		Nicks:        members,
+		Peers:        len(n.Host.Network().Peers()),
+		Reachability: reach,
+		Relays:       int(n.relaysInUse.Load()),
+		BytesIn:      bw.TotalIn,
+		BytesOut:     bw.TotalOut,
+		RateIn:       bw.RateIn,
+		RateOut:      bw.RateOut,
+		Queued:       len(c.outbox) + int(c.held.Load()),
+		Unsent:       c.unsent.len(),
+		Terminals:    terminals,
+	}
+}
+
+// RunDaemon keeps n in the chat and serves terminals that attach over a unix
+// socket at path until ctx is cancelled or a client sends /shutdown.
+func RunDaemon(ctx context.Context, n *Node, nick, path string) error {
+	ln, err := listenDaemon(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	fmt.Printf("Daemon listening on %s (quichat attach to join)\n", path)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	h := &hub{clients: make(map[net.Conn]chan frame)}
+	chat := NewChat(n, nick, h)
+	chat.away.leave(time.Now()) // nobody is attached yet
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error { return chat.Run(ctx) })
+	g.Go(func() error {
+		<-ctx.Done()
+		return ln.Close()
+	})
+	g.Go(func() error {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			go serveClient(ctx, h, chat, c, cancel)
+		}
+	})
+
+	if err := g.Wait(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// listenDaemon binds the unix socket, clearing a stale one left by a crash.
+// Whoever can connect can type into the chat, so the socket is bound in a
+// private directory beside path, made ours alone and only then moved into
+// place: there is no moment, whatever the umask, when another user could
+// reach it.
+func listenDaemon(path string) (net.Listener, error) {
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	os.Remove(path)
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".qd") // short: socket paths are limited to about 100 bytes
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false) // RunDaemon removes it from path
+	if err = os.Chmod(tmp, 0o600); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// serveClient feeds one attached terminal's input into the chat engine, or
+// answers a status query. /quit detaches only this client; /shutdown stops
+// the daemon.
+func serveClient(ctx context.Context, h *hub, chat *Chat, c net.Conn, shutdown context.CancelFunc) {
+	defer chat.recoverPanic("client connection")
+	dec := json.NewDecoder(c)
+	var f frame
+	if err := dec.Decode(&f); err != nil {
+		c.Close()
+		return
+	}
+	switch f.Query {
+	case queryStatus:
+		// a status query is not a terminal: it must not end the "while you
+		// were away" count
+		st := chat.daemonStatus(h.count())
+		c.SetWriteDeadline(time.Now().Add(daemonWriteTimeout))
+		json.NewEncoder(c).Encode(frame{Node: &st})
+		c.Close()
+		return
+	case queryAttach, "": // "": clients from before queries, whose first frame is input
+	default:
+		c.SetWriteDeadline(time.Now().Add(daemonWriteTimeout))
+		json.NewEncoder(c).Encode(frame{Error: fmt.Sprintf("unknown query %q", f.Query)})
+		c.Close()
+		return
+	}
+
+	greeting := func() string { return chat.away.back(chat.n.room, time.Now()) }
+	h.add(c, greeting)
+	defer func() {
+		if h.remove(c) {
+			chat.away.leave(time.Now())
+		}
+	}()
+
+	for {
+		if f.Query == "" { // the attach query carries no input
+			if strings.TrimSpace(f.Text) == "/shutdown" {
+				h.Print(T("Daemon shutting down"))
+				shutdown()
+				return
+			}
+			quit, err := chat.Handle(ctx, f.Text)
+			if err != nil {
+				h.Print(fmt.Sprintf(T("Send failed: %v"), err))
+			}
+			if quit {
+				return
+			}
+		}
+		f = frame{}
+		if err := dec.Decode(&f); err != nil {
+			return
+		}
+	}
+}
+
+// Report renders s for quichat status.
+func (s DaemonStatus) Report() string {
+	var b strings.Builder
+	room := s.Room
+	if s.Network != "" {
+		room = s.Network + "/" + room
+	}
+	fmt.Fprintf(&b, "Nick:       %s (%s)\n", s.Nick, s.PeerID)
+	fmt.Fprintf(&b, "Uptime:     %s\n", s.Uptime)
+	fmt.Fprintf(&b, "Room:       %s, %d other members\n", room, s.Members)
+	fmt.Fprintf(&b, "Peers:      %d connected\n", s.Peers)
+	fmt.Fprintf(&b, "NAT:        %s, %d relays\n", s.Reachability, s.Relays)
+	fmt.Fprintf(&b, "Traffic:    %s in, %s out (now %s in, %s out)\n", formatSize(s.BytesIn), formatSize(s.BytesOut),
+		formatRate(int64(s.RateIn)), formatRate(int64(s.RateOut)))
+	fmt.Fprintf(&b, "Outbox:     %d queued, %d unsent\n", s.Queued, s.Unsent)
+	fmt.Fprintf(&b, "Terminals:  %d attached\n", s.Terminals)
+	return b.String()
+}
+
+// QueryStatus asks the daemon listening on path for a DaemonStatus.
+func QueryStatus(ctx context.Context, path string) (DaemonStatus, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return DaemonStatus{}, fmt.Errorf("no daemon on %s (start one with quichat daemon): %w", path, err)
+	}
+	defer c.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(daemonWriteTimeout)
+	}
+	c.SetDeadline(deadline)
+	if err := json.NewEncoder(c).Encode(frame{Query: queryStatus}); err != nil {
+		return DaemonStatus{}, err
+	}
+	var f frame
+	if err := json.NewDecoder(c).Decode(&f); err != nil {
+		return DaemonStatus{}, fmt.Errorf("daemon on %s: %w", path, err)
+	}
+	if f.Error != "" {
+		return DaemonStatus{}, fmt.Errorf("daemon on %s: %s", path, f.Error)
+	}
+	if f.Node == nil {
+		return DaemonStatus{}, fmt.Errorf("daemon on %s does not answer status queries; restart it", path)
+	}
+	return *f.Node, nil
+}
+
+// Attach connects this terminal to a running daemon's chat session.
+func Attach(ctx context.Context, path string) error {
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return fmt.Errorf("no daemon on %s (start one with quichat daemon): %w", path, err)
+	}
+	defer c.Close()
+	if err := json.NewEncoder(c).Encode(frame{Query: queryAttach}); err != nil {
+		return err
+	}
+
+	ui, err := newTermUI()
+	if err != nil {
+		return err
+	}
+	defer ui.Close()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// ─── Receiver ───────────────────────────────────────────────────────────────
+	g.Go(guard("receiver", func() error {
+		dec := json.NewDecoder(c)
+		for {
+			var f frame
+			if err := dec.Decode(&f); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if errors.Is(err, io.EOF) {
+					ui.Print(T("Daemon closed the connection"))
+					ui.Close()
+					return errQuit
+				}
+				return err
+			}
+			if f.Error != "" {
+				return fmt.Errorf("daemon: %s", f.Error)
+			}
+			if f.Status != "" {
+				ui.SetStatus(f.Status)
+				continue
+			}
+			ui.Print(f.Text)
+		}
+	}))
+
+	// ─── Sender ─────────────────────────────────────────────────────────────────
+	g.Go(guard("sender", func() error {
+		enc := json.NewEncoder(c)
+		return readLines(ctx, ui, func(line string) (bool, error) {
+			if err := enc.Encode(frame{Text: line}); err != nil {
+				return false, err
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "/quit", "/exit", "/q":
+				return true, nil
+			}
+			return false, nil
+		})
+	}))
+
+	g.Go(func() error {
+		<-ctx.Done()
+		c.Close() // unblock the receiver
+		return nil
+	})
+
+	if err := g.Wait(); !errors.Is(err, errQuit) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUptimeJSON(t *testing.T) {
+	st := DaemonStatus{Uptime: Uptime(2*time.Hour + 3*time.Second)}
+	b, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct{ Uptime json.Number }
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Uptime != "7203" {
+		t.Fatalf("uptime is %s in JSON, want 7203 seconds", raw.Uptime)
+	}
+	var back DaemonStatus
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Uptime != st.Uptime || back.Uptime.String() != "2h0m3s" {
+		t.Fatalf("round trip gave %s, want %s", back.Uptime, st.Uptime)
+	}
+}
+
+func TestHubDropsStuckClient(t *testing.T) {
+	h := &hub{clients: make(map[net.Conn]chan frame)}
+	stuck, stuckPeer := net.Pipe() // nobody reads stuckPeer
+	defer stuckPeer.Close()
+	good, goodPeer := net.Pipe()
+	defer goodPeer.Close()
+	h.add(stuck, func() string { return "" })
+	h.add(good, func() string { return "" })
+
+	h.Print("hello")
+	goodPeer.SetReadDeadline(time.Now().Add(time.Second))
+	var f frame
+	if err := json.NewDecoder(goodPeer).Decode(&f); err != nil || f.Text != "hello" {
+		t.Fatalf("attached client got %+v, %v", f, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := range daemonQueue + 2 {
+			h.Print(strconv.Itoa(i))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Print waited on a client that doesn't read")
+	}
+	h.mu.Lock()
+	_, attached := h.clients[stuck]
+	h.mu.Unlock()
+	if attached {
+		t.Fatal("the stuck client is still attached")
+	}
+}
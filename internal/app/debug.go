@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// servePprof exposes the net/http/pprof handlers on addr until ctx is done.
+// They get their own mux so they never leak onto the health endpoints.
+func servePprof(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// debugReport renders /debug: goroutines, memory, GC and libp2p streams.
+func (n *Node) debugReport() string {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Goroutines: %d"), runtime.NumGoroutine())
+	fmt.Fprintf(&b, "\n  "+T("Heap: %s in use, %s reserved, %d objects"),
+		byteSize(ms.HeapAlloc), byteSize(ms.HeapSys), ms.HeapObjects)
+	var lastPause time.Duration
+	if ms.NumGC > 0 {
+		lastPause = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+	fmt.Fprintf(&b, "\n  "+T("GC: %d cycles, %v total pause, last pause %v"),
+		ms.NumGC, time.Duration(ms.PauseTotalNs), lastPause)
+
+	conns := n.Host.Network().Conns()
+	perProto := make(map[string]int)
+	streams := 0
+	for _, c := range conns {
+		for _, s := range c.GetStreams() {
+			streams++
+			proto := string(s.Protocol())
+			if proto == "" {
+				proto = "(negotiating)"
+			}
+			perProto[proto]++
+		}
+	}
+	fmt.Fprintf(&b, "\n  "+T("Streams: %d open on %d connections"), streams, len(conns))
+	protos := make([]string, 0, len(perProto))
+	for p := range perProto {
+		protos = append(protos, p)
+	}
+	sort.Strings(protos)
+	for _, p := range protos {
+		fmt.Fprintf(&b, "\n    %-40s %d", p, perProto[p])
+	}
+	if n.DHT != nil && !n.serve {
+		b.WriteString("\n  " + n.rendezvous.report())
+	}
+	return b.String()
+}
+
+// byteSize formats n bytes with a binary unit.
+func byteSize(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
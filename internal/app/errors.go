@@ -0,0 +1,100 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The failures people run into most come back as a *HintError, which says
+// what went wrong in a line and what to try next, instead of a chain of
+// wrapped libp2p errors. errors.Is tells them apart by these kinds.
+var (
+	ErrInvalidAddr          = errors.New("invalid multiaddr")
+	ErrBootstrapUnreachable = errors.New("bootstrap peer unreachable")
+	ErrPortInUse            = errors.New("port in use")
+	ErrNoPeers              = errors.New("no peers")
+)
+
+// HintError is a failure with advice for the user.
+type HintError struct {
+	Kind error  // one of the Err* kinds above
+	Msg  string // what went wrong
+	Hint string // what to try; an English catalog key
+	Err  error  // the cause, if any
+}
+
+func (e *HintError) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *HintError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Explain renders err for the terminal. A *HintError anywhere in the chain
+// gives its message, the first line of the cause and the hint; any other
+// error is returned as it is.
+func Explain(err error) string {
+	var h *HintError
+	if !errors.As(err, &h) {
+		return err.Error()
+	}
+	var b strings.Builder
+	b.WriteString(h.Msg)
+	if h.Err != nil {
+		cause, _, _ := strings.Cut(h.Err.Error(), "\n")
+		fmt.Fprintf(&b, " (%s)", cause)
+	}
+	b.WriteString("\n  " + T(h.Hint))
+	return b.String()
+}
+
+// invalidAddr reports a multiaddr that doesn't parse or lacks a peer ID.
+func invalidAddr(what, s string, err error) error {
+	return &HintError{
+		Kind: ErrInvalidAddr,
+		Msg:  fmt.Sprintf("invalid %s multiaddr %q", what, s),
+		Hint: "An address looks like /ip4/203.0.113.7/udp/4001/quic-v1/p2p/12D3KooW…; copy it whole from the \"Listening on\" lines the other node prints, or use --invite",
+		Err:  err,
+	}
+}
+
+// bootstrapUnreachable reports that startup gave up on the bootstrap peers.
+func (n *Node) bootstrapUnreachable(err error) error {
+	hint := "Is the bootstrap node running? Check the address, try --bootstrap-retries 3, or --proxy if a firewall is in the way"
+	if n.invite != nil {
+		hint = "The peer that made this invite may have left; ask someone in the room for a fresh /invite"
+	}
+	return &HintError{Kind: ErrBootstrapUnreachable, Msg: "can't reach the bootstrap peer", Hint: hint, Err: err}
+}
+
+// portInUse reports that no port we may listen on is free.
+func portInUse(from, to int, err error) error {
+	msg := fmt.Sprintf("port %d is in use", from)
+	if to > from {
+		msg = fmt.Sprintf("ports %d-%d are all in use", from, to)
+	}
+	return &HintError{
+		Kind: ErrPortInUse,
+		Msg:  msg,
+		Hint: "Pick another with --listen, or --listen 0 for any free port; another quichat may be running (quichat status)",
+		Err:  err,
+	}
+}
+
+// noPeers reports a room still empty after waiting d.
+func (n *Node) noPeers(d time.Duration) error {
+	hint := "Nobody else is here yet: share /invite, or start with --bootstrap or --invite to join someone"
+	if n.invite != nil || len(n.bootstrapAddrs) > 0 {
+		hint = "The bootstrap peer answered but nobody else is in this room; check that everyone uses the same room and --network"
+	}
+	return &HintError{Kind: ErrNoPeers, Msg: fmt.Sprintf(T("No peers in the room after %s"), d), Hint: hint}
+}
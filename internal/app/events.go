@@ -0,0 +1,125 @@
+package app
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// watchEvents turns host event-bus traffic into status lines for the UI:
+// address changes, reachability verdicts from AutoNAT, relay selection by
+// AutoRelay, and the identification of our bootstrap peer.
+func (n *Node) watchEvents() error {
+	sub, err := n.Host.EventBus().Subscribe([]any{
+		new(event.EvtLocalAddressesUpdated),
+		new(event.EvtLocalReachabilityChanged),
+		new(event.EvtAutoRelayAddrsUpdated),
+		new(event.EvtPeerIdentificationCompleted),
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer sub.Close()
+		var relays []peer.ID
+		for {
+			select {
+			case <-n.ctx.Done():
+				return
+			case e, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				switch evt := e.(type) {
+				case event.EvtLocalAddressesUpdated:
+					n.reprintAddrs()
+				case event.EvtLocalReachabilityChanged:
+					n.reach.Store(int32(evt.Reachability))
+					n.notifyReachability(evt.Reachability)
+				case event.EvtAutoRelayAddrsUpdated:
+					now := relayPeers(evt.RelayAddrs)
+					n.relaysInUse.Store(int32(len(now)))
+					if !slices.Equal(now, relays) {
+						relays = now
+						n.notifyRelays(relays)
+					}
+				case event.EvtPeerIdentificationCompleted:
+					n.records.put(evt.Peer, evt.SignedPeerRecord)
+					if evt.Peer == n.bootstrapID || n.verbose {
+						n.notify("Identified %s (%s)", shortID(evt.Peer), agentOrUnknown(evt.AgentVersion))
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reprintAddrs notifies the UI when the displayed address list changed
+// since it was last printed. Changes before the startup print are ignored.
+func (n *Node) reprintAddrs() {
+	list := n.formatAddrs(T("Your multiaddrs changed:"))
+	body := list[strings.Index(list, "\n")+1:]
+	n.addrMu.Lock()
+	changed := n.lastAddrs != "" && body != n.lastAddrs
+	if changed {
+		n.lastAddrs = body
+	}
+	n.addrMu.Unlock()
+	if changed {
+		n.notify("%s", strings.TrimRight(list, "\n"))
+	}
+}
+
+func (n *Node) notifyReachability(r network.Reachability) {
+	switch r {
+	case network.ReachabilityPublic:
+		n.notify("You are now publicly reachable")
+	case network.ReachabilityPrivate:
+		n.notify("You are behind a NAT; peers will reach you through a relay")
+	}
+}
+
+func (n *Node) notifyRelays(relays []peer.ID) {
+	if len(relays) == 0 {
+		n.notify("No longer using any relay")
+		return
+	}
+	names := make([]string, len(relays))
+	for i, id := range relays {
+		names[i] = shortID(id)
+	}
+	n.notify("Using relay %s", strings.Join(names, ", "))
+}
+
+// relayPeers extracts the sorted, de-duplicated relay IDs from circuit addrs.
+func relayPeers(addrs []ma.Multiaddr) []peer.ID {
+	var ids []peer.ID
+	for _, a := range addrs {
+		if id := relayOf(a); id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// shortID abbreviates a peer ID to its last six characters.
+func shortID(id peer.ID) string {
+	s := id.String()
+	if len(s) <= 6 {
+		return s
+	}
+	return "…" + s[len(s)-6:]
+}
+
+func agentOrUnknown(agent string) string {
+	if agent == "" {
+		return "unknown agent"
+	}
+	return agent
+}
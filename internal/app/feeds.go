@@ -0,0 +1,253 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	feedsFile        = "feeds.json"
+	feedDefaultEvery = 15 * time.Minute
+	feedMinEvery     = time.Minute
+	feedTimeout      = 30 * time.Second
+	feedBurst        = 3       // items posted per poll; the rest are summed up in one line
+	feedSeenMax      = 500     // GUIDs remembered per feed
+	feedMaxBytes     = 4 << 20 // larger feeds are skipped
+)
+
+// FeedConfig is an RSS or Atom feed announced in the room.
+type FeedConfig struct {
+	URL   string `json:"url"`
+	Every string `json:"every,omitempty"` // poll interval, e.g. "30m"; default 15m, at least 1m
+	// Rooms limits the announcements to these rooms; empty means any room.
+	Rooms []string `json:"rooms,omitempty"`
+}
+
+func (f FeedConfig) interval() (time.Duration, error) {
+	if f.Every == "" {
+		return feedDefaultEvery, nil
+	}
+	d, err := time.ParseDuration(f.Every)
+	if err != nil {
+		return 0, fmt.Errorf("feed %s: every: %w", f.URL, err)
+	}
+	return max(d, feedMinEvery), nil
+}
+
+// validateFeeds rejects feed entries that could never be polled.
+func validateFeeds(feeds []FeedConfig) error {
+	for _, f := range feeds {
+		if !strings.HasPrefix(f.URL, "https://") && !strings.HasPrefix(f.URL, "http://") {
+			return fmt.Errorf("feed %q: url must start with http:// or https://", f.URL)
+		}
+		if _, err := f.interval(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feedList returns a copy of the configured feeds.
+func (c *Config) feedList() []FeedConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.Feeds)
+}
+
+// feedItem is one entry of either feed format.
+type feedItem struct {
+	GUID, Title, Link string
+}
+
+// feedDoc decodes RSS 2.0 (<rss><channel>) and Atom (<feed>) alike.
+type feedDoc struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title string `xml:"title"`
+			Link  string `xml:"link"`
+			GUID  string `xml:"guid"`
+		} `xml:"item"`
+	} `xml:"channel"`
+	Title   string `xml:"title"`
+	Entries []struct {
+		Title string `xml:"title"`
+		ID    string `xml:"id"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// parseFeed returns a feed's title and its items, newest first as feeds
+// list them.
+func parseFeed(b []byte) (string, []feedItem, error) {
+	var doc feedDoc
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return "", nil, err
+	}
+	var items []feedItem
+	for _, it := range doc.Channel.Items {
+		guid := it.GUID
+		if guid == "" {
+			guid = it.Link
+		}
+		items = append(items, feedItem{GUID: guid, Title: it.Title, Link: it.Link})
+	}
+	for _, e := range doc.Entries {
+		it := feedItem{GUID: e.ID, Title: e.Title}
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				it.Link = l.Href
+				break
+			}
+		}
+		if it.GUID == "" {
+			it.GUID = it.Link
+		}
+		items = append(items, it)
+	}
+	title := doc.Channel.Title
+	if title == "" {
+		title = doc.Title
+	}
+	return strings.TrimSpace(title), items, nil
+}
+
+// feedState remembers the GUIDs already announced per feed URL, persisted
+// so a restart neither repeats old items nor floods the room.
+type feedState struct {
+	mu   sync.Mutex
+	path string
+	Seen map[string][]string `json:"seen"`
+}
+
+func loadFeedState() (*feedState, error) {
+	path, err := statePath(feedsFile)
+	if err != nil {
+		return nil, err
+	}
+	st := &feedState{path: path, Seen: make(map[string][]string)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Seen == nil {
+		st.Seen = make(map[string][]string)
+	}
+	return st, nil
+}
+
+// fresh marks items as seen and returns the unseen ones, oldest first.
+// known reports whether the feed had been polled before.
+func (st *feedState) fresh(url string, items []feedItem) (fresh []feedItem, known bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	seen, known := st.Seen[url]
+	for i := len(items) - 1; i >= 0; i-- {
+		if it := items[i]; it.GUID != "" && !slices.Contains(seen, it.GUID) {
+			fresh = append(fresh, it)
+			seen = append(seen, it.GUID)
+		}
+	}
+	if len(seen) > feedSeenMax {
+		seen = seen[len(seen)-feedSeenMax:]
+	}
+	st.Seen[url] = seen
+	return fresh, known
+}
+
+func (st *feedState) save() error {
+	st.mu.Lock()
+	b, err := json.MarshalIndent(st, "", "  ")
+	st.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(st.path, append(b, '\n'), 0o600)
+}
+
+// runFeeds polls every feed configured for this room and announces new
+// items. A feed's first poll only announces its newest item; after that at
+// most feedBurst items are posted per poll and the rest are counted, so
+// coming back from downtime doesn't flood the room.
+func (c *Chat) runFeeds(ctx context.Context, feeds []FeedConfig) error {
+	st, err := loadFeedState()
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, f := range feeds {
+		if len(f.Rooms) > 0 && !slices.Contains(f.Rooms, c.n.room) {
+			continue
+		}
+		every, _ := f.interval()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c.pollFeed(ctx, st, f.URL)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(every):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *Chat) pollFeed(ctx context.Context, st *feedState, url string) {
+	ctx, cancel := context.WithTimeout(ctx, feedTimeout)
+	defer cancel()
+	b, err := fetch(ctx, url, feedMaxBytes)
+	if err != nil {
+		return // the next poll will do
+	}
+	title, items, err := parseFeed(b)
+	if err != nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Feed %s can't be read: %v"), url, err) + ansiReset)
+		return
+	}
+	fresh, known := st.fresh(url, items)
+	if err := st.save(); err != nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Feed state not saved: %v"), err) + ansiReset)
+	}
+	if !known && len(fresh) > 0 {
+		fresh = fresh[len(fresh)-1:]
+	}
+	if title == "" {
+		title = url
+	}
+	skipped := max(len(fresh)-feedBurst, 0)
+	for _, it := range fresh[skipped:] {
+		text := fmt.Sprintf("[%s] %s", title, strings.TrimSpace(it.Title))
+		if it.Link != "" {
+			text += " " + strings.TrimSpace(it.Link)
+		}
+		if _, err := c.post(text); err != nil {
+			return
+		}
+	}
+	if skipped > 0 {
+		c.post(fmt.Sprintf("[%s] …and %d more new items", title, skipped))
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// filters decide what the chat engine shows; they never affect what is
+// received or relayed.
+type filters struct {
+	mu           sync.Mutex
+	hideJoins    bool
+	hideNotices  bool
+	mentionsOnly bool
+	muted        []string // nicks (lower case) or peer IDs
+}
+
+func (f *filters) showJoin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.hideJoins && !f.mentionsOnly
+}
+
+func (f *filters) showNotice() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.hideNotices
+}
+
+// showMessage reports whether a chat line from nick/from is displayed.
+func (f *filters) showMessage(nick string, from peer.ID, mentioned bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if slices.Contains(f.muted, strings.ToLower(nick)) || slices.Contains(f.muted, from.String()) {
+		return false
+	}
+	return !f.mentionsOnly || mentioned
+}
+
+// report renders the active filters for /filter.
+func (f *filters) report() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	onOff := func(b bool) string {
+		if b {
+			return T("on")
+		}
+		return T("off")
+	}
+	muted := T("nobody")
+	if len(f.muted) > 0 {
+		muted = strings.Join(f.muted, ", ")
+	}
+	return fmt.Sprintf(T("Filters: hide joins %s, hide notices %s, mentions only %s, muted: %s"),
+		onOff(f.hideJoins), onOff(f.hideNotices), onOff(f.mentionsOnly), muted)
+}
+
+// runFilter implements /filter.
+//
+//	/filter
+//	/filter joins|notices|mentions on|off
+//	/filter mute|unmute <nick|peer-id>
+//	/filter clear
+func (c *Chat) runFilter(args []string) {
+	const usage = "/filter [joins|notices|mentions on|off] [mute|unmute <nick|peer-id>] [clear]"
+	f := &c.filters
+	if len(args) == 0 {
+		c.ui.Print(f.report())
+		return
+	}
+
+	f.mu.Lock()
+	ok := true
+	switch sub := strings.ToLower(args[0]); {
+	case sub == "clear" && len(args) == 1:
+		f.hideJoins, f.hideNotices, f.mentionsOnly, f.muted = false, false, false, nil
+	case (sub == "joins" || sub == "notices" || sub == "mentions") && len(args) == 2:
+		var on bool
+		switch strings.ToLower(args[1]) {
+		case "on":
+			on = true
+		case "off":
+		default:
+			ok = false
+		}
+		switch sub {
+		case "joins":
+			f.hideJoins = on
+		case "notices":
+			f.hideNotices = on
+		case "mentions":
+			f.mentionsOnly = on
+		}
+	case sub == "mute" && len(args) == 2:
+		who := muteKey(args[1])
+		if !slices.Contains(f.muted, who) {
+			f.muted = append(f.muted, who)
+		}
+	case sub == "unmute" && len(args) == 2:
+		f.muted = slices.DeleteFunc(f.muted, func(s string) bool { return s == muteKey(args[1]) })
+	default:
+		ok = false
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), usage))
+		return
+	}
+	c.ui.Print(f.report())
+}
+
+// muteKey normalises a /filter mute target: peer IDs as is, nicks lower case.
+func muteKey(s string) string {
+	if _, err := peer.Decode(s); err == nil {
+		return s
+	}
+	return strings.ToLower(s)
+}
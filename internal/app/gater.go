@@ -0,0 +1,203 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// gater is the node's libp2p connection gater. It rejects denied peers and
+// address ranges in both directions, and with an allowlist set only listed
+// peers may connect at all. Rejection happens before any protocol runs.
+type gater struct {
+	mu        sync.RWMutex
+	allow     map[peer.ID]struct{} // nil: allow everyone
+	denyPeers map[peer.ID]struct{}
+	denyNets  []*net.IPNet
+	noRelay   bool // refuse relayed connections (relay budget spent)
+}
+
+// LoadPeerList reads peer IDs from path, one per line. Blank lines and
+// anything after '#' are ignored.
+func LoadPeerList(path string) ([]peer.ID, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ids []peer.ID
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text, _, _ := strings.Cut(sc.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		id, err := peer.Decode(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid peer ID %q: %w", path, line, text, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, sc.Err()
+}
+
+// parseCIDR accepts either a CIDR range or a bare IP (treated as /32 or /128).
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+	}
+	return ipnet, nil
+}
+
+// setAllowlist restricts connections to ids.
+func (g *gater) setAllowlist(ids []peer.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.allow = make(map[peer.ID]struct{}, len(ids))
+	for _, id := range ids {
+		g.allow[id] = struct{}{}
+	}
+}
+
+// loadRules installs the deny rules from cfg.
+func (g *gater) loadRules(cfg GaterConfig) error {
+	for _, s := range cfg.DenyPeers {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return fmt.Errorf("config: invalid denied peer %q: %w", s, err)
+		}
+		g.denyPeer(id)
+	}
+	for _, s := range cfg.DenyCIDRs {
+		ipnet, err := parseCIDR(s)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		g.denyNet(ipnet)
+	}
+	return nil
+}
+
+func (g *gater) denyPeer(id peer.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.denyPeers == nil {
+		g.denyPeers = make(map[peer.ID]struct{})
+	}
+	g.denyPeers[id] = struct{}{}
+}
+
+func (g *gater) denyNet(ipnet *net.IPNet) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.denyNets = append(g.denyNets, ipnet)
+}
+
+func (g *gater) allowedPeer(p peer.ID) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if _, denied := g.denyPeers[p]; denied {
+		return false
+	}
+	if g.allow == nil {
+		return true
+	}
+	_, ok := g.allow[p]
+	return ok
+}
+
+// blockRelays makes the gater refuse all relayed connections from now on.
+func (g *gater) blockRelays() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.noRelay = true
+}
+
+func (g *gater) allowedAddr(a ma.Multiaddr) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.noRelay && isRelayed(a) {
+		return false
+	}
+	ip, err := manet.ToIP(a)
+	if err != nil {
+		return true // not an IP address (e.g. relayed); judge by peer ID
+	}
+	return !slices.ContainsFunc(g.denyNets, func(n *net.IPNet) bool { return n.Contains(ip) })
+}
+
+func (g *gater) InterceptPeerDial(p peer.ID) bool { return g.allowedPeer(p) }
+
+func (g *gater) InterceptAddrDial(p peer.ID, a ma.Multiaddr) bool {
+	return g.allowedPeer(p) && g.allowedAddr(a)
+}
+
+func (g *gater) InterceptAccept(c network.ConnMultiaddrs) bool {
+	return g.allowedAddr(c.RemoteMultiaddr())
+}
+
+func (g *gater) InterceptSecured(_ network.Direction, p peer.ID, c network.ConnMultiaddrs) bool {
+	return g.allowedPeer(p) && g.allowedAddr(c.RemoteMultiaddr())
+}
+
+func (g *gater) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) { return true, 0 }
+
+// BanIP blocks an IP or CIDR range, drops existing connections from it,
+// and records the rule in the config.
+func (n *Node) BanIP(s string) error {
+	ipnet, err := parseCIDR(s)
+	if err != nil {
+		return err
+	}
+	n.gater.denyNet(ipnet)
+	for _, c := range n.Host.Network().Conns() {
+		if !n.gater.allowedAddr(c.RemoteMultiaddr()) {
+			_ = c.Close()
+		}
+	}
+	return n.cfg.Update(func(c *Config) {
+		if !slices.Contains(c.Gater.DenyCIDRs, ipnet.String()) {
+			c.Gater.DenyCIDRs = append(c.Gater.DenyCIDRs, ipnet.String())
+		}
+	})
+}
+
+// BanPeer blocks a peer ID, disconnects it and records the rule in the config.
+func (n *Node) BanPeer(s string) error {
+	id, err := peer.Decode(s)
+	if err != nil {
+		return fmt.Errorf("invalid peer ID %q: %w", s, err)
+	}
+	n.gater.denyPeer(id)
+	_ = n.Host.Network().ClosePeer(id)
+	n.reputation.update(id, func(r *Reputation) { r.Blocked = true })
+	return n.cfg.Update(func(c *Config) {
+		if !slices.Contains(c.Gater.DenyPeers, id.String()) {
+			c.Gater.DenyPeers = append(c.Gater.DenyPeers, id.String())
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	pbv2 "github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/pb"
+)
+
+// relayStats counts relay-service activity for the serve-mode status page.
+// It implements relay.MetricsTracer.
+type relayStats struct {
+	reservations atomic.Int64
+	circuits     atomic.Int64
+	bytes        atomic.Int64
+}
+
+func (s *relayStats) RelayStatus(bool)                      {}
+func (s *relayStats) ConnectionOpened()                     { s.circuits.Add(1) }
+func (s *relayStats) ConnectionClosed(time.Duration)        { s.circuits.Add(-1) }
+func (s *relayStats) ConnectionRequestHandled(pbv2.Status)  {}
+func (s *relayStats) ReservationRequestHandled(pbv2.Status) {}
+func (s *relayStats) BytesTransferred(cnt int)              { s.bytes.Add(int64(cnt)) }
+func (s *relayStats) ReservationClosed(cnt int)             { s.reservations.Add(-int64(cnt)) }
+func (s *relayStats) ReservationAllowed(isRenewal bool) {
+	if !isRenewal {
+		s.reservations.Add(1)
+	}
+}
+
+// Status is a point-in-time summary of a serve-mode node.
+type Status struct {
+	PeerID       string   `json:"peer_id"`
+	Uptime       Uptime   `json:"uptime"` // seconds in JSON
+	Peers        int      `json:"peers"`
+	DHTPeers     int      `json:"dht_peers"`
+	Reservations int64    `json:"relay_reservations"`
+	Circuits     int64    `json:"relay_circuits"`
+	RelayedBytes int64    `json:"relayed_bytes"`
+	Addrs        []string `json:"addrs"`
+}
+
+// Status reports the node's current health figures.
+func (n *Node) Status() Status {
+	st := Status{
+		PeerID: n.Host.ID().String(),
+		Uptime: Uptime(time.Since(n.started).Round(time.Second)),
+		Peers:  len(n.Host.Network().Peers()),
+	}
+	if n.DHT != nil {
+		st.DHTPeers = n.DHT.RoutingTable().Size()
+	}
+	if n.relayStats != nil {
+		st.Reservations = n.relayStats.reservations.Load()
+		st.Circuits = n.relayStats.circuits.Load()
+		st.RelayedBytes = n.relayStats.bytes.Load()
+	}
+	for _, a := range n.visibleAddrs(n.Host.Addrs()) {
+		st.Addrs = append(st.Addrs, fmt.Sprintf("%s/p2p/%s", a, n.Host.ID()))
+	}
+	return st
+}
+
+var statusPage = template.Must(template.New("status").Parse(`<!doctype html>
+<title>quichat serve</title>
+<h1>quichat serve</h1>
+<table>
+<tr><th align=left>Peer ID</th><td><code>{{.PeerID}}</code></td></tr>
+<tr><th align=left>Uptime</th><td>{{.Uptime}}</td></tr>
+<tr><th align=left>Connected peers</th><td>{{.Peers}}</td></tr>
+<tr><th align=left>DHT routing table</th><td>{{.DHTPeers}}</td></tr>
+<tr><th align=left>Relay reservations</th><td>{{.Reservations}}</td></tr>
+<tr><th align=left>Open relay circuits</th><td>{{.Circuits}}</td></tr>
+<tr><th align=left>Relayed bytes</th><td>{{.RelayedBytes}}</td></tr>
+</table>
+<h2>Addresses</h2>
+<ul>{{range .Addrs}}<li><code>{{.}}</code></li>{{end}}</ul>
+`))
+
+// ServeHealth runs the serve-mode HTTP endpoints until ctx is cancelled:
+// /healthz (process alive), /readyz (DHT has peers) and a status page at /.
+func ServeHealth(ctx context.Context, addr string, n *Node) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if n.DHT == nil || n.DHT.RoutingTable().Size() == 0 {
+			http.Error(w, "not ready: DHT routing table is empty", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = statusPage.Execute(w, n.Status())
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
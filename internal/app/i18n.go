@@ -0,0 +1,623 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// lang is the active UI language; "en" uses the source strings as is.
+var lang = "en"
+
+// T returns the translation of an English UI string (usually a format
+// string) in the active language, falling back to English.
+func T(s string) string {
+	if tr, ok := catalogs[lang][s]; ok {
+		return tr
+	}
+	return s
+}
+
+// SetLanguage selects the UI language. An empty tag picks one from
+// LC_ALL, LC_MESSAGES or LANG, falling back to English.
+func SetLanguage(tag string) error {
+	if tag == "" {
+		for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if v := os.Getenv(env); v != "" {
+				if base := langBase(v); base == "en" || catalogs[base] != nil {
+					lang = base
+				}
+				return nil
+			}
+		}
+		return nil
+	}
+	base := langBase(tag)
+	if base != "en" && catalogs[base] == nil {
+		return fmt.Errorf("unsupported language %q (have %s)", tag, strings.Join(Languages(), ", "))
+	}
+	lang = base
+	return nil
+}
+
+// Languages lists the supported language tags.
+func Languages() []string {
+	tags := []string{"en"}
+	for tag := range catalogs {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags[1:])
+	return tags
+}
+
+// langBase reduces "es_MX.UTF-8" or "es-MX" to "es".
+func langBase(tag string) string {
+	tag = strings.ToLower(tag)
+	if i := strings.IndexAny(tag, "_-.@"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// catalogs maps language → English source string → translation.
+var catalogs = map[string]map[string]string{
+	"es": {
+		"Show this help":                                                    "Muestra esta ayuda",
+		"Leave the chat":                                                    "Sale del chat",
+		"Write a multi-line message in $EDITOR":                             "Escribe un mensaje de varias líneas en $EDITOR",
+		"Show peers currently in the room":                                  "Muestra los pares de la sala",
+		"Measure round-trip latency to all peers":                           "Mide la latencia de ida y vuelta a todos los pares",
+		"Print an invite code and QR for this room":                         "Muestra un código de invitación y un QR para esta sala",
+		"Show which relays carry my traffic":                                "Muestra qué relés llevan mi tráfico",
+		"List recent messages that mention me":                              "Lista los mensajes recientes que me mencionan",
+		"Show room activity and gossip mesh health":                         "Muestra la actividad de la sala y el estado de la malla",
+		"Show goroutines, memory, GC and open streams":                      "Muestra goroutines, memoria, GC y streams abiertos",
+		"Pin a message for everyone in the room":                            "Fija un mensaje para toda la sala",
+		"List pinned messages":                                              "Lista los mensajes fijados",
+		"Resend messages that failed to publish":                            "Reenvía los mensajes que no se pudieron publicar",
+		"/admit [nick]":                                                     "/admit [apodo]",
+		"List the lobby or let a waiting peer in (room operator)":           "Muestra la sala de espera o deja entrar a alguien (operador de la sala)",
+		"/nick [name]":                                                      "/nick [nombre]",
+		"Show or change your nick":                                          "Muestra o cambia tu apodo",
+		"/whois <nick>":                                                     "/whois <apodo>",
+		"Show a peer's ID, protocol version and supported features":         "Muestra el ID, la versión del protocolo y las funciones de un participante",
+		"Block an address range (saved to config)":                          "Bloquea un rango de direcciones (se guarda en la config)",
+		"Block a peer ID (saved to config)":                                 "Bloquea un ID de par (se guarda en la config)",
+		"/filter [rule]":                                                    "/filter [regla]",
+		"Show or change display filters (joins, notices, mentions, mute)":   "Muestra o cambia los filtros (joins, notices, mentions, mute)",
+		"/alias [name [line ; line…]]":                                      "/alias [nombre [línea ; línea…]]",
+		"List or define aliases (saved to config)":                          "Lista o define alias (se guarda en la config)",
+		"/unalias <name>":                                                   "/unalias <nombre>",
+		"Remove an alias":                                                   "Elimina un alias",
+		"/s/old/new/[g]":                                                    "/s/viejo/nuevo/[g]",
+		"Correct your last message":                                         "Corrige tu último mensaje",
+		"/schedule <when> <text>":                                           "/schedule <cuándo> <texto>",
+		"Send a message later: 09:00, 2026-10-17 09:00 or 15m":              "Envía un mensaje más tarde: 09:00, 2026-10-17 09:00 o 15m",
+		"List or cancel this room's scheduled messages":                     "Lista o cancela los mensajes programados de esta sala",
+		"/remind <me|nick> in <20m> <text>":                                 "/remind <me|apodo> in <20m> <texto>",
+		"Remind yourself, or someone in the room, later":                    "Recuérdate algo, o a alguien de la sala, más tarde",
+		"Read what arrived while no terminal was attached (daemon)":         "Lee lo que llegó mientras no había ninguna terminal conectada (daemon)",
+		"Show messages folded away during a burst":                          "Muestra los mensajes plegados durante una ráfaga",
+		"/translate <id> [lang]":                                            "/translate <id> [idioma]",
+		"Translate a message; /translate auto on|off for every message":     "Traduce un mensaje; /translate auto on|off para todos",
+		"/trust <nick|id>":                                                  "/trust <apodo|id>",
+		"Accept a peer as the owner of its nick after a key change warning": "Acepta a un participante como dueño de su apodo tras un aviso de cambio de clave",
+		"/verify <nick|id>":                                                 "/verify <apodo|id>",
+		"Mark a peer whose ID you checked in person; /unverify undoes it":   "Marca un participante cuyo ID comprobaste en persona; /unverify lo deshace",
+		"/throttle [up|down <rate>|off]":                                    "/throttle [up|down <tasa>|off]",
+		"Show or change the bandwidth limits, e.g. /throttle up 500K":       "Muestra o cambia los límites de ancho de banda, p. ej. /throttle up 500K",
+		"Available commands:":                                               "Comandos disponibles:",
+		"Session":                                                           "Sesión",
+		"Messages":                                                          "Mensajes",
+		"Room":                                                              "Sala",
+		"Peers":                                                             "Participantes",
+		"Moderation":                                                        "Moderación",
+		"Settings":                                                          "Ajustes",
+		"Diagnostics":                                                       "Diagnóstico",
+		"Stop quichat daemon and leave the room (attached terminals)":       "Detiene quichat daemon y sale de la sala (terminales conectadas)",
+		"Also: %s":                             "También: %s",
+		"/help <command> shows more about one": "/help <comando> muestra más sobre uno",
+		"/%s isn't available here":             "/%s no está disponible aquí",
+		"During a burst only the first 30 messages of each second are printed. /more shows the rest oldest first, 50 at a time; /more 200 or /more all shows more at once.":                          "Durante una ráfaga solo se muestran los primeros 30 mensajes de cada segundo. /more muestra el resto, del más antiguo al más reciente, de 50 en 50; /more 200 o /more all muestran más de una vez.",
+		"Scheduled messages are kept on disk and sent once you are back in the room. One more than ten minutes overdue by then is dropped with a notice; your own reminders are shown late instead.": "Los mensajes programados se guardan en disco y se envían cuando vuelves a la sala. Si para entonces llevan más de diez minutos de retraso se descartan con un aviso; tus propios recordatorios se muestran tarde.",
+		"\"at 09:00\" works as well as \"in 20m\". A reminder for yourself is shown only to you; one for someone else is posted to the room.":                                                        "\"at 09:00\" funciona igual que \"in 20m\". Un recordatorio para ti solo lo ves tú; uno para otra persona se publica en la sala.",
+		"Rules: joins on|off, notices on|off, mentions on|off (show only messages naming you), mute <nick|peer-id>, unmute <nick|peer-id>, clear.":                                                   "Reglas: joins on|off, notices on|off, mentions on|off (solo los mensajes que te nombran), mute <apodo|id>, unmute <apodo|id>, clear.",
+		"An alias runs its lines in order, with its arguments appended to the last one; aliases don't nest. Built-in commands can't be redefined.":                                                   "Un alias ejecuta sus líneas en orden y añade sus argumentos a la última; los alias no se anidan. Los comandos integrados no se pueden redefinir.",
+		"*** %s joined the chat ***":                     "*** %s se unió al chat ***",
+		"Pong from %s: %d ms":                            "Pong de %s: %d ms",
+		"Ping #%s: %d/%d peers responded":                "Ping #%s: respondieron %d/%d pares",
+		", min/avg/max %d/%d/%d ms":                      ", mín/media/máx %d/%d/%d ms",
+		"Nobody to ping yet":                             "Aún no hay nadie a quien hacer ping",
+		"sender clock %s ahead":                          "reloj del remitente adelantado %s",
+		"sender clock %s behind":                         "reloj del remitente atrasado %s",
+		"*** %s pinned %d message(s), /pins to list ***": "*** %s fijó %d mensaje(s), /pins para verlos ***",
+		"Peers (%d): %v":                                 "Pares (%d): %v",
+		"Invite code: %s":                                "Código de invitación: %s",
+		"Join with: quichat run --invite %s":             "Únete con: quichat run --invite %s",
+		"Usage: %s":                                      "Uso: %s",
+		"Pinned #%s":                                     "Fijado #%s",
+		"Unknown command: %s":                            "Comando desconocido: %s",
+		"Ban failed: %v":                                 "No se pudo bloquear: %v",
+		"Banned %s":                                      "Bloqueado %s",
+		"/%s is a built-in command":                      "/%s es un comando integrado",
+		"No alias /%s":                                   "No existe el alias /%s",
+		"Saving alias failed: %v":                        "No se pudo guardar el alias: %v",
+		"Removing alias failed: %v":                      "No se pudo eliminar el alias: %v",
+		"Removed /%s":                                    "Eliminado /%s",
+		"No aliases defined":                             "No hay alias definidos",
+		"Aliases:":                                       "Alias:",
+		"No mentions yet":                                "Aún no hay menciones",
+		"Mentions (%d):":                                 "Menciones (%d):",
+		"No pinned messages":                             "No hay mensajes fijados",
+		"Pinned (%d):":                                   "Fijados (%d):",
+		"Daemon shutting down":                           "El daemon se está cerrando",
+		"Send failed: %v":                                "Error al enviar: %v",
+		"Daemon closed the connection":                   "El daemon cerró la conexión",
+		"Identified %s (%s)":                             "Identificado %s (%s)",
+		"Your multiaddrs:":                               "Tus multiaddrs:",
+		"Your multiaddrs changed:":                       "Tus multiaddrs cambiaron:",
+		"You are now publicly reachable":                 "Ahora eres accesible públicamente",
+		"You are behind a NAT; peers will reach you through a relay":   "Estás detrás de un NAT; los pares te alcanzarán a través de un relé",
+		"No longer using any relay":                                    "Ya no se usa ningún relé",
+		"Using relay %s":                                               "Usando el relé %s",
+		"Relay budget of %d MB used up; relayed connections closed":    "Se agotó el presupuesto de relé de %d MB; conexiones por relé cerradas",
+		"Not using any relay.":                                         "No se usa ningún relé.",
+		"warning: port %d is in use, listening on %d instead":          "aviso: el puerto %d está en uso, escuchando en %d",
+		"Listening on %s":                                              "Escuchando en %s",
+		"Welcome to P2P Quichat! 🚀":                                    "¡Bienvenido a P2P Quichat! 🚀",
+		"send %d lines as one message? [y/N] ":                         "¿enviar %d líneas como un solo mensaje? [y/N] ",
+		"Paste discarded":                                              "Pegado descartado",
+		"Compose failed: %v":                                           "Error al redactar: %v",
+		"Compose cancelled (empty message)":                            "Redacción cancelada (mensaje vacío)",
+		"👋  Bye!":                                                      "👋  ¡Adiós!",
+		"Quichat %s is available (you have %s) – run `quichat update`": "Quichat %s está disponible (tienes %s); ejecuta `quichat update`",
+		"on":     "sí",
+		"off":    "no",
+		"nobody": "nadie",
+		"Filters: hide joins %s, hide notices %s, mentions only %s, muted: %s": "Filtros: ocultar entradas %s, ocultar avisos %s, solo menciones %s, silenciados: %s",
+		"Room %s": "Sala %s",
+		"Messages: %d last minute, %d last 10 minutes, %d last hour": "Mensajes: %d en el último minuto, %d en los últimos 10 minutos, %d en la última hora",
+		"Most active: %s": "Más activos: %s",
+		"Mesh: %d peers grafted, %d in topic; %d grafts, %d prunes":        "Malla: %d pares injertados, %d en el tema; %d grafts, %d prunes",
+		"Gossip: IHAVE %d sent / %d received, IWANT %d sent / %d received": "Gossip: IHAVE %d enviados / %d recibidos, IWANT %d enviados / %d recibidos",
+		"Goroutines: %d":                            "Goroutines: %d",
+		"Sending #%s failed (%v), retrying…":        "Falló el envío de #%s (%v), reintentando…",
+		"✗ #%s not sent: %v (/retry to try again)":  "✗ #%s no enviado: %v (/retry para reintentar)",
+		"too many messages waiting to be sent":      "demasiados mensajes esperando a enviarse",
+		"Nothing to retry":                          "Nada que reintentar",
+		"Retrying %d message(s)":                    "Reintentando %d mensaje(s)",
+		"Waiting for peers… (%d queued)":            "Esperando a otros participantes… (%d en cola)",
+		"Peers found, sending %d queued message(s)": "Participantes encontrados, enviando %d mensaje(s) en cola",
+		"Publish failed: %v":                        "Falló la publicación: %v",
+		"Inbox: %d/%d waiting to be shown, %d dropped because the display fell behind": "Bandeja: %d/%d esperando a mostrarse, %d descartados porque la pantalla se quedó atrás",
+		"Internal error while handling %s: %v (crash report: %s); carrying on":         "Error interno al procesar %s: %v (informe de fallo: %s); se continúa",
+		"incoming message":  "un mensaje entrante",
+		"command":           "un comando",
+		"client connection": "una conexión de cliente",
+		"Heap: %s in use, %s reserved, %d objects":                       "Heap: %s en uso, %s reservados, %d objetos",
+		"GC: %d cycles, %v total pause, last pause %v":                   "GC: %d ciclos, %v de pausa total, última pausa %v",
+		"Streams: %d open on %d connections":                             "Streams: %d abiertos en %d conexiones",
+		"Messages: %d delivered, %d duplicates, %d rejected, %d dropped": "Mensajes: %d entregados, %d duplicados, %d rechazados, %d descartados",
+		"Connecting to bootstrap peers":                                  "Conectando con los nodos de arranque",
+		"Bootstrapping DHT":                                              "Arrancando la DHT",
+		"Joining room":                                                   "Entrando en la sala",
+		"Starting gossip router":                                         "Iniciando el enrutador gossip",
+		"still dialing; check the address, or try --proxy":               "sigue marcando; revisa la dirección o prueba --proxy",
+		"waiting for the bootstrap peer to share its routing table":      "esperando a que el nodo de arranque comparta su tabla de rutas",
+		"%d peers": "%d pares",
+		"ok":       "ok",
+		"failed":   "falló",
+		"Only the room operator (started with --max-members) can admit peers":                                                "Solo el operador de la sala (iniciado con --max-members) puede admitir participantes",
+		"Room has %d/%d members, nobody is waiting":                                                                          "La sala tiene %d/%d miembros, nadie está esperando",
+		"Room has %d/%d members, %d waiting:":                                                                                "La sala tiene %d/%d miembros, %d esperando:",
+		"*** You are in the lobby: the room is full (%d/%d). You can read along and speak once the operator admits you. ***": "*** Estás en la sala de espera: la sala está llena (%d/%d). Puedes leer y podrás hablar cuando el operador te admita. ***",
+		"*** You have been admitted to the room ***":                                                                         "*** Has sido admitido en la sala ***",
+		"Admitted %s": "Admitido %s",
+		"*** %s is waiting in the lobby (/admit %s) ***":                    "*** %s está en la sala de espera (/admit %s) ***",
+		"Not sent: you are in the lobby until the room operator admits you": "No enviado: estás en la sala de espera hasta que el operador te admita",
+		"No peer called %s has announced itself":                            "Ningún participante llamado %s se ha anunciado",
+		"%s is %s, last heard %s ago":                                       "%s es %s, visto por última vez hace %s",
+		"Capabilities: unknown (client predates capability announcements)":  "Capacidades: desconocidas (cliente anterior a los anuncios de capacidades)",
+		"Protocol: %s":      "Protocolo: %s",
+		"Capabilities:":     "Capacidades:",
+		"protobuf envelope": "sobre protobuf",
+		"pins":              "fijados",
+		"waiting room":      "sala de espera",
+		"files":             "archivos",
+		"e2e DMs":           "MD cifrados",
+		"reactions":         "reacciones",
+		"cover traffic":     "tráfico de cobertura",
+		"%s runs a client without waiting rooms and won't be told to wait":                         "%s usa un cliente sin sala de espera y no sabrá que debe esperar",
+		"Ignoring messages from %s (%s): it speaks protocol %s, this client speaks %s":             "Se ignoran los mensajes de %s (%s): usa el protocolo %s y este cliente el %s",
+		"Most peers in this room run protocol %s, newer than this client's %s; run quichat update": "La mayoría de la sala usa el protocolo %s, más nuevo que el %s de este cliente; ejecuta quichat update",
+		"Can't read messages from %s: they use a newer message format; run quichat update":         "No se pueden leer los mensajes de %s: usan un formato más nuevo; ejecuta quichat update",
+		"Transcript not written: %v":                            "No se escribió la transcripción: %v",
+		"Webhook %s failed: %v":                                 "El webhook %s falló: %v",
+		"Accepting messages at http://%s/api/rooms/%s/messages": "Se aceptan mensajes en http://%s/api/rooms/%s/messages",
+		"MQTT: %v; reconnecting in %s":                          "MQTT: %v; reconectando en %s",
+		"MQTT: connected to %s":                                 "MQTT: conectado a %s",
+		"MQTT: message on %s not posted: %v":                    "MQTT: el mensaje de %s no se publicó: %v",
+		"Feed %s can't be read: %v":                             "No se puede leer el feed %s: %v",
+		"Feed state not saved: %v":                              "No se guardó el estado de los feeds: %v",
+		"XMPP: serving the room as %s":                          "XMPP: sirviendo la sala como %s",
+		"XMPP: %v; reconnecting in %s":                          "XMPP: %v; reconectando en %s",
+		"Nostr: %v; reconnecting in %s":                         "Nostr: %v; reconectando en %s",
+		"Nostr: connected to %s, relaying notes tagged #%s":     "Nostr: conectado a %s, retransmitiendo notas con la etiqueta #%s",
+		"Nostr: %s posts as %s":                                 "Nostr: %s publica como %s",
+		"Nostr: relay refused a note: %s":                       "Nostr: el relé rechazó una nota: %s",
+		"Nostr: relay says: %s":                                 "Nostr: el relé dice: %s",
+		"Nostr: note %s not posted: %v":                         "Nostr: la nota %s no se publicó: %v",
+		"Serving the transcript at http://%s/api/history":       "Sirviendo la transcripción en http://%s/api/history",
+		"corrects #%s":                                          "corrige #%s",
+		"Nothing to correct: you haven't sent a message yet":    "Nada que corregir: aún no has enviado ningún mensaje",
+		"%q is not in your last message":                        "%q no está en tu último mensaje",
+		"message corrections":                                   "correcciones de mensajes",
+		"Not scheduled: %v":                                     "No programado: %v",
+		"Scheduled #%s for %s":                                  "Programado #%s para %s",
+		"No scheduled message #%s in this room":                 "No hay ningún mensaje programado #%s en esta sala",
+		"Cancelled #%s":                                         "Cancelado #%s",
+		"No scheduled messages in this room":                    "No hay mensajes programados en esta sala",
+		"Scheduled messages (%d):":                              "Mensajes programados (%d):",
+		"Scheduled messages can't be read: %v":                  "No se pueden leer los mensajes programados: %v",
+		"Scheduled message #%s was due at %s and not sent: %s":  "El mensaje programado #%s debía enviarse a las %s y no se envió: %s",
+		"Reminder #%s for %s at %s":                             "Recordatorio #%s para %s a las %s",
+		"(due %s)":                                              "(previsto para %s)",
+		"Reminder: %s":                                          "Recordatorio: %s",
+		"reminder for %s: %s":                                   "recordatorio para %s: %s",
+		"While you were away (%s): %d messages in %s from %s, %d mentions; /catchup to read them":     "Mientras no estabas (%s): %d mensajes en %s de %s, %d menciones; /catchup para leerlos",
+		"Nothing to catch up on: /catchup covers the time no terminal was attached to quichat daemon": "Nada que ponerse al día: /catchup cubre el tiempo en que ninguna terminal estaba conectada a quichat daemon",
+		"Missed between %s and %s (%d):":                          "Perdidos entre %s y %s (%d):",
+		"…and %d more not kept":                                   "…y %d más que no se guardaron",
+		"No translation backend: set translate.url in the config": "No hay servicio de traducción: configura translate.url",
+		"Translating every message in this room into %s":          "Traduciendo todos los mensajes de esta sala a %s",
+		"Auto-translation off":                                    "Traducción automática desactivada",
+		"Too many translations waiting, try again shortly":        "Demasiadas traducciones en espera, inténtalo en un momento",
+		"Translation of #%s failed: %v":                           "La traducción de #%s falló: %v",
+		"%s says: %s":                                             "%s dice: %s",
+		"Text to speech failed: %v":                               "La síntesis de voz falló: %v",
+		"Text to speech is off: %v":                               "Síntesis de voz desactivada: %v",
+		"From %s at %s: %s":                                       "De %s a las %s: %s",
+		"Mention from %s at %s: %s":                               "Mención de %s a las %s: %s",
+		"public":                                                  "pública",
+		"NAT, via relay":                                          "NAT, por relay",
+		"NAT, no relay yet":                                       "NAT, aún sin relay",
+		"reachability unknown":                                    "accesibilidad desconocida",
+		"%d unread":                                               "%d sin leer",
+		"%d mention(s)":                                           "%d mención(es)",
+		"Recording stopped: %v":                                   "Grabación detenida: %v",
+		"Replaying %s in %s, recorded %s by quichat %s":           "Reproduciendo a %s en %s, grabado el %s con quichat %s",
+		"✗ Not sent: the message is %d KiB, the limit is %d KiB":  "✗ No enviado: el mensaje ocupa %d KiB y el límite es %d KiB",
+		"message too long":                                        "mensaje demasiado largo",
+		"… %d more lines not shown":                               "… %d líneas más sin mostrar",
+		"Several peers go by %s; this one is shown as %s":         "Varios participantes se llaman %s; este aparece como %s",
+		"%s matches several peers (%s); add the ~tag shown after the nick": "%s coincide con varios participantes (%s); añade la ~etiqueta que aparece tras el apodo",
+		"%d of %d connected": "%d de %d conectados",
+		"LAN discovery is off (%v); peers must be given with --bootstrap": "El descubrimiento en la LAN está desactivado (%v); indica los participantes con --bootstrap",
+		"Room advertisement failed: %v; retrying in %s":                   "Falló el anuncio de la sala: %v; se reintenta en %s",
+		"Advertised the room in the DHT; again in %s":                     "Sala anunciada en la DHT; de nuevo en %s",
+		"Found %d room members in the DHT, dialing %d":                    "Encontrados %d miembros de la sala en la DHT, conectando con %d",
+		"Room advertisement: not yet":                                     "Anuncio de la sala: todavía no",
+		"Room advertisement: failing (%v), next try in %s":                "Anuncio de la sala: fallando (%v), próximo intento en %s",
+		"Room advertisement: %d so far, last %s ago, next in %s":          "Anuncio de la sala: %d hasta ahora, el último hace %s, el próximo en %s",
+		" (last attempt failed: %v)":                                      " (el último intento falló: %v)",
+		"Room lookup: %d members found, %d dialed, %s ago":                "Búsqueda de la sala: %d miembros encontrados, %d contactados, hace %s",
+		"First seen %s, last seen %s, %d messages":                        "Visto por primera vez %s, por última vez %s, %d mensajes",
+		"Spam score: %.1f (throttled at %.0f)":                            "Puntuación de spam: %.1f (se limita a partir de %.0f)",
+		"Throttled until %s (%d times so far)":                            "Limitado hasta %s (%d veces hasta ahora)",
+		"Throttled %d times before":                                       "Limitado %d veces antes",
+		"Blocked":                                                         "Bloqueado",
+		"Verified":                                                        "Verificado",
+		"Hiding messages from %s until %s: too many too fast":             "Se ocultan los mensajes de %s hasta las %s: demasiados y demasiado rápido",
+		"%s (%s) is marked as verified":                                   "%s (%s) queda marcado como verificado",
+		"%s (%s) is no longer marked as verified":                         "%s (%s) ya no está marcado como verificado",
+		"unlimited": "sin límite",
+		"Upload: %s (now %s)\n  Download: %s (now %s)":                                                "Subida: %s (ahora %s)\n  Bajada: %s (ahora %s)",
+		"The transcript is using %s of its %s quota; its oldest entries will be deleted to make room": "La transcripción ocupa %s de su cuota de %s; sus entradas más antiguas se borrarán para dejar sitio",
+		"Transcript quota of %s reached: deleted %s, its oldest part":                                 "Cuota de %s de la transcripción alcanzada: se borró %s, su parte más antigua",
+		"… %d more messages (%s), /more to show them":                                                 "… %d mensajes más (%s), /more para verlos",
+		"%d older messages were not kept":                                                             "No se guardaron %d mensajes más antiguos",
+		"No more messages":                                                                            "No hay más mensajes",
+		"… %d more, /more to continue":                                                                "… %d más, /more para seguir",
+		"*** %s is now known as %s ***":                                                               "*** %s ahora se llama %s ***",
+		"*** You are now known as %s (were %s) ***":                                                   "*** Ahora te llamas %s (antes %s) ***",
+		"You are %s": "Eres %s",
+		"Invalid nick: use up to %d bytes, no control characters or ~": "Apodo no válido: usa hasta %d bytes, sin caracteres de control ni ~",
+		"Formerly known as: %s": "Antes conocido como: %s",
+		"%s, now %s":            "%s, ahora %s",
+		"⚠ WARNING: %s is not the %s first seen in this room (%s, now %s). It may be an impostor. If they changed keys, check with them and /trust %s": "⚠ AVISO: %s no es el %s visto por primera vez en esta sala (%s, ahora %s). Puede ser un impostor. Si cambió de clave, compruébalo con esa persona y usa /trust %s",
+		"not the %s first seen here":        "no es el %s visto aquí primero",
+		"Trust not saved: %v":               "No se guardó la confianza: %v",
+		"%s now belongs to %s in this room": "%s pertenece ahora a %s en esta sala",
+		"stable key":                        "clave estable",
+		"An address looks like /ip4/203.0.113.7/udp/4001/quic-v1/p2p/12D3KooW…; copy it whole from the \"Listening on\" lines the other node prints, or use --invite": "Una dirección tiene esta forma: /ip4/203.0.113.7/udp/4001/quic-v1/p2p/12D3KooW…; cópiala entera de las líneas \"Escuchando en\" que muestra el otro nodo, o usa --invite",
+		"Is the bootstrap node running? Check the address, try --bootstrap-retries 3, or --proxy if a firewall is in the way":                                         "¿Está en marcha el nodo de arranque? Revisa la dirección, prueba --bootstrap-retries 3, o --proxy si hay un cortafuegos de por medio",
+		"The peer that made this invite may have left; ask someone in the room for a fresh /invite":                                                                   "Quien creó esta invitación puede haberse ido; pide a alguien de la sala una /invite nueva",
+		"Pick another with --listen, or --listen 0 for any free port; another quichat may be running (quichat status)":                                                "Elige otro con --listen, o --listen 0 para cualquier puerto libre; puede que ya haya otro quichat en marcha (quichat status)",
+		"Nobody else is here yet: share /invite, or start with --bootstrap or --invite to join someone":                                                               "Aún no hay nadie más: comparte /invite, o arranca con --bootstrap o --invite para unirte a alguien",
+		"The bootstrap peer answered but nobody else is in this room; check that everyone uses the same room and --network":                                           "El par de arranque respondió pero no hay nadie más en esta sala; comprueba que todos usáis la misma sala y el mismo --network",
+		"No peers in the room after %s":           "Ningún par en la sala tras %s",
+		"*** %s left the chat ***":                "*** %s salió del chat ***",
+		"✗ %d message(s) not sent before leaving": "✗ %d mensaje(s) sin enviar al salir",
+		"leave notices":                           "avisos de salida",
+		"QUIC, WebTransport and WebRTC aren't limited: start with --max-upload or --max-download to limit them too": "QUIC, WebTransport y WebRTC no tienen límite: arranca con --max-upload o --max-download para limitarlos también",
+	},
+	"hi": {
+		"Show this help":                                                    "यह सहायता दिखाएँ",
+		"Leave the chat":                                                    "चैट छोड़ें",
+		"Write a multi-line message in $EDITOR":                             "$EDITOR में कई पंक्तियों वाला संदेश लिखें",
+		"Show peers currently in the room":                                  "इस रूम के पीयर दिखाएँ",
+		"Measure round-trip latency to all peers":                           "सभी पीयर तक राउंड-ट्रिप विलंब मापें",
+		"Print an invite code and QR for this room":                         "इस रूम का आमंत्रण कोड और QR दिखाएँ",
+		"Show which relays carry my traffic":                                "दिखाएँ कि कौन से रिले मेरा ट्रैफ़िक ले जा रहे हैं",
+		"List recent messages that mention me":                              "हाल के संदेश जिनमें मेरा नाम है",
+		"Show room activity and gossip mesh health":                         "रूम की गतिविधि और मेश की स्थिति दिखाएँ",
+		"Show goroutines, memory, GC and open streams":                      "गोरूटीन, मेमोरी, GC और खुली स्ट्रीम दिखाएँ",
+		"Pin a message for everyone in the room":                            "पूरे रूम के लिए संदेश पिन करें",
+		"List pinned messages":                                              "पिन किए गए संदेश दिखाएँ",
+		"Resend messages that failed to publish":                            "जो संदेश प्रकाशित नहीं हो सके उन्हें फिर से भेजें",
+		"List the lobby or let a waiting peer in (room operator)":           "लॉबी देखें या इंतज़ार कर रहे साथी को अंदर आने दें (रूम ऑपरेटर)",
+		"/nick [name]":                                                      "/nick [नाम]",
+		"Show or change your nick":                                          "अपना निक देखें या बदलें",
+		"Show a peer's ID, protocol version and supported features":         "किसी साथी की ID, प्रोटोकॉल संस्करण और समर्थित सुविधाएँ दिखाएँ",
+		"Block an address range (saved to config)":                          "पता-श्रेणी ब्लॉक करें (कॉन्फ़िग में सहेजा जाता है)",
+		"Block a peer ID (saved to config)":                                 "पीयर ID ब्लॉक करें (कॉन्फ़िग में सहेजा जाता है)",
+		"/filter [rule]":                                                    "/filter [नियम]",
+		"Show or change display filters (joins, notices, mentions, mute)":   "डिस्प्ले फ़िल्टर दिखाएँ या बदलें (joins, notices, mentions, mute)",
+		"/alias [name [line ; line…]]":                                      "/alias [नाम [पंक्ति ; पंक्ति…]]",
+		"List or define aliases (saved to config)":                          "उपनाम दिखाएँ या बनाएँ (कॉन्फ़िग में सहेजा जाता है)",
+		"/unalias <name>":                                                   "/unalias <नाम>",
+		"Remove an alias":                                                   "उपनाम हटाएँ",
+		"/s/old/new/[g]":                                                    "/s/पुराना/नया/[g]",
+		"Correct your last message":                                         "अपना पिछला संदेश सुधारें",
+		"/schedule <when> <text>":                                           "/schedule <कब> <पाठ>",
+		"Send a message later: 09:00, 2026-10-17 09:00 or 15m":              "संदेश बाद में भेजें: 09:00, 2026-10-17 09:00 या 15m",
+		"List or cancel this room's scheduled messages":                     "इस रूम के निर्धारित संदेश देखें या रद्द करें",
+		"/remind <me|nick> in <20m> <text>":                                 "/remind <me|उपनाम> in <20m> <पाठ>",
+		"Remind yourself, or someone in the room, later":                    "बाद में खुद को, या रूम में किसी को, याद दिलाएँ",
+		"Read what arrived while no terminal was attached (daemon)":         "जब कोई टर्मिनल जुड़ा नहीं था तब क्या आया, पढ़ें (daemon)",
+		"Show messages folded away during a burst":                          "किसी बौछार के दौरान समेटे गए संदेश दिखाएँ",
+		"/translate <id> [lang]":                                            "/translate <id> [भाषा]",
+		"Translate a message; /translate auto on|off for every message":     "संदेश का अनुवाद करें; सभी के लिए /translate auto on|off",
+		"/trust <nick|id>":                                                  "/trust <निक|id>",
+		"Accept a peer as the owner of its nick after a key change warning": "कुंजी बदलने की चेतावनी के बाद साथी को उसके निक का मालिक मानें",
+		"/verify <nick|id>":                                                 "/verify <निक|id>",
+		"Mark a peer whose ID you checked in person; /unverify undoes it":   "उस साथी को सत्यापित चिह्नित करें जिसकी ID आपने खुद जाँची; /unverify इसे हटाता है",
+		"/throttle [up|down <rate>|off]":                                    "/throttle [up|down <दर>|off]",
+		"Show or change the bandwidth limits, e.g. /throttle up 500K":       "बैंडविड्थ सीमाएँ दिखाएँ या बदलें, जैसे /throttle up 500K",
+		"Available commands:":                                               "उपलब्ध कमांड:",
+		"Session":                                                           "सत्र",
+		"Messages":                                                          "संदेश",
+		"Room":                                                              "रूम",
+		"Peers":                                                             "साथी",
+		"Moderation":                                                        "मॉडरेशन",
+		"Settings":                                                          "सेटिंग्स",
+		"Diagnostics":                                                       "जाँच",
+		"Stop quichat daemon and leave the room (attached terminals)":       "quichat daemon रोकें और रूम छोड़ें (जुड़े टर्मिनल)",
+		"Also: %s":                             "यह भी: %s",
+		"/help <command> shows more about one": "/help <कमांड> किसी एक के बारे में और बताता है",
+		"/%s isn't available here":             "/%s यहाँ उपलब्ध नहीं है",
+		"During a burst only the first 30 messages of each second are printed. /more shows the rest oldest first, 50 at a time; /more 200 or /more all shows more at once.":                          "बौछार के दौरान हर सेकंड के पहले 30 संदेश ही दिखाए जाते हैं। /more बाकी संदेश, सबसे पुराने पहले, 50-50 करके दिखाता है; /more 200 या /more all एक साथ ज़्यादा दिखाते हैं।",
+		"Scheduled messages are kept on disk and sent once you are back in the room. One more than ten minutes overdue by then is dropped with a notice; your own reminders are shown late instead.": "निर्धारित संदेश डिस्क पर रखे जाते हैं और रूम में लौटने पर भेजे जाते हैं। तब तक दस मिनट से ज़्यादा देर हो चुकी हो तो संदेश सूचना के साथ छोड़ दिया जाता है; आपके अपने रिमाइंडर देर से दिखाए जाते हैं।",
+		"\"at 09:00\" works as well as \"in 20m\". A reminder for yourself is shown only to you; one for someone else is posted to the room.":                                                        "\"in 20m\" की तरह \"at 09:00\" भी चलता है। आपके लिए रिमाइंडर सिर्फ़ आपको दिखता है; किसी और के लिए रिमाइंडर रूम में भेजा जाता है।",
+		"Rules: joins on|off, notices on|off, mentions on|off (show only messages naming you), mute <nick|peer-id>, unmute <nick|peer-id>, clear.":                                                   "नियम: joins on|off, notices on|off, mentions on|off (सिर्फ़ वे संदेश जिनमें आपका नाम है), mute <निक|id>, unmute <निक|id>, clear.",
+		"An alias runs its lines in order, with its arguments appended to the last one; aliases don't nest. Built-in commands can't be redefined.":                                                   "उपनाम अपनी पंक्तियाँ क्रम से चलाता है और उसके आर्ग्युमेंट आख़िरी पंक्ति में जुड़ते हैं; उपनाम एक-दूसरे में नहीं चलते। बिल्ट-इन कमांड दोबारा परिभाषित नहीं की जा सकतीं।",
+		"*** %s joined the chat ***":                     "*** %s चैट में शामिल हुए ***",
+		"Pong from %s: %d ms":                            "%s से पोंग: %d ms",
+		"Ping #%s: %d/%d peers responded":                "पिंग #%s: %d/%d पीयर ने जवाब दिया",
+		", min/avg/max %d/%d/%d ms":                      ", न्यून/औसत/अधिक %d/%d/%d ms",
+		"Nobody to ping yet":                             "अभी पिंग करने के लिए कोई नहीं है",
+		"sender clock %s ahead":                          "भेजने वाले की घड़ी %s आगे",
+		"sender clock %s behind":                         "भेजने वाले की घड़ी %s पीछे",
+		"*** %s pinned %d message(s), /pins to list ***": "*** %s ने %d संदेश पिन किए, सूची के लिए /pins ***",
+		"Peers (%d): %v":                                 "पीयर (%d): %v",
+		"Invite code: %s":                                "आमंत्रण कोड: %s",
+		"Join with: quichat run --invite %s":             "जुड़ने के लिए: quichat run --invite %s",
+		"Usage: %s":                                      "उपयोग: %s",
+		"Pinned #%s":                                     "पिन किया #%s",
+		"Unknown command: %s":                            "अज्ञात कमांड: %s",
+		"Ban failed: %v":                                 "ब्लॉक विफल: %v",
+		"Banned %s":                                      "%s ब्लॉक किया गया",
+		"/%s is a built-in command":                      "/%s एक अंतर्निहित कमांड है",
+		"No alias /%s":                                   "/%s नाम का कोई उपनाम नहीं",
+		"Saving alias failed: %v":                        "उपनाम सहेजना विफल: %v",
+		"Removing alias failed: %v":                      "उपनाम हटाना विफल: %v",
+		"Removed /%s":                                    "/%s हटाया गया",
+		"No aliases defined":                             "कोई उपनाम परिभाषित नहीं",
+		"Aliases:":                                       "उपनाम:",
+		"No mentions yet":                                "अभी तक कोई उल्लेख नहीं",
+		"Mentions (%d):":                                 "उल्लेख (%d):",
+		"No pinned messages":                             "कोई पिन किया गया संदेश नहीं",
+		"Pinned (%d):":                                   "पिन किए गए (%d):",
+		"Daemon shutting down":                           "डेमन बंद हो रहा है",
+		"Send failed: %v":                                "भेजना विफल: %v",
+		"Daemon closed the connection":                   "डेमन ने कनेक्शन बंद कर दिया",
+		"Identified %s (%s)":                             "%s की पहचान हुई (%s)",
+		"Your multiaddrs:":                               "आपके multiaddr:",
+		"Your multiaddrs changed:":                       "आपके multiaddr बदल गए:",
+		"You are now publicly reachable":                 "अब आप सार्वजनिक रूप से पहुँच योग्य हैं",
+		"You are behind a NAT; peers will reach you through a relay":   "आप NAT के पीछे हैं; पीयर रिले के ज़रिए आप तक पहुँचेंगे",
+		"No longer using any relay":                                    "अब कोई रिले उपयोग में नहीं",
+		"Using relay %s":                                               "रिले %s उपयोग में",
+		"Relay budget of %d MB used up; relayed connections closed":    "%d MB का रिले बजट समाप्त; रिले वाले कनेक्शन बंद किए गए",
+		"Not using any relay.":                                         "कोई रिले उपयोग में नहीं।",
+		"warning: port %d is in use, listening on %d instead":          "चेतावनी: पोर्ट %d उपयोग में है, इसके बजाय %d पर सुन रहे हैं",
+		"Listening on %s":                                              "%s पर सुन रहे हैं",
+		"Welcome to P2P Quichat! 🚀":                                    "P2P Quichat में आपका स्वागत है! 🚀",
+		"send %d lines as one message? [y/N] ":                         "%d पंक्तियाँ एक संदेश के रूप में भेजें? [y/N] ",
+		"Paste discarded":                                              "पेस्ट रद्द किया गया",
+		"Compose failed: %v":                                           "संदेश लिखना विफल: %v",
+		"Compose cancelled (empty message)":                            "लिखना रद्द (खाली संदेश)",
+		"👋  Bye!":                                                      "👋  अलविदा!",
+		"Quichat %s is available (you have %s) – run `quichat update`": "Quichat %s उपलब्ध है (आपके पास %s है) – `quichat update` चलाएँ",
+		"on":     "चालू",
+		"off":    "बंद",
+		"nobody": "कोई नहीं",
+		"Filters: hide joins %s, hide notices %s, mentions only %s, muted: %s": "फ़िल्टर: जुड़ना छिपाएँ %s, सूचनाएँ छिपाएँ %s, केवल उल्लेख %s, म्यूट: %s",
+		"Room %s": "रूम %s",
+		"Messages: %d last minute, %d last 10 minutes, %d last hour": "संदेश: पिछले मिनट %d, पिछले 10 मिनट %d, पिछले घंटे %d",
+		"Most active: %s": "सबसे सक्रिय: %s",
+		"Mesh: %d peers grafted, %d in topic; %d grafts, %d prunes":        "मेश: %d पीयर ग्राफ़्ट, टॉपिक में %d; %d graft, %d prune",
+		"Gossip: IHAVE %d sent / %d received, IWANT %d sent / %d received": "गॉसिप: IHAVE %d भेजे / %d मिले, IWANT %d भेजे / %d मिले",
+		"Goroutines: %d":                            "गोरूटीन: %d",
+		"Sending #%s failed (%v), retrying…":        "#%s भेजना विफल (%v), फिर कोशिश कर रहे हैं…",
+		"✗ #%s not sent: %v (/retry to try again)":  "✗ #%s नहीं भेजा गया: %v (फिर कोशिश के लिए /retry)",
+		"too many messages waiting to be sent":      "भेजे जाने की प्रतीक्षा में बहुत सारे संदेश",
+		"Nothing to retry":                          "दोबारा भेजने के लिए कुछ नहीं",
+		"Retrying %d message(s)":                    "%d संदेश फिर से भेज रहे हैं",
+		"Waiting for peers… (%d queued)":            "साथियों का इंतज़ार… (%d कतार में)",
+		"Peers found, sending %d queued message(s)": "साथी मिल गए, कतार के %d संदेश भेज रहे हैं",
+		"Publish failed: %v":                        "प्रकाशन विफल: %v",
+		"Inbox: %d/%d waiting to be shown, %d dropped because the display fell behind": "इनबॉक्स: %d/%d दिखाए जाने की प्रतीक्षा में, %d छोड़े गए क्योंकि डिस्प्ले पीछे रह गया",
+		"Internal error while handling %s: %v (crash report: %s); carrying on":         "%s संभालते समय आंतरिक त्रुटि: %v (क्रैश रिपोर्ट: %s); सत्र जारी है",
+		"incoming message":  "आने वाला संदेश",
+		"command":           "कमांड",
+		"client connection": "क्लाइंट कनेक्शन",
+		"Heap: %s in use, %s reserved, %d objects":                       "हीप: %s उपयोग में, %s आरक्षित, %d ऑब्जेक्ट",
+		"GC: %d cycles, %v total pause, last pause %v":                   "GC: %d चक्र, कुल विराम %v, पिछला विराम %v",
+		"Streams: %d open on %d connections":                             "स्ट्रीम: %d खुली, %d कनेक्शनों पर",
+		"Messages: %d delivered, %d duplicates, %d rejected, %d dropped": "संदेश: %d पहुँचे, %d डुप्लिकेट, %d अस्वीकृत, %d छोड़े गए",
+		"Connecting to bootstrap peers":                                  "बूटस्ट्रैप पीयर्स से जुड़ रहे हैं",
+		"Bootstrapping DHT":                                              "DHT शुरू कर रहे हैं",
+		"Joining room":                                                   "रूम में शामिल हो रहे हैं",
+		"Starting gossip router":                                         "गॉसिप राउटर शुरू कर रहे हैं",
+		"still dialing; check the address, or try --proxy":               "अभी भी डायल कर रहे हैं; पता जाँचें या --proxy आज़माएँ",
+		"waiting for the bootstrap peer to share its routing table":      "बूटस्ट्रैप पीयर की रूटिंग टेबल का इंतज़ार",
+		"%d peers": "%d पीयर",
+		"ok":       "ठीक",
+		"failed":   "विफल",
+		"Only the room operator (started with --max-members) can admit peers":                                                "केवल रूम ऑपरेटर (--max-members के साथ शुरू किया गया) साथियों को अंदर आने दे सकता है",
+		"Room has %d/%d members, nobody is waiting":                                                                          "रूम में %d/%d सदस्य हैं, कोई इंतज़ार नहीं कर रहा",
+		"Room has %d/%d members, %d waiting:":                                                                                "रूम में %d/%d सदस्य हैं, %d इंतज़ार में:",
+		"*** You are in the lobby: the room is full (%d/%d). You can read along and speak once the operator admits you. ***": "*** आप लॉबी में हैं: रूम भरा है (%d/%d)। आप पढ़ सकते हैं, और ऑपरेटर के अंदर आने देने पर बोल सकेंगे। ***",
+		"*** You have been admitted to the room ***":                                                                         "*** आपको रूम में आने दिया गया है ***",
+		"Admitted %s": "%s को अंदर आने दिया",
+		"*** %s is waiting in the lobby (/admit %s) ***":                    "*** %s लॉबी में इंतज़ार कर रहे हैं (/admit %s) ***",
+		"Not sent: you are in the lobby until the room operator admits you": "नहीं भेजा गया: रूम ऑपरेटर के अंदर आने देने तक आप लॉबी में हैं",
+		"No peer called %s has announced itself":                            "%s नाम के किसी साथी ने अपनी घोषणा नहीं की है",
+		"%s is %s, last heard %s ago":                                       "%s है %s, आख़िरी बार %s पहले सुना गया",
+		"Capabilities: unknown (client predates capability announcements)":  "क्षमताएँ: अज्ञात (क्लाइंट क्षमता घोषणाओं से पुराना है)",
+		"Protocol: %s":      "प्रोटोकॉल: %s",
+		"Capabilities:":     "क्षमताएँ:",
+		"protobuf envelope": "protobuf लिफ़ाफ़ा",
+		"pins":              "पिन",
+		"waiting room":      "प्रतीक्षा कक्ष",
+		"files":             "फ़ाइलें",
+		"e2e DMs":           "e2e निजी संदेश",
+		"reactions":         "प्रतिक्रियाएँ",
+		"cover traffic":     "आवरण ट्रैफ़िक",
+		"%s runs a client without waiting rooms and won't be told to wait":                         "%s का क्लाइंट प्रतीक्षा कक्ष नहीं जानता, उसे इंतज़ार करने को नहीं कहा जाएगा",
+		"Ignoring messages from %s (%s): it speaks protocol %s, this client speaks %s":             "%s (%s) के संदेश अनदेखे किए जा रहे हैं: वह प्रोटोकॉल %s बोलता है, यह क्लाइंट %s",
+		"Most peers in this room run protocol %s, newer than this client's %s; run quichat update": "इस रूम के ज़्यादातर साथी प्रोटोकॉल %s चलाते हैं, जो इस क्लाइंट के %s से नया है; quichat update चलाएँ",
+		"Can't read messages from %s: they use a newer message format; run quichat update":         "%s के संदेश पढ़े नहीं जा सकते: वे नए संदेश प्रारूप में हैं; quichat update चलाएँ",
+		"Transcript not written: %v":                            "ट्रांसक्रिप्ट नहीं लिखी गई: %v",
+		"Webhook %s failed: %v":                                 "वेबहुक %s विफल: %v",
+		"Accepting messages at http://%s/api/rooms/%s/messages": "http://%s/api/rooms/%s/messages पर संदेश स्वीकार किए जा रहे हैं",
+		"MQTT: %v; reconnecting in %s":                          "MQTT: %v; %s में फिर से जुड़ेंगे",
+		"MQTT: connected to %s":                                 "MQTT: %s से जुड़े",
+		"MQTT: message on %s not posted: %v":                    "MQTT: %s का संदेश नहीं भेजा गया: %v",
+		"Feed %s can't be read: %v":                             "फ़ीड %s पढ़ी नहीं जा सकती: %v",
+		"Feed state not saved: %v":                              "फ़ीड की स्थिति सहेजी नहीं गई: %v",
+		"XMPP: serving the room as %s":                          "XMPP: रूम को %s के रूप में परोसा जा रहा है",
+		"XMPP: %v; reconnecting in %s":                          "XMPP: %v; %s में फिर से जुड़ेंगे",
+		"Nostr: %v; reconnecting in %s":                         "Nostr: %v; %s में फिर से जुड़ेंगे",
+		"Nostr: connected to %s, relaying notes tagged #%s":     "Nostr: %s से जुड़े, #%s टैग वाले नोट रिले हो रहे हैं",
+		"Nostr: %s posts as %s":                                 "Nostr: %s, %s के रूप में पोस्ट करता है",
+		"Nostr: relay refused a note: %s":                       "Nostr: रिले ने एक नोट अस्वीकार किया: %s",
+		"Nostr: relay says: %s":                                 "Nostr: रिले का संदेश: %s",
+		"Nostr: note %s not posted: %v":                         "Nostr: नोट %s पोस्ट नहीं हुआ: %v",
+		"Serving the transcript at http://%s/api/history":       "ट्रांसक्रिप्ट http://%s/api/history पर उपलब्ध है",
+		"corrects #%s":                                          "#%s को सुधारता है",
+		"Nothing to correct: you haven't sent a message yet":    "सुधारने को कुछ नहीं: आपने अभी तक कोई संदेश नहीं भेजा",
+		"%q is not in your last message":                        "%q आपके पिछले संदेश में नहीं है",
+		"message corrections":                                   "संदेश सुधार",
+		"Not scheduled: %v":                                     "निर्धारित नहीं हुआ: %v",
+		"Scheduled #%s for %s":                                  "#%s को %s के लिए निर्धारित किया",
+		"No scheduled message #%s in this room":                 "इस रूम में कोई निर्धारित संदेश #%s नहीं है",
+		"Cancelled #%s":                                         "#%s रद्द किया",
+		"No scheduled messages in this room":                    "इस रूम में कोई निर्धारित संदेश नहीं है",
+		"Scheduled messages (%d):":                              "निर्धारित संदेश (%d):",
+		"Scheduled messages can't be read: %v":                  "निर्धारित संदेश पढ़े नहीं जा सकते: %v",
+		"Scheduled message #%s was due at %s and not sent: %s":  "निर्धारित संदेश #%s %s पर भेजा जाना था और नहीं भेजा गया: %s",
+		"Reminder #%s for %s at %s":                             "#%s अनुस्मारक %s के लिए, %s पर",
+		"(due %s)":                                              "(%s पर होना था)",
+		"Reminder: %s":                                          "अनुस्मारक: %s",
+		"reminder for %s: %s":                                   "%s के लिए अनुस्मारक: %s",
+		"While you were away (%s): %d messages in %s from %s, %d mentions; /catchup to read them":     "आपकी अनुपस्थिति में (%s): %d संदेश %s में, %s से, %d उल्लेख; पढ़ने के लिए /catchup",
+		"Nothing to catch up on: /catchup covers the time no terminal was attached to quichat daemon": "पढ़ने को कुछ नहीं: /catchup उस समय को दिखाता है जब quichat daemon से कोई टर्मिनल जुड़ा नहीं था",
+		"Missed between %s and %s (%d):":                          "%s और %s के बीच छूटे (%d):",
+		"…and %d more not kept":                                   "…और %d जो रखे नहीं गए",
+		"No translation backend: set translate.url in the config": "कोई अनुवाद सेवा नहीं: कॉन्फ़िग में translate.url सेट करें",
+		"Translating every message in this room into %s":          "इस रूम का हर संदेश %s में अनुवादित हो रहा है",
+		"Auto-translation off":                                    "स्वचालित अनुवाद बंद",
+		"Too many translations waiting, try again shortly":        "बहुत सारे अनुवाद प्रतीक्षा में हैं, थोड़ी देर में फिर कोशिश करें",
+		"Translation of #%s failed: %v":                           "#%s का अनुवाद विफल: %v",
+		"%s says: %s":                                             "%s कहते हैं: %s",
+		"Text to speech failed: %v":                               "टेक्स्ट टू स्पीच विफल: %v",
+		"Text to speech is off: %v":                               "टेक्स्ट टू स्पीच बंद है: %v",
+		"From %s at %s: %s":                                       "%s से %s पर: %s",
+		"Mention from %s at %s: %s":                               "%s से %s पर उल्लेख: %s",
+		"public":                                                  "सार्वजनिक",
+		"NAT, via relay":                                          "NAT, रिले के ज़रिए",
+		"NAT, no relay yet":                                       "NAT, अभी कोई रिले नहीं",
+		"reachability unknown":                                    "पहुँच अज्ञात",
+		"%d unread":                                               "%d अपठित",
+		"%d mention(s)":                                           "%d उल्लेख",
+		"Recording stopped: %v":                                   "रिकॉर्डिंग रुकी: %v",
+		"Replaying %s in %s, recorded %s by quichat %s":           "%s को %s में दोबारा चलाया जा रहा है, %s को quichat %s से रिकॉर्ड किया गया",
+		"✗ Not sent: the message is %d KiB, the limit is %d KiB":  "✗ नहीं भेजा गया: संदेश %d KiB का है, सीमा %d KiB है",
+		"message too long":                                        "संदेश बहुत लंबा है",
+		"… %d more lines not shown":                               "… %d और पंक्तियाँ नहीं दिखाई गईं",
+		"Several peers go by %s; this one is shown as %s":         "कई साथी %s नाम से हैं; यह वाला %s के रूप में दिखाया जाता है",
+		"%s matches several peers (%s); add the ~tag shown after the nick": "%s कई साथियों (%s) से मेल खाता है; निक के बाद दिखाया गया ~टैग जोड़ें",
+		"%d of %d connected": "%d जुड़े, कुल %d में से",
+		"LAN discovery is off (%v); peers must be given with --bootstrap": "LAN खोज बंद है (%v); साथियों को --bootstrap से देना होगा",
+		"Room advertisement failed: %v; retrying in %s":                   "कमरे की घोषणा विफल: %v; %s में फिर कोशिश",
+		"Advertised the room in the DHT; again in %s":                     "कमरा DHT में घोषित किया; फिर से %s में",
+		"Found %d room members in the DHT, dialing %d":                    "DHT में कमरे के %d सदस्य मिले, %d से जुड़ रहे हैं",
+		"Room advertisement: not yet":                                     "कमरे की घोषणा: अभी नहीं",
+		"Room advertisement: failing (%v), next try in %s":                "कमरे की घोषणा: विफल हो रही है (%v), अगली कोशिश %s में",
+		"Room advertisement: %d so far, last %s ago, next in %s":          "कमरे की घोषणा: अब तक %d, पिछली %s पहले, अगली %s में",
+		" (last attempt failed: %v)":                                      " (पिछली कोशिश विफल: %v)",
+		"Room lookup: %d members found, %d dialed, %s ago":                "कमरे की खोज: %d सदस्य मिले, %d से जुड़े, %s पहले",
+		"First seen %s, last seen %s, %d messages":                        "पहली बार %s देखा, आखिरी बार %s, %d संदेश",
+		"Spam score: %.1f (throttled at %.0f)":                            "स्पैम स्कोर: %.1f (%.0f पर रोका जाता है)",
+		"Throttled until %s (%d times so far)":                            "%s तक रोका गया (अब तक %d बार)",
+		"Throttled %d times before":                                       "पहले %d बार रोका गया",
+		"Blocked":                                                         "अवरुद्ध",
+		"Verified":                                                        "सत्यापित",
+		"Hiding messages from %s until %s: too many too fast":             "%s के संदेश %s तक छिपाए जा रहे हैं: बहुत ज़्यादा, बहुत तेज़ी से",
+		"%s (%s) is marked as verified":                                   "%s (%s) को सत्यापित चिह्नित किया गया",
+		"%s (%s) is no longer marked as verified":                         "%s (%s) अब सत्यापित चिह्नित नहीं है",
+		"unlimited": "असीमित",
+		"Upload: %s (now %s)\n  Download: %s (now %s)":                                                "अपलोड: %s (अभी %s)\n  डाउनलोड: %s (अभी %s)",
+		"The transcript is using %s of its %s quota; its oldest entries will be deleted to make room": "ट्रांसक्रिप्ट %s इस्तेमाल कर रही है, कोटा %s है; जगह बनाने के लिए सबसे पुरानी प्रविष्टियाँ हटाई जाएँगी",
+		"Transcript quota of %s reached: deleted %s, its oldest part":                                 "ट्रांसक्रिप्ट का %s का कोटा भर गया: उसका सबसे पुराना हिस्सा %s हटाया गया",
+		"… %d more messages (%s), /more to show them":                                                 "… %d और संदेश (%s), देखने के लिए /more",
+		"%d older messages were not kept":                                                             "%d पुराने संदेश रखे नहीं गए",
+		"No more messages":                                                                            "और कोई संदेश नहीं",
+		"… %d more, /more to continue":                                                                "… %d और, आगे के लिए /more",
+		"*** %s is now known as %s ***":                                                               "*** %s अब %s नाम से जाना जाता है ***",
+		"*** You are now known as %s (were %s) ***":                                                   "*** अब आपका नाम %s है (पहले %s) ***",
+		"You are %s": "आप %s हैं",
+		"Invalid nick: use up to %d bytes, no control characters or ~": "अमान्य निक: अधिकतम %d बाइट, कोई कंट्रोल कैरेक्टर या ~ नहीं",
+		"Formerly known as: %s": "पहले के नाम: %s",
+		"%s, now %s":            "%s, अब %s",
+		"⚠ WARNING: %s is not the %s first seen in this room (%s, now %s). It may be an impostor. If they changed keys, check with them and /trust %s": "⚠ चेतावनी: %s इस रूम में पहली बार देखा गया %s नहीं है (%s, अब %s)। यह कोई नकलची हो सकता है। अगर उन्होंने कुंजी बदली है, तो उनसे पुष्टि करें और /trust %s चलाएँ",
+		"not the %s first seen here":        "यहाँ पहली बार देखा गया %s नहीं",
+		"Trust not saved: %v":               "भरोसा सहेजा नहीं गया: %v",
+		"%s now belongs to %s in this room": "इस रूम में %s अब %s का है",
+		"stable key":                        "स्थिर कुंजी",
+		"An address looks like /ip4/203.0.113.7/udp/4001/quic-v1/p2p/12D3KooW…; copy it whole from the \"Listening on\" lines the other node prints, or use --invite": "पता कुछ ऐसा दिखता है: /ip4/203.0.113.7/udp/4001/quic-v1/p2p/12D3KooW…; इसे दूसरे नोड की \"… पर सुन रहे हैं\" वाली पंक्तियों से पूरा कॉपी करें, या --invite इस्तेमाल करें",
+		"Is the bootstrap node running? Check the address, try --bootstrap-retries 3, or --proxy if a firewall is in the way":                                         "क्या बूटस्ट्रैप नोड चल रहा है? पता जाँचें, --bootstrap-retries 3 आज़माएँ, या फ़ायरवॉल बीच में हो तो --proxy",
+		"The peer that made this invite may have left; ask someone in the room for a fresh /invite":                                                                   "जिस साथी ने यह आमंत्रण बनाया था वह शायद जा चुका है; रूम में किसी से नया /invite माँगें",
+		"Pick another with --listen, or --listen 0 for any free port; another quichat may be running (quichat status)":                                                "--listen से दूसरा चुनें, या किसी भी खाली पोर्ट के लिए --listen 0; शायद कोई और quichat चल रहा है (quichat status)",
+		"Nobody else is here yet: share /invite, or start with --bootstrap or --invite to join someone":                                                               "अभी यहाँ और कोई नहीं है: /invite साझा करें, या किसी से जुड़ने के लिए --bootstrap या --invite के साथ शुरू करें",
+		"The bootstrap peer answered but nobody else is in this room; check that everyone uses the same room and --network":                                           "बूटस्ट्रैप पीयर ने जवाब दिया पर इस रूम में और कोई नहीं है; जाँचें कि सब एक ही रूम और --network इस्तेमाल कर रहे हैं",
+		"No peers in the room after %s":           "%s बाद भी रूम में कोई पीयर नहीं",
+		"*** %s left the chat ***":                "*** %s चैट से चले गए ***",
+		"✗ %d message(s) not sent before leaving": "✗ जाने से पहले %d संदेश नहीं भेजे जा सके",
+		"leave notices":                           "जाने की सूचना",
+		"QUIC, WebTransport and WebRTC aren't limited: start with --max-upload or --max-download to limit them too": "QUIC, WebTransport और WebRTC सीमित नहीं हैं: इन्हें भी सीमित करने के लिए --max-upload या --max-download के साथ शुरू करें",
+	},
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on what a peer may send. Messages beyond them are dropped before
+// they are delivered or forwarded.
+const (
+	maxPayload = 64 << 10 // bytes of an encoded message, cover padding included
+	maxTextLen = 32 << 10 // bytes of a chat line we agree to send
+	maxNickLen = 64
+	maxIDLen   = 64  // message IDs, including the ID a correction refers to
+	maxMetaLen = 256 // trace parent, wire format and version
+
+	maxShownLine  = 1000 // characters of one line of a message shown before it is cut
+	maxShownLines = 50   // lines of a message shown before the rest is cut
+)
+
+var (
+	errInvalidMessage = errors.New("invalid message")
+	errTooLong        = errors.New("message too long")
+)
+
+// checkMessage rejects a decoded message whose fields are oversized or not
+// valid UTF-8, or whose Lamport time is implausibly far ahead. JSON
+// decoding already replaces bad UTF-8, protobuf doesn't.
+func checkMessage(m *Message) error {
+	if m.Clock > maxClock {
+		return fmt.Errorf("%w: clock %d", errInvalidMessage, m.Clock)
+	}
+	fields := []struct {
+		name, v string
+		max     int
+	}{
+		{"nick", m.Nick, maxNickLen},
+		{"id", m.ID, maxIDLen},
+		{"edit", m.Edit, maxIDLen},
+		{"trace", m.Trace, maxMetaLen},
+		{"wire", m.Wire, maxMetaLen},
+		{"version", m.Version, maxMetaLen},
+		{"text", m.Text, maxPayload},
+		{"pad", m.Pad, maxPayload},
+	}
+	for _, f := range fields {
+		if len(f.v) > f.max {
+			return fmt.Errorf("%w: %s is %d bytes", errInvalidMessage, f.name, len(f.v))
+		}
+		if !utf8.ValidString(f.v) {
+			return fmt.Errorf("%w: %s is not UTF-8", errInvalidMessage, f.name)
+		}
+	}
+	return nil
+}
+
+// controlArg returns what follows prefix in a control message such as
+// __PING__<id>, if text is one and the argument fits in max bytes.
+func controlArg(text, prefix string, max int) (string, bool) {
+	if len(text) < len(prefix) || text[:len(prefix)] != prefix || len(text)-len(prefix) > max {
+		return "", false
+	}
+	return text[len(prefix):], true
+}
+
+// sanitize makes text from a peer safe to print: escape sequences and other
+// control characters can't reach the terminal, where they could move the
+// cursor, retitle the window or hide text, and are shown as visible symbols
+// (ESC as ␛) instead. Bidi overrides, which can make text read in a
+// different order than it was written, are dropped. Newlines stay unless
+// multiline is false; carriage returns go.
+func sanitize(s string, multiline bool) string {
+	clean := true
+	for _, r := range s {
+		if unsafeRune(r) || (r == '\n' && !multiline) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch {
+		case r == '\n' && multiline:
+			b.WriteRune(r)
+		case r == '\n' || r == '\t':
+			b.WriteByte(' ')
+		case r == '\r', r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		case r < 0x20:
+			b.WriteRune(0x2400 + r) // control pictures: ␀ … ␟
+		case r == 0x7f:
+			b.WriteRune('␡')
+		case r >= 0x80 && r < 0xa0:
+			b.WriteRune(utf8.RuneError)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func unsafeRune(r rune) bool {
+	return (r < 0x20 && r != '\n') || (r >= 0x7f && r < 0xa0) ||
+		(r >= 0x202a && r <= 0x202e) || (r >= 0x2066 && r <= 0x2069) || r == utf8.RuneError
+}
+
+// capShown shortens a message for display to maxShownLines lines of at most
+// maxShownLine characters each, saying how much was left out.
+func capShown(text string) string {
+	lines := strings.Split(text, "\n")
+	cut := 0
+	if len(lines) > maxShownLines {
+		cut = len(lines) - maxShownLines
+		lines = lines[:maxShownLines]
+	}
+	for i, l := range lines {
+		if utf8.RuneCountInString(l) > maxShownLine {
+			lines[i] = string([]rune(l)[:maxShownLine]) + "…"
+		}
+	}
+	if cut > 0 {
+		lines = append(lines, fmt.Sprintf(T("… %d more lines not shown"), cut))
+	}
+	return strings.Join(lines, "\n")
+}
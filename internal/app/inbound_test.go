@@ -0,0 +1,48 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzControlArg(f *testing.F) {
+	f.Add("__PING__3f9a0c1d2e4b5a69")
+	f.Add("__PONG__3f9a0c1d2e4b5a69")
+	f.Add("__PING__")
+	f.Add("__PONG__" + strings.Repeat("x", maxIDLen+1))
+	f.Add("__PIN")
+	f.Add("hello")
+
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, prefix := range []string{"__PING__", "__PONG__"} {
+			id, ok := controlArg(text, prefix, maxIDLen)
+			if !ok {
+				if strings.HasPrefix(text, prefix) && len(text)-len(prefix) <= maxIDLen {
+					t.Fatalf("%q refused", text)
+				}
+				continue
+			}
+			if len(id) > maxIDLen {
+				t.Fatalf("argument of %d bytes accepted", len(id))
+			}
+			if prefix+id != text {
+				t.Fatalf("%q parsed as %q", text, id)
+			}
+		}
+	})
+}
+
+func BenchmarkSanitize(b *testing.B) {
+	for _, tt := range []struct{ name, text string }{
+		{"plain", strings.Repeat("hello, room ", 40)},
+		{"hostile", strings.Repeat("\x1b[2J\u202eevil\r\n", 40)},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(tt.text)))
+			for b.Loop() {
+				sanitize(tt.text, true)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"github.com/mdp/qrterminal/v3"
+	"github.com/mr-tron/base58"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Invite versions: 2 adds the network after the addresses; 3 always carries
+// it and says the room's topic is hashed, and may end with the room
+// operator's peer ID. Versions 1 and 2 name rooms with readable topics and
+// are still written by --legacy-topics nodes, so the clients they are meant
+// for can read them.
+const (
+	inviteVersion        = 1
+	inviteVersionNetwork = 2
+	inviteVersionHashed  = 3
+)
+
+// Invite bundles everything a new peer needs to join: who to dial and which room.
+type Invite struct {
+	Peer         peer.AddrInfo
+	Room         string
+	Network      string  // --network of the inviting node; "" for the public one
+	LegacyTopics bool    // the room is joined under its readable topic name
+	Operator     peer.ID // whose rosters cap the room; "" if the inviter doesn't know
+}
+
+// Invite builds an invite for this node's current non-loopback addresses.
+func (n *Node) Invite() Invite {
+	inv := Invite{Peer: peer.AddrInfo{ID: n.Host.ID()}, Room: n.room, Network: n.network, LegacyTopics: n.legacyTopics, Operator: n.operator}
+	for _, a := range sortAddrs(n.Host.Addrs()) {
+		if manet.IsIPLoopback(a) {
+			continue
+		}
+		inv.Peer.Addrs = append(inv.Peer.Addrs, a)
+	}
+	return inv
+}
+
+// Encode packs the invite into a base58 string:
+// version | peer ID | room | addr count | addrs [| network [| operator]],
+// each length-prefixed. Legacy invites leave the operator out; older
+// clients ignore it in hashed ones.
+func (inv Invite) Encode() string {
+	var buf bytes.Buffer
+	v := byte(inviteVersionHashed)
+	if inv.LegacyTopics {
+		v = inviteVersion
+		if inv.Network != "" {
+			v = inviteVersionNetwork
+		}
+	}
+	buf.WriteByte(v)
+	writeChunk(&buf, []byte(inv.Peer.ID))
+	writeChunk(&buf, []byte(inv.Room))
+	buf.Write(binary.AppendUvarint(nil, uint64(len(inv.Peer.Addrs))))
+	for _, a := range inv.Peer.Addrs {
+		writeChunk(&buf, a.Bytes())
+	}
+	if v != inviteVersion {
+		writeChunk(&buf, []byte(inv.Network))
+	}
+	if v == inviteVersionHashed && inv.Operator != "" {
+		writeChunk(&buf, []byte(inv.Operator))
+	}
+	return base58.Encode(buf.Bytes())
+}
+
+// ParseInvite decodes a code produced by Invite.Encode.
+func ParseInvite(code string) (Invite, error) {
+	raw, err := base58.Decode(code)
+	if err != nil {
+		return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+	}
+	r := bytes.NewReader(raw)
+	v, err := r.ReadByte()
+	if err != nil {
+		return Invite{}, errors.New("invalid invite code: empty")
+	}
+	if v != inviteVersion && v != inviteVersionNetwork && v != inviteVersionHashed {
+		return Invite{}, fmt.Errorf("unsupported invite version %d", v)
+	}
+
+	inv := Invite{LegacyTopics: v != inviteVersionHashed}
+	id, err := readChunk(r)
+	if err != nil {
+		return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+	}
+	if inv.Peer.ID, err = peer.IDFromBytes(id); err != nil {
+		return Invite{}, fmt.Errorf("invalid invite peer ID: %w", err)
+	}
+	room, err := readChunk(r)
+	if err != nil {
+		return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+	}
+	inv.Room = string(room)
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+	}
+	for i := uint64(0); i < count; i++ {
+		b, err := readChunk(r)
+		if err != nil {
+			return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+		}
+		addr, err := ma.NewMultiaddrBytes(b)
+		if err != nil {
+			return Invite{}, fmt.Errorf("invalid invite address: %w", err)
+		}
+		inv.Peer.Addrs = append(inv.Peer.Addrs, addr)
+	}
+	if len(inv.Peer.Addrs) == 0 {
+		return Invite{}, errors.New("invite code carries no addresses")
+	}
+	if v != inviteVersion {
+		network, err := readChunk(r)
+		if err != nil {
+			return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+		}
+		inv.Network = string(network)
+		if err := ValidateNetwork(inv.Network); err != nil {
+			return Invite{}, fmt.Errorf("invalid invite network: %w", err)
+		}
+	}
+	if v == inviteVersionHashed && r.Len() > 0 {
+		op, err := readChunk(r)
+		if err != nil {
+			return Invite{}, fmt.Errorf("invalid invite code: %w", err)
+		}
+		if inv.Operator, err = peer.IDFromBytes(op); err != nil {
+			return Invite{}, fmt.Errorf("invalid invite operator: %w", err)
+		}
+	}
+	return inv, nil
+}
+
+// PrintQR renders s as a half-block QR code suitable for a terminal.
+func PrintQR(w io.Writer, s string) {
+	qrterminal.GenerateWithConfig(s, qrterminal.Config{
+		Level:          qrterminal.L,
+		Writer:         w,
+		HalfBlocks:     true,
+		BlackChar:      qrterminal.BLACK_BLACK,
+		WhiteBlackChar: qrterminal.WHITE_BLACK,
+		WhiteChar:      qrterminal.WHITE_WHITE,
+		BlackWhiteChar: qrterminal.BLACK_WHITE,
+		QuietZone:      1,
+	})
+}
+
+func writeChunk(buf *bytes.Buffer, b []byte) {
+	buf.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	buf.Write(b)
+}
+
+func readChunk(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
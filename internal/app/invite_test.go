@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// sameInvite compares invites, addresses by their bytes.
+func sameInvite(a, b Invite) bool {
+	if a.Peer.ID != b.Peer.ID || a.Room != b.Room || a.Network != b.Network || a.Operator != b.Operator ||
+		a.LegacyTopics != b.LegacyTopics || len(a.Peer.Addrs) != len(b.Peer.Addrs) {
+		return false
+	}
+	for i := range a.Peer.Addrs {
+		if !a.Peer.Addrs[i].Equal(b.Peer.Addrs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzParseInvite(f *testing.F) {
+	info := peer.AddrInfo{ID: testPeer(f), Addrs: []ma.Multiaddr{
+		ma.StringCast("/ip4/192.0.2.1/udp/4001/quic-v1"),
+		ma.StringCast("/ip6/2001:db8::1/tcp/4001"),
+	}}
+	for _, inv := range []Invite{
+		{Peer: info, Room: DefaultRoom},
+		{Peer: info, Room: "ops", Network: "acme"},
+		{Peer: info, Room: "ops", Network: "acme", Operator: testPeer(f)},
+		{Peer: info, Room: "ops", LegacyTopics: true},
+		{Peer: info, Room: "ops", Network: "acme", LegacyTopics: true},
+	} {
+		f.Add(inv.Encode())
+	}
+	f.Add("")
+	f.Add("0OIl")
+	f.Add("2")
+
+	f.Fuzz(func(t *testing.T, code string) {
+		inv, err := ParseInvite(code)
+		if err != nil {
+			return
+		}
+		back, err := ParseInvite(inv.Encode())
+		if err != nil {
+			t.Fatalf("parse after encode: %v", err)
+		}
+		if !sameInvite(inv, back) {
+			t.Fatalf("round trip changed %+v into %+v", inv, back)
+		}
+	})
+}
+
+// TestInviteGolden pins the invite format: codes already handed out must
+// keep parsing, and a node must keep writing what older clients read. The
+// golden codes were built from go-multiaddr's own address bytes, and are
+// decoded here without going through Encode.
+func TestInviteGolden(t *testing.T) {
+	id, err := peer.Decode("12D3KooW9tJMax94Lrqw7Y5Qw36viGQAS2gTEPQ5Wg1vTk7xPfQs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, err := peer.Decode("12D3KooWRawPbxPtP1eZaJpumGnyWX2DcUyd3RQnydr3eAto4Az7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := []string{"/ip4/192.0.2.1/udp/4001/quic-v1", "/ip6/2001:db8::1/tcp/4001"}
+	info := peer.AddrInfo{ID: id}
+	for _, a := range addrs {
+		info.Addrs = append(info.Addrs, ma.StringCast(a))
+	}
+	cases := []struct {
+		name string
+		inv  Invite
+	}{
+		{"hashed", Invite{Peer: info, Room: "lobby"}},
+		{"hashed-network", Invite{Peer: info, Room: "ops", Network: "acme"}},
+		{"hashed-operator", Invite{Peer: info, Room: "ops", Network: "acme", Operator: operator}},
+		{"legacy", Invite{Peer: info, Room: "ops", LegacyTopics: true}},
+		{"legacy-network", Invite{Peer: info, Room: "ops", Network: "acme", LegacyTopics: true}},
+	}
+	var b strings.Builder
+	for _, c := range cases {
+		fmt.Fprintf(&b, "%s %s\n", c.name, c.inv.Encode())
+	}
+	golden(t, "invite", []byte(b.String()))
+
+	data, err := os.ReadFile("testdata/invite.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(cases) {
+		t.Fatalf("%d codes in invite.golden, want %d", len(lines), len(cases))
+	}
+	for i, line := range lines {
+		name, code, _ := strings.Cut(line, " ")
+		inv, err := ParseInvite(code)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !sameInvite(cases[i].inv, inv) {
+			t.Errorf("%s parses as %+v, want %+v", name, inv, cases[i].inv)
+		}
+		for j, a := range inv.Peer.Addrs {
+			if a.String() != addrs[j] {
+				t.Errorf("%s: address %d is %s, want %s", name, j, a, addrs[j])
+			}
+		}
+	}
+}
+
+func TestParseInviteRefusesBadNetwork(t *testing.T) {
+	info := peer.AddrInfo{ID: testPeer(t), Addrs: []ma.Multiaddr{ma.StringCast("/ip4/192.0.2.1/udp/4001/quic-v1")}}
+	for _, network := range []string{"acme/../x", "two words", "\x1b]0;pwned\x07", strings.Repeat("n", maxNetworkLen+1)} {
+		code := Invite{Peer: info, Room: "ops", Network: network}.Encode()
+		if _, err := ParseInvite(code); err == nil {
+			t.Errorf("invite with network %q accepted", network)
+		}
+	}
+}
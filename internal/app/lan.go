@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	network "github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	record "github.com/libp2p/go-libp2p/core/record"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// LAN discovery for --offline-lan: with no DHT to find each other through,
+// nodes announce their signed peer record to a multicast group every
+// lanInterval and dial the room members they hear. The group is
+// administratively scoped, so routers keep it on the local network.
+const (
+	lanGroup    = "239.255.77.77:14193"
+	lanInterval = 5 * time.Second
+	lanMaxBytes = 8 << 10 // upper bound on one beacon
+)
+
+type lanBeacon struct {
+	Room   string `json:"room"`
+	Record []byte `json:"record"` // our marshaled, signed peer record
+}
+
+// initLANDiscovery starts announcing ourselves and listening for others on
+// the local network. Without multicast (no interface, a firewall) only
+// direct multiaddrs work, which is worth a warning rather than failing.
+func (n *Node) initLANDiscovery() error {
+	group, err := net.ResolveUDPAddr("udp4", lanGroup)
+	if err != nil {
+		return err
+	}
+	in, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err == nil {
+		var out *net.UDPConn
+		if out, err = net.DialUDP("udp4", nil, group); err == nil {
+			go n.announceLAN(out)
+			go n.listenLAN(in)
+			return nil
+		}
+		in.Close()
+	}
+	fmt.Fprintln(termOut, termText(ansiWarn+fmt.Sprintf(T("LAN discovery is off (%v); peers must be given with --bootstrap"), err)+ansiReset))
+	return nil
+}
+
+// announceLAN sends a beacon now and every lanInterval until n.ctx is done.
+func (n *Node) announceLAN(conn *net.UDPConn) {
+	defer conn.Close()
+	tick := time.NewTicker(lanInterval)
+	defer tick.Stop()
+	for {
+		if b, err := n.lanBeacon(); err == nil {
+			conn.Write(b) // lost beacons are made up for by the next
+		}
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// lanBeacon signs a peer record of our current direct addresses.
+func (n *Node) lanBeacon() ([]byte, error) {
+	var addrs []ma.Multiaddr
+	for _, a := range n.Host.Addrs() {
+		if !isRelayed(a) {
+			addrs = append(addrs, a)
+		}
+	}
+	rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: n.Host.ID(), Addrs: addrs})
+	env, err := record.Seal(rec, n.Host.Peerstore().PrivKey(n.Host.ID()))
+	if err != nil {
+		return nil, err
+	}
+	signed, err := env.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(lanBeacon{Room: n.topic, Record: signed})
+}
+
+// listenLAN dials every member of our room that announces itself.
+func (n *Node) listenLAN(conn *net.UDPConn) {
+	go func() {
+		<-n.ctx.Done()
+		conn.Close()
+	}()
+	buf := make([]byte, lanMaxBytes)
+	for {
+		size, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var b lanBeacon
+		if json.Unmarshal(buf[:size], &b) != nil || b.Room != n.topic {
+			continue
+		}
+		info, ok := verifyRecord(b.Record)
+		if !ok || info.ID == n.Host.ID() || n.Host.Network().Connectedness(info.ID) == network.Connected {
+			continue
+		}
+		n.Host.Peerstore().AddAddrs(info.ID, info.Addrs, pxAddrTTL)
+		go n.Host.Connect(n.ctx, info)
+	}
+}
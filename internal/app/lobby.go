@@ -0,0 +1,272 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const rosterPrefix = "__ROSTER__"
+
+// roster is what a room operator (a node started with --max-members)
+// announces: who may speak and what waiting peers are told.
+type roster struct {
+	Max      int       `json:"max"`
+	Admitted []peer.ID `json:"admitted"`
+	Welcome  string    `json:"welcome,omitempty"`
+}
+
+// lobby enforces a membership cap. The operator admits the first Max peers
+// that join and holds later ones in the lobby until /admit; everyone else
+// follows the rosters of the operator named by the config or their invite. Chat lines from
+// peers outside the roster are dropped by every node's topic validator, so
+// they are never propagated; lobby members still read the room.
+type lobby struct {
+	mu       sync.Mutex
+	operator bool
+	roster   *roster            // nil while the room is uncapped
+	admitted map[peer.ID]bool   // roster.Admitted as a set
+	waiting  map[peer.ID]string // operator only: nick of each lobby member
+	inside   bool               // whether we were admitted in the last roster
+}
+
+// open makes this node the operator of a room capped at max members.
+func (l *lobby) open(self peer.ID, max int, welcome string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.operator, l.inside = true, true
+	l.roster = &roster{Max: max, Admitted: []peer.ID{self}, Welcome: welcome}
+	l.admitted = map[peer.ID]bool{self: true}
+	l.waiting = make(map[peer.ID]string)
+}
+
+// allows reports whether chat lines from p may be shown and propagated.
+func (l *lobby) allows(p peer.ID) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.roster == nil || l.admitted[p]
+}
+
+// arrived is called by the operator for each peer that joins. It admits
+// the peer if there is room and reports whether it was newly sent to the
+// lobby instead.
+func (l *lobby) arrived(p peer.ID, nick string) (waiting bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.operator || l.admitted[p] {
+		return false
+	}
+	if len(l.roster.Admitted) < l.roster.Max {
+		l.admit(p)
+		return false
+	}
+	_, known := l.waiting[p]
+	l.waiting[p] = nick
+	return !known
+}
+
+// admitNamed admits the waiting peer whose nick, nick~tag or peer ID
+// (prefix) is name.
+func (l *lobby) admitNamed(name string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.operator {
+		return "", errors.New("only the room operator (started with --max-members) can admit peers")
+	}
+	var found []peer.ID
+	var labels []string
+	for p, nick := range l.waiting {
+		if named(name, p, nick) {
+			found = append(found, p)
+			labels = append(labels, nick+"~"+nickTag(p))
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("nobody called %s is waiting in the lobby", name)
+	case 1:
+		nick := l.waiting[found[0]]
+		l.admit(found[0])
+		return nick, nil
+	}
+	return "", ambiguous(name, labels)
+}
+
+func (l *lobby) admit(p peer.ID) {
+	delete(l.waiting, p)
+	l.admitted[p] = true
+	l.roster.Admitted = append(l.roster.Admitted, p)
+}
+
+// update applies a roster from the operator and reports whether we were
+// just admitted or just placed in the lobby.
+func (l *lobby) update(r roster, self peer.ID) (admitted, held bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.operator {
+		return false, false
+	}
+	prev, first := l.inside, l.roster == nil
+	l.roster = &r
+	l.admitted = make(map[peer.ID]bool, len(r.Admitted))
+	for _, p := range r.Admitted {
+		l.admitted[p] = true
+	}
+	l.inside = l.admitted[self]
+	return !first && !prev && l.inside, !l.inside && (first || prev)
+}
+
+// announcement returns the roster control message, or "" if uncapped.
+func (l *lobby) announcement() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.operator {
+		return ""
+	}
+	return encodeRoster(*l.roster)
+}
+
+// encodeRoster builds the roster control message.
+func encodeRoster(r roster) string {
+	b, _ := json.Marshal(r)
+	return rosterPrefix + string(b)
+}
+
+// decodeRoster reads a roster control message.
+func decodeRoster(text string) (roster, error) {
+	var r roster
+	err := json.Unmarshal([]byte(strings.TrimPrefix(text, rosterPrefix)), &r)
+	return r, err
+}
+
+// report renders the lobby for /admit without arguments.
+func (l *lobby) report() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.operator {
+		return T("Only the room operator (started with --max-members) can admit peers")
+	}
+	if len(l.waiting) == 0 {
+		return fmt.Sprintf(T("Room has %d/%d members, nobody is waiting"), len(l.roster.Admitted), l.roster.Max)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Room has %d/%d members, %d waiting:"), len(l.roster.Admitted), l.roster.Max, len(l.waiting))
+	for p, nick := range l.waiting {
+		fmt.Fprintf(&b, "\n  %s (%s)", nick, shortID(p))
+	}
+	return b.String()
+}
+
+// validate is the room's topic validator: malformed or oversized messages,
+// anything from a peer banned with /banpeer, and anything from peers
+// outside the roster but what lobbyMayPost allows, are ignored (not
+// delivered, not forwarded).
+func (c *Chat) validate(_ context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	if c.n.reputation.blocked(msg.GetFrom()) {
+		return pubsub.ValidationIgnore
+	}
+	m, err := decodeMessage(msg.Data)
+	if err != nil && !errors.Is(err, errWireVersion) {
+		return pubsub.ValidationIgnore
+	}
+	if c.lobby.allows(msg.GetFrom()) {
+		return pubsub.ValidationAccept // newer wire versions too, for peers that read them
+	}
+	if err != nil || !lobbyMayPost(m) {
+		return pubsub.ValidationIgnore
+	}
+	return pubsub.ValidationAccept
+}
+
+// lobbyMayPost reports whether a peer held in the lobby may send m: its
+// JOINs and LEAVE, so the operator sees it come and go, and pings and
+// pongs. Pins, rosters, corrections and anything else are dropped.
+func lobbyMayPost(m Message) bool {
+	if m.ID != "" {
+		return false
+	}
+	if m.Text == joinText || m.Text == leaveText {
+		return true
+	}
+	_, ping := controlArg(m.Text, "__PING__", maxIDLen)
+	_, pong := controlArg(m.Text, "__PONG__", maxIDLen)
+	return ping || pong
+}
+
+// roomOperator returns the peer whose rosters cap the room: the one the
+// config names for it, else the one the invite names. Invites from before
+// they carried the operator, and --bootstrap, fall back to the inviter or
+// the first bootstrap peer given, which was the operator in the setups
+// they were made for. It is "" when nothing names one.
+func (n *Node) roomOperator() (peer.ID, error) {
+	if s := n.cfg.operator(n.room); s != "" {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return "", fmt.Errorf("operators[%q] in the config: %w", n.room, err)
+		}
+		return id, nil
+	}
+	if n.invite != nil {
+		return cmp.Or(n.invite.Operator, n.invite.Peer.ID), nil
+	}
+	if len(n.bootstrapAddrs) > 0 {
+		if a, err := ma.NewMultiaddr(n.bootstrapAddrs[0]); err == nil {
+			id, _ := peer.IDFromP2PAddr(a)
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// rosterFrom handles a roster control message; only the operator's counts.
+func (c *Chat) rosterFrom(from peer.ID, text string) {
+	if c.n.operator == "" || from != c.n.operator {
+		return
+	}
+	r, err := decodeRoster(text)
+	if err != nil {
+		return
+	}
+	admitted, held := c.lobby.update(r, c.n.Host.ID())
+	switch {
+	case held:
+		msg := fmt.Sprintf(T("*** You are in the lobby: the room is full (%d/%d). You can read along and speak once the operator admits you. ***"),
+			len(r.Admitted), r.Max)
+		if r.Welcome != "" {
+			msg += "\n» " + sanitize(r.Welcome, true)
+		}
+		c.ui.Print("\033[1;33m" + msg + ansiReset)
+	case admitted:
+		c.ui.Print("\033[1;32m" + T("*** You have been admitted to the room ***") + ansiReset)
+	}
+}
+
+// announceRoster publishes the roster if we are the operator.
+func (c *Chat) announceRoster(ctx context.Context) {
+	if text := c.lobby.announcement(); text != "" {
+		c.publish(ctx, text)
+	}
+}
+
+// runAdmit implements /admit [nick|peer-id].
+func (c *Chat) runAdmit(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		c.ui.Print(c.lobby.report())
+		return
+	}
+	nick, err := c.lobby.admitNamed(args[0])
+	if err != nil {
+		c.ui.Print(err.Error())
+		return
+	}
+	c.announceRoster(ctx)
+	c.ui.Print(fmt.Sprintf(T("Admitted %s"), nick))
+}
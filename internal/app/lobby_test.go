@@ -0,0 +1,61 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// testPeer returns the ID of a freshly generated key.
+func testPeer(tb testing.TB) peer.ID {
+	tb.Helper()
+	_, id := testKey(tb)
+	return id
+}
+
+func FuzzDecodeRoster(f *testing.F) {
+	id := testPeer(f)
+	f.Add(encodeRoster(roster{Max: 3, Admitted: []peer.ID{id}, Welcome: "back at 10"}))
+	f.Add(rosterPrefix + `{"max":1,"admitted":[]}`)
+	f.Add(rosterPrefix + `{"max":-1,"admitted":["not a peer"]}`)
+	f.Add(rosterPrefix + `{"admitted":null,"welcome":"\u001b]0;pwned\u0007"}`)
+	f.Add(rosterPrefix)
+
+	f.Fuzz(func(t *testing.T, text string) {
+		r, err := decodeRoster(text)
+		if err != nil {
+			return
+		}
+		back, err := decodeRoster(encodeRoster(r))
+		if err != nil {
+			t.Fatalf("decode after encode: %v", err)
+		}
+		if !reflect.DeepEqual(r, back) {
+			t.Fatalf("round trip changed %+v into %+v", r, back)
+		}
+	})
+}
+
+func TestLobbyMayPost(t *testing.T) {
+	for _, tt := range []struct {
+		m    Message
+		want bool
+	}{
+		{Message{Text: joinText}, true},
+		{Message{Text: leaveText}, true},
+		{Message{Text: "__PING__3f9a0c1d"}, true},
+		{Message{Text: "__PONG__3f9a0c1d"}, true},
+		{Message{Text: "__PING__3f9a0c1d", ID: "a1"}, false},
+		{Message{ID: "a1", Text: "hello"}, false},
+		{Message{Text: "hello"}, false},
+		{Message{Text: encodePins([]Message{sampleMessage()})}, false},
+		{Message{Text: rosterPrefix + `{"max":1}`}, false},
+		{Message{Text: coverText}, false},
+		{Message{Text: "__PONG__" + string(make([]byte, maxIDLen+1))}, false},
+	} {
+		if got := lobbyMayPost(tt.m); got != tt.want {
+			t.Errorf("lobbyMayPost(%q) = %v, want %v", tt.m.Text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+const maxMentions = 100
+
+// mention is a message from someone else that named us.
+type mention struct {
+	At   time.Time
+	Room string
+	From peer.ID // sender, so the mention follows it through renames
+	Nick string
+	Text string
+}
+
+// mentionIndex keeps the most recent mentions for /mentions.
+type mentionIndex struct {
+	mu   sync.Mutex
+	list []mention
+}
+
+func (mi *mentionIndex) add(m mention) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+	mi.list = append(mi.list, m)
+	if len(mi.list) > maxMentions {
+		mi.list = mi.list[len(mi.list)-maxMentions:]
+	}
+}
+
+// report renders the index oldest first, as /mentions shows it. nickOf
+// gives a sender's current nick, shown beside the one it had if it has
+// changed since.
+func (mi *mentionIndex) report(nickOf func(peer.ID) string) string {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+	if len(mi.list) == 0 {
+		return T("No mentions yet")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Mentions (%d):"), len(mi.list))
+	for _, m := range mi.list {
+		nick := m.Nick
+		if now := nickOf(m.From); now != "" && now != nick {
+			nick = fmt.Sprintf(T("%s, now %s"), nick, now)
+		}
+		fmt.Fprintf(&b, "\n  [%s] %s <%s> %s",
+			m.At.Local().Format("2006-01-02 15:04:05"), m.Room, nick, m.Text)
+	}
+	return b.String()
+}
+
+// mentions reports whether text names nick as a whole word, with or
+// without a leading @, ignoring case.
+func mentions(text, nick string) bool {
+	if nick == "" {
+		return false
+	}
+	if isASCII(nick) {
+		return mentionsASCII(text, nick)
+	}
+	text, nick = strings.ToLower(text), strings.ToLower(nick)
+	for i := 0; ; {
+		j := strings.Index(text[i:], nick)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(nick)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// mentionsASCII is mentions for the common all-ASCII nick. It compares in
+// place instead of lower-casing every incoming message.
+func mentionsASCII(text, nick string) bool {
+	for start := 0; start+len(nick) <= len(text); start++ {
+		end := start + len(nick)
+		if !strings.EqualFold(text[start:end], nick) {
+			continue
+		}
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
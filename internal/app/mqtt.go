@@ -0,0 +1,285 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A minimal MQTT 3.1.1 client, enough for the bridge: it connects with a
+// clean session, subscribes at QoS 0 and publishes at QoS 0. QoS 1
+// deliveries from the broker are acknowledged; nothing is persisted.
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+
+	mqttKeepAlive  = 30 * time.Second
+	mqttDialTime   = 10 * time.Second
+	mqttMaxPacket  = 256 << 10 // larger packets from the broker end the session
+	mqttDefaultTCP = "1883"
+	mqttDefaultTLS = "8883"
+)
+
+// mqttMessage is one PUBLISH received from the broker.
+type mqttMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// mqttClient is one session with a broker.
+type mqttClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex
+	next uint16 // packet identifier for SUBSCRIBE
+}
+
+// dialMQTT connects to broker, a mqtt:// or mqtts:// URL whose user info,
+// if any, is sent as username and password.
+func dialMQTT(ctx context.Context, broker, clientID string) (*mqttClient, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL %q: %w", broker, err)
+	}
+	host, port := u.Hostname(), u.Port()
+	d := &net.Dialer{Timeout: mqttDialTime}
+	var conn net.Conn
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		if port == "" {
+			port = mqttDefaultTCP
+		}
+		conn, err = d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	case "mqtts", "ssl", "tls":
+		if port == "" {
+			port = mqttDefaultTLS
+		}
+		td := &tls.Dialer{NetDialer: d, Config: &tls.Config{ServerName: host}}
+		conn, err = td.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	default:
+		return nil, fmt.Errorf("broker URL %q: scheme must be mqtt:// or mqtts://", broker)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mqttClient{conn: conn, r: bufio.NewReader(conn)}
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	flags := byte(0x02) // clean session
+	if u.User != nil {
+		flags |= 0x80
+		if _, ok := u.User.Password(); ok {
+			flags |= 0x40
+		}
+	}
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(mqttKeepAlive/time.Second))
+	body = appendMQTTString(body, clientID)
+	if u.User != nil {
+		body = appendMQTTString(body, u.User.Username())
+		if pw, ok := u.User.Password(); ok {
+			body = appendMQTTString(body, pw)
+		}
+	}
+	conn.SetDeadline(time.Now().Add(mqttDialTime))
+	if err := c.write(mqttConnect<<4, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	typ, _, ack, err := c.read()
+	if err == nil && (typ != mqttConnack || len(ack) != 2) {
+		err = errors.New("broker did not acknowledge the connection")
+	}
+	if err == nil && ack[1] != 0 {
+		err = fmt.Errorf("broker refused the connection (%s)", mqttRefusal(ack[1]))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func mqttRefusal(code byte) string {
+	switch code {
+	case 1:
+		return "unsupported protocol version"
+	case 2:
+		return "client ID rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+// subscribe asks for topics at QoS 0. The SUBACK arrives in run.
+func (c *mqttClient) subscribe(topics []string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	c.next++
+	body := binary.BigEndian.AppendUint16(nil, c.next)
+	for _, t := range topics {
+		body = appendMQTTString(body, t)
+		body = append(body, 0)
+	}
+	return c.write(mqttSubscribe<<4|0x02, body)
+}
+
+// publish sends payload to topic at QoS 0.
+func (c *mqttClient) publish(topic string, payload []byte) error {
+	return c.write(mqttPublish<<4, append(appendMQTTString(nil, topic), payload...))
+}
+
+// run reads from the broker until the connection fails or ctx is done,
+// handing each PUBLISH to deliver and pinging to keep the session alive.
+func (c *mqttClient) run(ctx context.Context, deliver func(mqttMessage)) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		tick := time.NewTicker(mqttKeepAlive / 2)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				c.write(mqttDisconnect<<4, nil)
+				c.conn.Close()
+				return
+			case <-done:
+				return
+			case <-tick.C:
+				c.write(mqttPingreq<<4, nil)
+			}
+		}
+	}()
+
+	for {
+		// a broker that stops answering our pings is gone
+		c.conn.SetReadDeadline(time.Now().Add(mqttKeepAlive * 3 / 2))
+		typ, flags, body, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		switch typ {
+		case mqttPublish:
+			m, id, err := parseMQTTPublish(flags, body)
+			if err != nil {
+				return err
+			}
+			if flags&0x06 != 0 { // QoS 1 (or 2, acknowledged the same way by a QoS 0 subscriber)
+				c.write(mqttPuback<<4, binary.BigEndian.AppendUint16(nil, id))
+			}
+			deliver(m)
+		case mqttSuback:
+			for _, code := range body[min(2, len(body)):] {
+				if code == 0x80 {
+					return errors.New("broker refused a subscription")
+				}
+			}
+		}
+	}
+}
+
+func (c *mqttClient) Close() error { return c.conn.Close() }
+
+func (c *mqttClient) write(header byte, body []byte) error {
+	pkt := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		pkt = append(pkt, b)
+		if n == 0 {
+			break
+		}
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(append(pkt, body...))
+	return err
+}
+
+func (c *mqttClient) read() (typ, flags byte, body []byte, err error) {
+	h, err := c.r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	n, shift := 0, 0
+	for i := 0; ; i++ {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		n |= int(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, 0, nil, errors.New("malformed MQTT packet length")
+		}
+	}
+	if n > mqttMaxPacket {
+		return 0, 0, nil, fmt.Errorf("MQTT packet of %d bytes is too large", n)
+	}
+	body = make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return h >> 4, h & 0x0f, body, nil
+}
+
+func parseMQTTPublish(flags byte, body []byte) (mqttMessage, uint16, error) {
+	if len(body) < 2 {
+		return mqttMessage{}, 0, errors.New("malformed MQTT publish")
+	}
+	tl := int(binary.BigEndian.Uint16(body))
+	body = body[2:]
+	if len(body) < tl {
+		return mqttMessage{}, 0, errors.New("malformed MQTT publish")
+	}
+	m := mqttMessage{Topic: string(body[:tl])}
+	body = body[tl:]
+	var id uint16
+	if flags&0x06 != 0 {
+		if len(body) < 2 {
+			return mqttMessage{}, 0, errors.New("malformed MQTT publish")
+		}
+		id = binary.BigEndian.Uint16(body)
+		body = body[2:]
+	}
+	m.Payload = body
+	return m, id, nil
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// readMQTTFrame reads one packet as it came over the wire.
+func readMQTTFrame(r *bufio.Reader) ([]byte, error) {
+	h, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	frame := []byte{h}
+	n, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		frame = append(frame, b)
+		n |= int(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(frame, body...), nil
+}
+
+func TestMQTTFramesGolden(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	frames := make(chan []byte, 3)
+	go func() { // a broker that accepts anyone
+		defer close(frames)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := range 3 {
+			f, err := readMQTTFrame(r)
+			if err != nil {
+				return
+			}
+			frames <- f
+			if i == 0 {
+				conn.Write([]byte{mqttConnack << 4, 2, 0, 0})
+			}
+		}
+	}()
+
+	c, err := dialMQTT(context.Background(), "mqtt://alice:s3cret@"+l.Addr().String(), "quichat-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.subscribe([]string{"quichat/ops/in", "quichat/ops/#"}); err != nil {
+		t.Fatal(err)
+	}
+	// long enough that the remaining length takes two bytes
+	if err := c.publish("quichat/ops/out", []byte(strings.Repeat("quichat ", 20))); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	for i, name := range []string{"connect", "subscribe", "publish"} {
+		f, ok := <-frames
+		if !ok {
+			t.Fatalf("the broker got %d packets, want 3", i)
+		}
+		fmt.Fprintf(&b, "%s %x\n", name, f)
+	}
+	golden(t, "mqtt_frames", []byte(b.String()))
+}
+
+func TestParseMQTTPublish(t *testing.T) {
+	body := append(appendMQTTString(nil, "quichat/ops/in"), 0x00, 0x07)
+	body = append(body, "hello"...)
+	m, id, err := parseMQTTPublish(0x02, body) // QoS 1 carries a packet ID
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Topic != "quichat/ops/in" || string(m.Payload) != "hello" || id != 7 {
+		t.Fatalf("parsed %q %q #%d", m.Topic, m.Payload, id)
+	}
+	if _, _, err := parseMQTTPublish(0, body[:5]); err == nil {
+		t.Fatal("a topic cut short was accepted")
+	}
+}
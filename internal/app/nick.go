@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// maxFormerNicks is how many earlier nicks are remembered per peer.
+const maxFormerNicks = 8
+
+// ownNick is the nick we go by, which /nick changes while the chat runs,
+// and the ones we went by before.
+type ownNick struct {
+	mu     sync.Mutex
+	cur    string
+	former []string
+}
+
+func (o *ownNick) get() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.cur
+}
+
+// set changes the nick and returns the previous one.
+func (o *ownNick) set(nick string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	old := o.cur
+	if old != "" {
+		o.former = rememberNick(o.former, old, nick)
+	}
+	o.cur = nick
+	return old
+}
+
+// all returns the current nick followed by the earlier ones.
+func (o *ownNick) all() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string{o.cur}, o.former...)
+}
+
+// rememberNick adds old to a list of earlier nicks, most recent last,
+// dropping cur (a peer going back to an old nick) and the oldest beyond
+// maxFormerNicks.
+func rememberNick(former []string, old, cur string) []string {
+	former = slices.DeleteFunc(slices.Clone(former), func(n string) bool { return n == old || n == cur })
+	former = append(former, old)
+	if len(former) > maxFormerNicks {
+		former = former[len(former)-maxFormerNicks:]
+	}
+	return former
+}
+
+// myNick is the nick we currently go by.
+func (c *Chat) myNick() string { return c.own.get() }
+
+// mentionsMe reports whether text names us by our nick or one we had
+// before, so replies to an old nick still reach us after /nick.
+func (c *Chat) mentionsMe(text string) bool {
+	for _, nick := range c.own.all() {
+		if mentions(text, nick) {
+			return true
+		}
+	}
+	return false
+}
+
+// renamed notes that p, known to us, now sends as nick. It returns the
+// nick p went by until now if that changed.
+func (pr *presence) renamed(p peer.ID, nick string) (string, bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	e, ok := pr.seen[p]
+	if !ok || e.nick == nick {
+		return "", false
+	}
+	old := e.nick
+	e.former = rememberNick(e.former, old, nick)
+	e.nick = nick
+	pr.seen[p] = e
+	return old, true
+}
+
+// nickOf returns the label p is shown under now, or "" if p is unknown.
+func (pr *presence) nickOf(p peer.ID) string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	e, ok := pr.seen[p]
+	if !ok {
+		return ""
+	}
+	return pr.labelLocked(p, e.nick, "")
+}
+
+// runNick implements /nick [name]: show our nick, or change it and tell the
+// room straight away rather than with the next heartbeat.
+func (c *Chat) runNick(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		c.ui.Print(fmt.Sprintf(T("You are %s"), c.myNick()))
+		return
+	}
+	nick := strings.Join(args, " ")
+	if !utf8.ValidString(nick) || len(nick) > maxNickLen || sanitize(nick, false) != nick || strings.ContainsAny(nick, "~\n") {
+		c.ui.Print(fmt.Sprintf(T("Invalid nick: use up to %d bytes, no control characters or ~"), maxNickLen))
+		return
+	}
+	if nick == c.myNick() {
+		return
+	}
+	old := c.own.set(nick)
+	c.ui.Print("\033[1;32m" + fmt.Sprintf(T("*** You are now known as %s (were %s) ***"), nick, old) + ansiReset)
+	// a failed announcement is repeated by the next heartbeat
+	_ = c.sendControl(ctx, Message{Nick: nick, Text: joinText, Ts: time.Now().UTC(), Wire: wireProto, Caps: c.n.caps()})
+	c.refreshStatus()
+}
@@ -2,23 +2,175 @@ package app
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	libp2p "github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	host "github.com/libp2p/go-libp2p/core/host"
+	metrics "github.com/libp2p/go-libp2p/core/metrics"
 	network "github.com/libp2p/go-libp2p/core/network"
 	peer "github.com/libp2p/go-libp2p/core/peer"
+	relay "github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
 	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultRoom is the room every node joins unless told otherwise.
+const DefaultRoom = "peerchat:global"
+
+// maxNetworkLen is the longest --network name, from the flag or an invite.
+const maxNetworkLen = 64
+
+// ValidateNetwork checks a network name, whether typed as --network or
+// read from an invite: at most maxNetworkLen bytes of UTF-8, without
+// slashes, whitespace or control characters.
+func ValidateNetwork(s string) error {
+	bad := func(r rune) bool { return r == '/' || unicode.IsSpace(r) || unicode.IsControl(r) }
+	if len(s) > maxNetworkLen || !utf8.ValidString(s) || strings.ContainsFunc(s, bad) {
+		return fmt.Errorf("must be at most %d characters without slashes, spaces or control characters, not %q", maxNetworkLen, s)
+	}
+	return nil
+}
+
+// topicName is the gossipsub topic of room in network: an HMAC of the room
+// name keyed with the network, so the relays and routers that see topic
+// announcements can't read room or network names off them, nor match a
+// room across networks. legacy gives the readable names of older releases,
+// the bare room name or quichat/<network>/room/<room>.
+//
+// Nothing secret goes into the HMAC, so it hides names only as well as they
+// resist guessing: anyone can hash "general" under "acme" and look for the
+// topic. Keying it with a secret would stop people joining a room by name,
+// so a room that must stay hidden needs a name, or network, nobody guesses.
+func topicName(network, room string, legacy bool) string {
+	switch {
+	case legacy && network == "":
+		return room
+	case legacy:
+		return "quichat/" + network + "/room/" + room
+	}
+	mac := hmac.New(sha256.New, []byte("quichat/network/"+network))
+	mac.Write([]byte(room))
+	return "quichat/" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Options configures a Node.
+type Options struct {
+	Nick      string // empty picks the room's identity from the config, or "anon"
+	Port      string
+	Bootstrap []string // multiaddrs of bootstrap peers, all dialed at once
+	Invite    *Invite  // takes precedence over Bootstrap when set
+	NoIPv6    bool     // listen on IPv4 only
+	Verbose   bool     // also show loopback addresses
+
+	AllowPeers  []peer.ID // if non-nil, only these peers may connect
+	Config      *Config
+	Proxy       string // socks5:// URL for outbound dials
+	Serve       bool   // run as a public bootstrap/relay node
+	Quiet       bool   // hide joins and connection notices
+	PubsubTrace string // file to write gossipsub trace events to
+	Pprof       string // address to serve net/http/pprof on
+	Wire        string // WireAuto (default) or WireJSON
+	MaxMembers  int    // cap the room and run it as its operator; 0 leaves it open
+	Welcome     string // shown to peers waiting in the lobby of a capped room
+
+	DialTimeout      time.Duration // per dial; zero keeps libp2p's default and 60s for the bootstrap peer
+	BootstrapMin     int           // bootstrap peers that must connect before startup goes on; 0 means 1
+	BootstrapRetries int           // extra attempts at the bootstrap peer after the first fails
+	BootstrapBackoff time.Duration // wait before the first retry, doubled after each
+	MaxUpload        int64         // bytes per second sent over all connections; 0 is unlimited
+	MaxDownload      int64         // bytes per second received over all connections; 0 is unlimited
+	Cover            time.Duration // send one fixed-size frame per interval, dummies if idle; 0 disables
+
+	Transcript    string // append delivered chat lines to this file as a signed hash chain
+	TranscriptMax int64  // bytes the transcript and its rotated parts may take; 0 is unlimited
+	API           string // address for the HTTP endpoint that posts into the room
+	Record        string // write every event of the session to this file for quichat replay
+	TTS           string // TTSAll or TTSMentions to read messages aloud; "" keeps quiet
+	StatusBar     bool   // keep a line with the room's state at the bottom of the terminal
+	OfflineLAN    bool   // no DHT, relays or update check; find peers by LAN multicast and --bootstrap
+	Network       string // namespace for topics, kept apart from every other network; "" is the public one
+	LegacyTopics  bool   // join rooms under their readable topic names, to meet clients that predate hashing
+	KeepKey       bool   // keep the room's keypair between runs, so peers can bind our nick to it
+}
+
 // Node encapsulates a libp2p host with DHT and PubSub functionality.
 type Node struct {
-	ctx           context.Context
-	nick, port    string
-	bootstrapAddr string
+	ctx            context.Context
+	cancel         context.CancelFunc // ends ctx; Close calls it first
+	nick, port     string
+	bootstrapAddrs []string
+	bootstrapMin   int
+	invite         *Invite
+	room           string
+	network        string
+	topic          string // gossipsub topic of room in network
+	legacyTopics   bool
+	keepKey        bool
+	identityKey    string // name of the identity's own keypair; "" uses the room's
+	noIPv6         bool
+	verbose        bool
+	quiet          bool
+	pubsubTrace    string
+	wire           string
+	maxMembers     int
+	welcome        string
+	cover          time.Duration
+	transcript     *transcript
+	record         *recorder
+	apiAddr        string
+	tts            string
+	statusBar      bool
+	lanOnly        bool
+	proxyURL       string
+	dialTimeout    time.Duration
+	retries        int
+	backoff        time.Duration
+	notices        chan string
+	bootstrapID    peer.ID
+	operator       peer.ID // whose rosters we follow; ourselves with --max-members
+
+	addrMu    sync.Mutex
+	lastAddrs string // last address list shown to the user
+
+	reach       atomic.Int32 // network.Reachability from AutoNAT
+	relaysInUse atomic.Int32 // relays AutoRelay reserved a slot with
+
+	rendezvous rendezvousState
+	throttle   throttle
+
+	addrBook   *addrBook
+	reputation *reputation
+	tofu       *tofu
+	gater      *gater
+	cfg        *Config
+	relay      *relayState
+
+	serve      bool
+	relayStats *relayStats
+	mesh       *meshTracer
+	records    signedRecords // for peer exchange
+	eventTrace traceFile     // nil unless --pubsub-trace
+	endTracing func()
+	started    time.Time
 
 	Host   host.Host
 	DHT    *dht.IpfsDHT
@@ -28,53 +180,365 @@ type Node struct {
 }
 
 // NewNode constructs and initializes a Node.
-func NewNode(ctx context.Context, nick, port, bootstrapAddr string) (*Node, error) {
-	n := &Node{ctx: ctx, nick: nick, port: port, bootstrapAddr: bootstrapAddr}
-
-	// Step-by-step initialization
-	if err := n.initHost(); err != nil {
+func NewNode(ctx context.Context, opts Options) (_ *Node, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	n := &Node{
+		ctx:            ctx,
+		cancel:         cancel,
+		nick:           opts.Nick,
+		port:           opts.Port,
+		bootstrapAddrs: opts.Bootstrap,
+		bootstrapMin:   max(opts.BootstrapMin, 1),
+		invite:         opts.Invite,
+		room:           DefaultRoom,
+		network:        opts.Network,
+		legacyTopics:   opts.LegacyTopics,
+		keepKey:        opts.KeepKey,
+		noIPv6:         opts.NoIPv6,
+		verbose:        opts.Verbose,
+		quiet:          opts.Quiet,
+		pubsubTrace:    opts.PubsubTrace,
+		wire:           opts.Wire,
+		maxMembers:     opts.MaxMembers,
+		welcome:        opts.Welcome,
+		cover:          opts.Cover,
+		apiAddr:        opts.API,
+		tts:            opts.TTS,
+		statusBar:      opts.StatusBar,
+		lanOnly:        opts.OfflineLAN,
+		proxyURL:       opts.Proxy,
+		dialTimeout:    opts.DialTimeout,
+		retries:        opts.BootstrapRetries,
+		backoff:        opts.BootstrapBackoff,
+		notices:        make(chan string, 32),
+		gater:          &gater{},
+		cfg:            opts.Config,
+		serve:          opts.Serve,
+		mesh:           newMeshTracer(),
+		started:        time.Now(),
+		endTracing:     startTracing(),
+	}
+	// whatever fails below, don't leave the context or the tracer running
+	defer func() {
+		if err != nil {
+			n.Close()
+		}
+	}()
+	if n.wire == "" {
+		n.wire = WireAuto
+	}
+	if n.backoff <= 0 {
+		n.backoff = defaultBootstrapBackoff
+	}
+	if n.cfg == nil {
+		cfg, err := LoadConfig("")
+		if err != nil {
+			return nil, err
+		}
+		n.cfg = cfg
+	}
+	n.throttle.up.set(opts.MaxUpload)
+	n.throttle.down.set(opts.MaxDownload)
+	if opts.AllowPeers != nil {
+		n.gater.setAllowlist(opts.AllowPeers)
+	}
+	if err := n.gater.loadRules(n.cfg.Gater); err != nil {
+		return nil, err
+	}
+	if err := validateWebhooks(n.cfg.Webhooks); err != nil {
+		return nil, err
+	}
+	if err := validateFeeds(n.cfg.Feeds); err != nil {
 		return nil, err
 	}
-	if err := n.initDHT(); err != nil {
+	if err := validateTranslate(n.cfg.Translate); err != nil {
 		return nil, err
 	}
-	if err := n.connectBootstrapPeer(); err != nil {
+	if n.apiAddr != "" && len(n.cfg.API.Tokens) == 0 {
+		return nil, errors.New("--api needs at least one token under api.tokens in the config")
+	}
+	static, err := parseStaticRelays(n.cfg.Relays.Static)
+	if err != nil {
+		return nil, err
+	}
+	n.relay = &relayState{
+		static: static,
+		budget: n.cfg.Relays.BudgetMB << 20,
+		bw:     metrics.NewBandwidthCounter(),
+	}
+	if n.invite != nil && n.invite.Room != "" {
+		n.room, n.network, n.legacyTopics = n.invite.Room, n.invite.Network, n.invite.LegacyTopics
+	}
+	n.topic = topicName(n.network, n.room, n.legacyTopics)
+	id := n.cfg.identity(n.room)
+	if n.nick == "" {
+		n.nick = id.Nick
+	}
+	if id.Key != "" {
+		if err := validKeyName(id.Key); err != nil {
+			return nil, fmt.Errorf("identity key %q: %w", id.Key, err)
+		}
+		n.identityKey = id.Key
+	}
+	n.keepKey = n.keepKey || id.KeepKey || id.Key != ""
+	if n.operator, err = n.roomOperator(); err != nil {
 		return nil, err
 	}
-	if err := n.initPubSub(); err != nil {
+
+	if err := n.start(ctx); err != nil {
 		return nil, err
 	}
+	if n.maxMembers > 0 {
+		n.operator = n.Host.ID()
+	}
+	if opts.Transcript != "" {
+		t, err := openTranscript(opts.Transcript, n.Host.Peerstore().PrivKey(n.Host.ID()), n.Host.ID(), opts.TranscriptMax, n.notify)
+		if err != nil {
+			return nil, err
+		}
+		n.transcript = t
+	}
+	if opts.Record != "" {
+		r, err := openRecorder(opts.Record)
+		if err == nil {
+			err = r.start(n.nick, n.room)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record: %w", err)
+		}
+		n.record = r
+	}
 	n.registerJoinNotifier()
 	n.printReachableAddr()
+	if n.relay.budget > 0 {
+		go n.watchRelayBudget()
+	}
+	if n.DHT != nil && !n.serve {
+		go n.maintainRendezvous()
+	}
+	if opts.Pprof != "" {
+		go func() {
+			if err := servePprof(ctx, opts.Pprof); err != nil {
+				n.notify("pprof: %v", err)
+			}
+		}()
+	}
+	// only when asked to, never around a --proxy or off the internet, and not
+	// without a key to check a release with
+	if n.cfg.Updates.Check && n.cfg.Updates.key() != "" && !n.serve && n.proxyURL == "" && !n.lanOnly {
+		go n.checkForUpdate()
+	}
 	n.printWelcomeBanner()
 
 	return n, nil
 }
 
+// start runs the step-by-step initialization, each step traced as a child
+// of one node.start span. Steps with a label are shown as startup progress.
+func (n *Node) start(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "node.start", trace.WithAttributes(
+		attribute.String("room", n.room),
+		attribute.String("network", n.network),
+		attribute.Bool("serve", n.serve),
+	))
+	defer span.End()
+
+	join := "Joining room"
+	if n.serve {
+		join = "Starting gossip router"
+	}
+	steps := []struct {
+		name, label, hint string
+		run               func() (string, error)
+	}{
+		{"host.init", "", "", silent(n.initHost)},
+		{"host.events", "", "", silent(n.watchEvents)},
+		{"addrbook.load", "", "", silent(n.initAddrBook)},
+		{"reputation.load", "", "", silent(n.initReputation)},
+		{"tofu.load", "", "", silent(n.initTOFU)},
+		{"dht.init", "", "", silent(n.initDHT)},
+		{"bootstrap.connect", "Connecting to bootstrap peers", "still dialing; check the address, or try --proxy", n.connectBootstrapPeers},
+		{"dht.bootstrap", "Bootstrapping DHT", "waiting for the bootstrap peer to share its routing table", n.waitForDHT},
+		{"pubsub.join", join, "", silent(n.initPubSub)},
+		{"px.init", "", "", silent(n.initPeerExchange)},
+		{"lan.discover", "", "", silent(n.initLANDiscovery)},
+	}
+	for _, step := range steps {
+		switch {
+		case step.name == "bootstrap.connect" && n.invite == nil && len(n.bootstrapAddrs) == 0,
+			strings.HasPrefix(step.name, "dht.") && n.lanOnly,
+			step.name == "lan.discover" && !n.lanOnly:
+			continue
+		}
+		err := traced(ctx, step.name, func() error {
+			if step.label == "" {
+				_, err := step.run()
+				return err
+			}
+			return stage(step.label, step.hint, step.run)
+		})
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// silent adapts a step with nothing to report for start.
+func silent(fn func() error) func() (string, error) {
+	return func() (string, error) { return "", fn() }
+}
+
+// portRetries is how many ports above the requested one we try when it is busy.
+const portRetries = 10
+
 // initHost sets up the libp2p Host with AutoRelay.
 func (n *Node) initHost() error {
-	var err error
-	n.Host, err = libp2p.New(
-		libp2p.ListenAddrStrings(
-			"/ip4/0.0.0.0/tcp/"+n.port,
-			"/ip4/0.0.0.0/udp/"+n.port+"/quic-v1",
-		),
-		libp2p.EnableAutoRelayWithPeerSource(n.relayCandidates),
-	)
-	return err
+	port, err := n.pickPort()
+	if err != nil {
+		return err
+	}
+	n.port = strconv.Itoa(port)
+
+	opts := []libp2p.Option{
+		libp2p.ListenAddrStrings(n.listenAddrs()...),
+		libp2p.ConnectionGater(n.gater),
+		libp2p.BandwidthReporter(n.relay.bw),
+	}
+	if n.proxyURL == "" {
+		opts = append(opts, n.transportOptions()...)
+	}
+	if n.keepKey {
+		key, err := roomKey(n.topic)
+		if n.identityKey != "" {
+			key, err = identityKey(n.identityKey)
+		}
+		if err != nil {
+			return fmt.Errorf("room key: %w", err)
+		}
+		opts = append(opts, libp2p.Identity(key))
+	}
+	if !n.lanOnly {
+		opts = append(opts, libp2p.EnableAutoRelayWithPeerSource(n.relayCandidates))
+	}
+	if n.dialTimeout > 0 {
+		opts = append(opts, libp2p.WithDialTimeout(n.dialTimeout))
+	}
+	if n.serve {
+		n.relayStats = &relayStats{}
+		opts = append(opts,
+			libp2p.EnableRelayService(relay.WithMetricsTracer(n.relayStats)),
+			libp2p.EnableNATService(),
+		)
+	}
+	if n.proxyURL != "" {
+		popts, err := n.proxyOptions()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, popts...)
+	}
+	n.Host, err = libp2p.New(opts...)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return portInUse(port, port, err) // taken since pickPort looked
+	}
+	if err != nil {
+		return err
+	}
+	n.printListenAddrs()
+	return nil
+}
+
+// listenAddrs returns the TCP and QUIC listen multiaddrs for n.port,
+// including the IPv6 wildcard unless disabled. Behind a proxy only TCP is used.
+func (n *Node) listenAddrs() []string {
+	addrs := []string{"/ip4/0.0.0.0/tcp/" + n.port}
+	if n.proxyURL == "" {
+		addrs = append(addrs, "/ip4/0.0.0.0/udp/"+n.port+"/quic-v1")
+	}
+	if !n.noIPv6 {
+		addrs = append(addrs, "/ip6/::/tcp/"+n.port)
+		if n.proxyURL == "" {
+			addrs = append(addrs, "/ip6/::/udp/"+n.port+"/quic-v1")
+		}
+	}
+	return addrs
+}
+
+// pickPort returns the requested port, or the next free one if it is in use.
+// Port 0 is passed through so the OS picks an ephemeral port.
+func (n *Node) pickPort() (int, error) {
+	port, err := strconv.Atoi(n.port)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid listen port %q", n.port)
+	}
+	if port == 0 {
+		return 0, nil
+	}
+	for try := port; try <= port+portRetries && try <= 65535; try++ {
+		err = portFree(try)
+		if err == nil {
+			if try != port {
+				fmt.Fprintln(termOut, termText("\033[33m"+fmt.Sprintf(T("warning: port %d is in use, listening on %d instead"), port, try)+ansiReset))
+			}
+			return try, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return 0, fmt.Errorf("listen on port %d: %w", try, err)
+		}
+	}
+	return 0, portInUse(port, min(port+portRetries, 65535), err)
+}
+
+// portFree reports whether both the TCP and the UDP (QUIC) port can be bound.
+func portFree(port int) error {
+	l, err := net.Listen("tcp4", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	l.Close()
+	pc, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return pc.Close()
 }
 
-// relayCandidates provides peers from the DHT routing table for AutoRelay.
+// printListenAddrs outputs the addresses the host actually bound.
+func (n *Node) printListenAddrs() {
+	for _, a := range n.Host.Network().ListenAddresses() {
+		fmt.Printf(T("Listening on %s")+"\n", a)
+	}
+}
+
+// relayCandidates provides AutoRelay with the preferred relays from the
+// config first, then peers from the DHT routing table.
 func (n *Node) relayCandidates(ctx context.Context, num int) <-chan peer.AddrInfo {
 	ch := make(chan peer.AddrInfo, num)
 	go func() {
 		defer close(ch)
+		send := func(ai peer.AddrInfo) bool {
+			select {
+			case ch <- ai:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, ai := range n.relay.static {
+			if !send(ai) {
+				return
+			}
+		}
 		if n.DHT == nil {
 			return
 		}
 		for _, pid := range n.DHT.RoutingTable().ListPeers() {
 			addrs := n.Host.Peerstore().Addrs(pid)
-			ch <- peer.AddrInfo{ID: pid, Addrs: addrs}
+			if !send(peer.AddrInfo{ID: pid, Addrs: addrs}) {
+				return
+			}
 		}
 	}()
 	return ch
@@ -83,41 +547,117 @@ func (n *Node) relayCandidates(ctx context.Context, num int) <-chan peer.AddrInf
 // initDHT creates and bootstraps the DHT.
 func (n *Node) initDHT() error {
 	var err error
-	n.DHT, err = dht.New(n.ctx, n.Host, dht.Mode(dht.ModeAuto))
+	mode := dht.ModeAuto
+	if n.serve {
+		mode = dht.ModeServer
+	}
+	n.DHT, err = dht.New(n.ctx, n.Host, dht.Mode(mode))
 	if err != nil {
 		return err
 	}
 	return n.DHT.Bootstrap(n.ctx)
 }
 
-// connectBootstrapPeer attempts a timed dial to the bootstrap node.
-func (n *Node) connectBootstrapPeer() error {
-	if n.bootstrapAddr == "" {
-		return nil
+const (
+	defaultBootstrapTimeout = 60 * time.Second
+	defaultBootstrapBackoff = 2 * time.Second
+)
+
+// dhtSettle bounds how long startup waits for the routing table to fill.
+const dhtSettle = 10 * time.Second
+
+// waitForDHT gives the routing table a moment to fill once we have dialed a
+// bootstrap peer that serves the DHT, and reports how many peers it holds.
+func (n *Node) waitForDHT() (string, error) {
+	deadline := time.Now().Add(dhtSettle)
+	for n.bootstrapID != "" && n.DHT.RoutingTable().Size() == 0 && time.Now().Before(deadline) {
+		// once identify has run we know whether waiting can help
+		protos, _ := n.Host.Peerstore().GetProtocols(n.bootstrapID)
+		if len(protos) > 0 && !slices.Contains(protos, dht.ProtocolDHT) {
+			break
+		}
+		select {
+		case <-n.ctx.Done():
+			return "", n.ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
-	maddr, err := ma.NewMultiaddr(n.bootstrapAddr)
-	if err != nil {
-		return fmt.Errorf("invalid bootstrap multiaddr %q: %w", n.bootstrapAddr, err)
+	return fmt.Sprintf(T("%d peers"), n.DHT.RoutingTable().Size()), nil
+}
+
+// connectBootstrapPeers dials every bootstrap peer (the invite's, or those
+// the --bootstrap addresses name) at once and returns as soon as
+// n.bootstrapMin of them are connected; the other dials go on in the
+// background. Peers that failed are dialed again up to n.retries more times
+// with exponential backoff.
+func (n *Node) connectBootstrapPeers() (string, error) {
+	var infos []peer.AddrInfo
+	if n.invite != nil {
+		infos = []peer.AddrInfo{n.invite.Peer}
+	} else {
+		var err error
+		if infos, err = n.resolveBootstraps(); err != nil {
+			return "", err
+		}
 	}
-	info, err := peer.AddrInfoFromP2pAddr(maddr)
-	fmt.Println(info)
-	if err != nil {
-		return fmt.Errorf("invalid bootstrap multiaddr %q: %w", n.bootstrapAddr, err)
+	need := min(n.bootstrapMin, len(infos))
+	timeout := n.dialTimeout
+	if timeout <= 0 {
+		timeout = defaultBootstrapTimeout
 	}
-	dialCtx, cancel := context.WithTimeout(n.ctx, 60*time.Second)
-	defer cancel()
 
-	return n.Host.Connect(dialCtx, *info)
+	backoff := n.backoff
+	for try := 0; ; try++ {
+		ctx := n.ctx
+		if try > 0 {
+			// the swarm remembers the failure; dial again regardless
+			ctx = network.WithForceDirectDial(ctx, "bootstrap retry")
+		}
+		connected, err := n.dialAll(ctx, infos, need, timeout)
+		if connected >= need {
+			n.bootstrapID = n.firstConnected(infos)
+			return fmt.Sprintf(T("%d of %d connected"), connected, len(infos)), nil
+		}
+		if try == n.retries {
+			if need > 1 {
+				err = fmt.Errorf("%d of %d bootstrap peers connected, %d needed: %w", connected, len(infos), need, err)
+			}
+			if n.retries > 0 {
+				err = fmt.Errorf("%d attempts: %w", try+1, err)
+			}
+			return "", n.bootstrapUnreachable(err)
+		}
+		select {
+		case <-n.ctx.Done():
+			return "", n.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
 }
 
-// initPubSub sets up GossipSub and subscribes to the global topic.
+// initPubSub sets up GossipSub and subscribes to the room's topic.
+// Serve-mode nodes only route gossip and never subscribe.
 func (n *Node) initPubSub() error {
+	opts := []pubsub.Option{pubsub.WithRawTracer(n.mesh)}
+	if n.pubsubTrace != "" {
+		tracer, err := newTraceFile(n.pubsubTrace)
+		if err != nil {
+			return fmt.Errorf("pubsub trace: %w", err)
+		}
+		n.eventTrace = tracer
+		opts = append(opts, pubsub.WithEventTracer(tracer))
+	}
+
 	var err error
-	n.PubSub, err = pubsub.NewGossipSub(n.ctx, n.Host)
+	n.PubSub, err = pubsub.NewGossipSub(n.ctx, n.Host, opts...)
 	if err != nil {
 		return err
 	}
-	n.Topic, err = n.PubSub.Join("peerchat:global")
+	if n.serve {
+		return nil
+	}
+	n.Topic, err = n.PubSub.Join(n.topic)
 	if err != nil {
 		return err
 	}
@@ -125,6 +665,33 @@ func (n *Node) initPubSub() error {
 	return err
 }
 
+// Close shuts the node down and flushes the pubsub trace file and any
+// buffered OpenTelemetry spans. The node's context is cancelled first, so
+// gossipsub and everything else running on it has stopped before the DHT
+// and the host underneath are closed.
+func (n *Node) Close() {
+	n.cancel()
+	if n.DHT != nil {
+		n.DHT.Close()
+	}
+	if n.Host != nil {
+		n.Host.Close()
+	}
+	if n.eventTrace != nil {
+		n.eventTrace.Close()
+	}
+	if n.transcript != nil {
+		n.transcript.Close()
+	}
+	if n.record != nil {
+		n.record.Close()
+	}
+	if n.reputation != nil {
+		n.reputation.save()
+	}
+	n.endTracing()
+}
+
 // registerJoinNotifier publishes a "joined" message on new connections.
 func (n *Node) registerJoinNotifier() {
 	n.Host.Network().Notify(&network.NotifyBundle{
@@ -133,10 +700,85 @@ func (n *Node) registerJoinNotifier() {
 	})
 }
 
-// printReachableAddr outputs one of the host's listen addresses.
+// printReachableAddr outputs every address peers could use to reach us.
 func (n *Node) printReachableAddr() {
-	addr := n.Host.Addrs()[0]
-	fmt.Printf("Your multiaddr: %s/p2p/%s\n", addr, n.Host.ID().String())
+	list := n.formatAddrs(T("Your multiaddrs:"))
+	n.addrMu.Lock()
+	n.lastAddrs = list[strings.Index(list, "\n")+1:]
+	n.addrMu.Unlock()
+	fmt.Print(list)
+}
+
+// formatAddrs renders the visible addresses as dialable /p2p multiaddrs.
+func (n *Node) formatAddrs(title string) string {
+	var b strings.Builder
+	b.WriteString(title + "\n")
+	for _, a := range n.visibleAddrs(n.Host.Addrs()) {
+		fmt.Fprintf(&b, "  %s/p2p/%s\n", a, n.Host.ID())
+	}
+	return b.String()
+}
+
+// visibleAddrs sorts addrs and drops loopback ones unless verbose is set.
+// If nothing but loopback is left, the loopback addresses are kept.
+func (n *Node) visibleAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	sorted := sortAddrs(addrs)
+	var out []ma.Multiaddr
+	for _, a := range sorted {
+		if n.shownAddr(a) {
+			out = append(out, a)
+		}
+	}
+	if len(out) == 0 {
+		return sorted
+	}
+	return out
+}
+
+// shownAddr reports whether a is worth printing at the current verbosity.
+func (n *Node) shownAddr(a ma.Multiaddr) bool {
+	return n.verbose || !manet.IsIPLoopback(a)
+}
+
+// Nick is the display name in effect for this node's room.
+func (n *Node) Nick() string { return n.nick }
+
+// Notices streams status lines (address changes and the like) for the UI.
+func (n *Node) Notices() <-chan string { return n.notices }
+
+// notify queues a status line for the UI in the active language, dropping it
+// if nobody is reading.
+func (n *Node) notify(format string, args ...any) {
+	select {
+	case n.notices <- fmt.Sprintf(T(format), args...):
+	default:
+	}
+}
+
+// verboseNotify is notify for diagnostics shown only with --verbose.
+func (n *Node) verboseNotify(format string, args ...any) {
+	if n.verbose {
+		n.notify(format, args...)
+	}
+}
+
+// sortAddrs orders addresses public first, then private, then loopback.
+func sortAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	rank := func(a ma.Multiaddr) int {
+		switch {
+		case manet.IsPublicAddr(a):
+			return 0
+		case manet.IsIPLoopback(a):
+			return 2
+		default:
+			return 1
+		}
+	}
+	sorted := slices.Clone(addrs)
+	slices.SortStableFunc(sorted, func(a, b ma.Multiaddr) int {
+		return rank(a) - rank(b)
+	})
+	return sorted
 }
 
 func (n *Node) printWelcomeBanner() {
@@ -147,6 +789,8 @@ func (n *Node) printWelcomeBanner() {
  |_| /___|_|    \__\_\\___/|___\___|_||_/_/ \_|_|  
                                                                                                                                                                                                                                                    
 `
-	fmt.Println(banner)
-	fmt.Println("Welcome to P2P Quichat! 🚀")
+	if !accessible {
+		fmt.Println(banner)
+	}
+	fmt.Println(T("Welcome to P2P Quichat! 🚀"))
 }
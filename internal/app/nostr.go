@@ -0,0 +1,578 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/gorilla/websocket"
+	"golang.org/x/sync/errgroup"
+)
+
+// Nostr bridge: room lines become kind-1 notes tagged with the room, signed
+// by a key derived from the bridge's secret and the sender's nick, so each
+// room member has a stable Nostr identity for as long as the bridge keeps
+// its secret. Notes carrying the tag from anyone else are posted to the room.
+const (
+	nostrKeyFile     = "nostr.key"
+	nostrDialTimeout = 10 * time.Second
+	nostrSeenMax     = 1000 // event IDs remembered to drop a relay's repeats
+	nostrKindMeta    = 0
+	nostrKindNote    = 1
+)
+
+// NostrBridgeOptions configures RunNostrBridge.
+type NostrBridgeOptions struct {
+	Relay string // ws:// or wss:// URL
+	Tag   string // value of the "t" tag marking the room's notes
+}
+
+// nostrEvent is a NIP-01 event.
+type nostrEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// nostrKey is a secp256k1 secret with its x-only public key.
+type nostrKey struct {
+	d  secp256k1.ModNScalar
+	px [32]byte
+}
+
+// newNostrKey derives a key from 32 bytes of secret material. It fails only
+// for the negligible share of inputs that are zero or not below the order.
+func newNostrKey(secret []byte) (*nostrKey, error) {
+	k := new(nostrKey)
+	if overflow := k.d.SetByteSlice(secret); overflow || k.d.IsZero() {
+		return nil, errors.New("secret is not a valid secp256k1 key")
+	}
+	var p secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&k.d, &p)
+	p.ToAffine()
+	if p.Y.IsOdd() {
+		k.d.Negate() // BIP-340 keys are the even-Y point for the x coordinate
+	}
+	p.X.PutBytes(&k.px)
+	return k, nil
+}
+
+func (k *nostrKey) pubkey() string { return hex.EncodeToString(k.px[:]) }
+
+func taggedHash(tag string, parts ...[]byte) [32]byte {
+	t := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(t[:])
+	h.Write(t[:])
+	for _, p := range parts {
+		h.Write(p)
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// sign returns the BIP-340 Schnorr signature of the 32-byte msg.
+func (k *nostrKey) sign(msg [32]byte) ([64]byte, error) {
+	var aux [32]byte
+	if _, err := rand.Read(aux[:]); err != nil {
+		return [64]byte{}, err
+	}
+	return k.signAux(msg, aux)
+}
+
+// signAux signs with the given auxiliary randomness.
+func (k *nostrKey) signAux(msg, aux [32]byte) ([64]byte, error) {
+	var sig [64]byte
+	d := k.d.Bytes()
+	t := taggedHash("BIP0340/aux", aux[:])
+	for i := range t {
+		t[i] ^= d[i]
+	}
+	nonce := taggedHash("BIP0340/nonce", t[:], k.px[:], msg[:])
+	var kn secp256k1.ModNScalar
+	kn.SetByteSlice(nonce[:])
+	if kn.IsZero() {
+		return sig, errors.New("zero nonce")
+	}
+	var r secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&kn, &r)
+	r.ToAffine()
+	if r.Y.IsOdd() {
+		kn.Negate()
+	}
+	var rx [32]byte
+	r.X.PutBytes(&rx)
+	e := nostrChallenge(rx[:], k.px[:], msg[:])
+	s := new(secp256k1.ModNScalar).Mul2(&e, &k.d).Add(&kn)
+	copy(sig[:32], rx[:])
+	s.PutBytesUnchecked(sig[32:])
+	return sig, nil
+}
+
+func nostrChallenge(rx, px, msg []byte) secp256k1.ModNScalar {
+	h := taggedHash("BIP0340/challenge", rx, px, msg)
+	var e secp256k1.ModNScalar
+	e.SetByteSlice(h[:])
+	return e
+}
+
+// schnorrVerify checks a BIP-340 signature by the x-only public key px.
+func schnorrVerify(px, msg, sig []byte) bool {
+	if len(px) != 32 || len(msg) != 32 || len(sig) != 64 {
+		return false
+	}
+	var x, y secp256k1.FieldVal
+	if x.SetByteSlice(px) || !secp256k1.DecompressY(&x, false, &y) {
+		return false
+	}
+	var rx secp256k1.FieldVal
+	if rx.SetByteSlice(sig[:32]) {
+		return false
+	}
+	var s secp256k1.ModNScalar
+	if s.SetByteSlice(sig[32:]) {
+		return false
+	}
+	e := nostrChallenge(sig[:32], px, msg)
+	e.Negate()
+
+	// R = s·G - e·P must have an even Y and the signature's X.
+	p := secp256k1.MakeJacobianPoint(&x, &y, new(secp256k1.FieldVal).SetInt(1))
+	var sg, ep, r secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&s, &sg)
+	secp256k1.ScalarMultNonConst(&e, &p, &ep)
+	secp256k1.AddNonConst(&sg, &ep, &r)
+	if (r.X.IsZero() && r.Y.IsZero()) || r.Z.IsZero() {
+		return false
+	}
+	r.ToAffine()
+	return !r.Y.IsOdd() && r.X.Equals(&rx)
+}
+
+// nostrEventID hashes the NIP-01 serialization of ev.
+func nostrEventID(ev *nostrEvent) [32]byte {
+	var b bytes.Buffer
+	b.WriteString("[0,")
+	nostrString(&b, ev.PubKey)
+	fmt.Fprintf(&b, ",%d,%d,[", ev.CreatedAt, ev.Kind)
+	for i, tag := range ev.Tags {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('[')
+		for j, v := range tag {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			nostrString(&b, v)
+		}
+		b.WriteByte(']')
+	}
+	b.WriteString("],")
+	nostrString(&b, ev.Content)
+	b.WriteByte(']')
+	return sha256.Sum256(b.Bytes())
+}
+
+// nostrString writes s as a JSON string escaped exactly as NIP-01 asks:
+// the seven escapes it lists, and every other character verbatim, control
+// characters included. encoding/json escapes more (<, >, &, U+2028, \u001b),
+// which changes the ID.
+func nostrString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// signEvent fills in ev's pubkey, ID and signature.
+func (k *nostrKey) signEvent(ev *nostrEvent) error {
+	ev.PubKey = k.pubkey()
+	id := nostrEventID(ev)
+	sig, err := k.sign(id)
+	if err != nil {
+		return err
+	}
+	ev.ID = hex.EncodeToString(id[:])
+	ev.Sig = hex.EncodeToString(sig[:])
+	return nil
+}
+
+// verify reports whether ev's ID matches its content and is signed by its
+// pubkey.
+func (ev *nostrEvent) verify() bool {
+	id := nostrEventID(ev)
+	if hex.EncodeToString(id[:]) != ev.ID {
+		return false
+	}
+	px, err1 := hex.DecodeString(ev.PubKey)
+	sig, err2 := hex.DecodeString(ev.Sig)
+	return err1 == nil && err2 == nil && schnorrVerify(px, id[:], sig)
+}
+
+// npub encodes an x-only public key as NIP-19 bech32.
+func npub(px []byte) string {
+	const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	// regroup 8-bit bytes into 5-bit words
+	var data []byte
+	acc, bits := 0, 0
+	for _, b := range px {
+		acc = (acc<<8 | int(b)) & 0xfff
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			data = append(data, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		data = append(data, byte(acc<<(5-bits)&31))
+	}
+	polymod := func(v []byte) uint32 {
+		gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+		chk := uint32(1)
+		for _, x := range v {
+			top := chk >> 25
+			chk = (chk&0x1ffffff)<<5 ^ uint32(x)
+			for i := range 5 {
+				if top>>i&1 == 1 {
+					chk ^= gen[i]
+				}
+			}
+		}
+		return chk
+	}
+	hrp := "npub"
+	var v []byte
+	for _, c := range hrp {
+		v = append(v, byte(c>>5))
+	}
+	v = append(v, 0)
+	for _, c := range hrp {
+		v = append(v, byte(c&31))
+	}
+	v = append(append(v, data...), 0, 0, 0, 0, 0, 0)
+	mod := polymod(v) ^ 1
+	var out strings.Builder
+	out.WriteString(hrp + "1")
+	for _, d := range data {
+		out.WriteByte(charset[d])
+	}
+	for i := range 6 {
+		out.WriteByte(charset[mod>>(5*(5-i))&31])
+	}
+	return out.String()
+}
+
+// shortNpub is how a Nostr author is named in the room.
+func shortNpub(pubkey string) string {
+	px, err := hex.DecodeString(pubkey)
+	if err != nil || len(px) != 32 {
+		return "nostr"
+	}
+	return npub(px)[:16]
+}
+
+// loadNostrSecret returns the bridge secret in ~/.quichat/nostr.key,
+// creating it on first use.
+func loadNostrSecret() ([]byte, error) {
+	path, err := statePath(nostrKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err == nil {
+		secret, err := hex.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil || len(secret) != 32 {
+			return nil, fmt.Errorf("%s: not a 32-byte hex secret", path)
+		}
+		return secret, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, writeFileAtomic(path, []byte(hex.EncodeToString(secret)+"\n"), 0o600)
+}
+
+// nostrIdentities maps room nicks to their derived keys.
+type nostrIdentities struct {
+	mu     sync.Mutex
+	secret []byte
+	tag    string
+	keys   map[string]*nostrKey // by nick
+	ours   map[string]bool      // pubkeys we sign with
+}
+
+// key derives the nick's key as HMAC(secret, tag, nick); fresh reports
+// that the nick had no key yet this session.
+func (ids *nostrIdentities) key(nick string) (k *nostrKey, fresh bool, err error) {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	if k := ids.keys[nick]; k != nil {
+		return k, false, nil
+	}
+	for i := byte(0); ; i++ {
+		mac := hmac.New(sha256.New, ids.secret)
+		mac.Write([]byte(ids.tag + "\x00" + nick + "\x00"))
+		mac.Write([]byte{i})
+		if k, err = newNostrKey(mac.Sum(nil)); err == nil {
+			break
+		}
+	}
+	ids.keys[nick] = k
+	ids.ours[k.pubkey()] = true
+	return k, true, nil
+}
+
+func (ids *nostrIdentities) isOurs(pubkey string) bool {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	return ids.ours[pubkey]
+}
+
+// RunNostrBridge joins the room as nick and relays between it and a Nostr
+// relay until ctx is cancelled, reconnecting with backoff. Other peers'
+// chat lines are published as kind-1 notes tagged ["t", opts.Tag], each
+// nick with its own derived key whose profile (kind 0) carries the nick;
+// notes with the tag from other authors are posted to the room as
+// "<npub1…> text" once their signature checks out.
+func RunNostrBridge(ctx context.Context, n *Node, nick string, opts NostrBridgeOptions, out io.Writer) error {
+	secret, err := loadNostrSecret()
+	if err != nil {
+		return err
+	}
+	if opts.Tag == "" {
+		opts.Tag = "quichat-" + n.room
+	}
+	ids := &nostrIdentities{secret: secret, tag: opts.Tag, keys: make(map[string]*nostrKey), ours: make(map[string]bool)}
+	ui := &lineUI{w: out}
+	chat := NewChat(n, nick, ui)
+	lines := make(chan arrival, bridgeQueue)
+	self := n.Host.ID()
+	chat.tap = func(a arrival) {
+		if a.src == nil || a.src.GetFrom() == self {
+			return // our own lines came from Nostr in the first place
+		}
+		select {
+		case lines <- a:
+		default:
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error { return chat.Run(ctx) })
+	g.Go(guard("nostr", func() error {
+		since := time.Now().Unix()
+		seen := make(map[string]bool)
+		var order []string
+		backoff := bridgeRetry
+		for {
+			err := chat.bridgeNostr(ctx, opts, ids, lines, &since, func(id string) bool {
+				if seen[id] {
+					return false
+				}
+				seen[id] = true
+				order = append(order, id)
+				if len(order) > nostrSeenMax {
+					delete(seen, order[0])
+					order = order[1:]
+				}
+				return true
+			})
+			if ctx.Err() != nil {
+				return nil
+			}
+			ui.Print(ansiWarn + fmt.Sprintf(T("Nostr: %v; reconnecting in %s"), err, backoff) + ansiReset)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, bridgeMaxBackoff)
+		}
+	}))
+	return g.Wait()
+}
+
+// bridgeNostr runs one relay session. since is advanced as notes arrive so
+// a reconnect asks only for what was missed; first reports whether an
+// event ID is new.
+func (c *Chat) bridgeNostr(ctx context.Context, opts NostrBridgeOptions, ids *nostrIdentities,
+	lines <-chan arrival, since *int64, first func(string) bool) error {
+	dctx, cancel := context.WithTimeout(ctx, nostrDialTimeout)
+	conn, _, err := websocket.DefaultDialer.DialContext(dctx, opts.Relay, nil)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var wmu sync.Mutex
+	send := func(v ...any) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		return conn.WriteJSON(v)
+	}
+	filter := map[string]any{"kinds": []int{nostrKindNote}, "#t": []string{opts.Tag}, "since": *since}
+	if err := send("REQ", "quichat", filter); err != nil {
+		return err
+	}
+	c.ui.Print(fmt.Sprintf(T("Nostr: connected to %s, relaying notes tagged #%s"), brokerHost(opts.Relay), opts.Tag))
+
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case a := <-lines:
+				k, fresh, err := ids.key(a.m.Nick)
+				if err != nil {
+					continue
+				}
+				if fresh {
+					meta, _ := json.Marshal(map[string]string{"name": a.m.Nick, "about": "quichat room " + c.n.room})
+					ev := &nostrEvent{CreatedAt: time.Now().Unix(), Kind: nostrKindMeta, Tags: [][]string{}, Content: string(meta)}
+					if k.signEvent(ev) == nil && send("EVENT", ev) != nil {
+						return
+					}
+					c.ui.Print(fmt.Sprintf(T("Nostr: %s posts as %s"), a.m.Nick, npub(k.px[:])))
+				}
+				ev := &nostrEvent{CreatedAt: a.m.Ts.Unix(), Kind: nostrKindNote,
+					Tags: [][]string{{"t", opts.Tag}}, Content: a.m.Text}
+				if err := k.signEvent(ev); err != nil {
+					continue
+				}
+				if send("EVENT", ev) != nil {
+					return // the read loop notices the broken connection
+				}
+			}
+		}
+	}()
+
+	for {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		var msg []json.RawMessage
+		if json.Unmarshal(b, &msg) != nil || len(msg) < 2 {
+			continue
+		}
+		var typ string
+		json.Unmarshal(msg[0], &typ)
+		switch typ {
+		case "EVENT":
+			var ev nostrEvent
+			if len(msg) < 3 || json.Unmarshal(msg[2], &ev) != nil {
+				continue
+			}
+			c.nostrNote(&ev, opts.Tag, ids, since, first)
+		case "OK":
+			var ok bool
+			var reason string
+			if len(msg) >= 4 && json.Unmarshal(msg[2], &ok) == nil && !ok {
+				json.Unmarshal(msg[3], &reason)
+				c.ui.Print(ansiWarn + fmt.Sprintf(T("Nostr: relay refused a note: %s"), reason) + ansiReset)
+			}
+		case "NOTICE":
+			var notice string
+			json.Unmarshal(msg[1], &notice)
+			c.ui.Print(fmt.Sprintf(T("Nostr: relay says: %s"), notice))
+		case "CLOSED":
+			var reason string
+			if len(msg) >= 3 {
+				json.Unmarshal(msg[2], &reason)
+			}
+			return errors.New("relay closed the subscription: " + reason)
+		}
+	}
+}
+
+// nostrNote posts a relayed note to the room if it is genuine, tagged for
+// the room, new, and not one the bridge signed itself.
+func (c *Chat) nostrNote(ev *nostrEvent, tag string, ids *nostrIdentities, since *int64, first func(string) bool) {
+	if ev.Kind != nostrKindNote || ids.isOurs(ev.PubKey) || !ev.verify() {
+		return
+	}
+	tagged := false
+	for _, t := range ev.Tags {
+		if len(t) >= 2 && t[0] == "t" && t[1] == tag {
+			tagged = true
+		}
+	}
+	if !tagged || !first(ev.ID) {
+		return
+	}
+	*since = max(*since, ev.CreatedAt)
+	text := strings.TrimSpace(ev.Content)
+	if len(text) > bridgeMaxText {
+		text = text[:bridgeMaxText]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+		text += "…"
+	}
+	if text == "" {
+		return
+	}
+	if _, err := c.post(fmt.Sprintf("<%s> %s", shortNpub(ev.PubKey), text)); err != nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Nostr: note %s not posted: %v"), shortEventID(ev.ID), err) + ansiReset)
+	}
+}
+
+// shortEventID abbreviates a hex event ID for notices.
+func shortEventID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
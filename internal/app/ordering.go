@@ -0,0 +1,202 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	reorderWindow = 500 * time.Millisecond // how long a gap may hold back later messages
+	maxSkew       = 2 * time.Minute        // sender clocks further off than this are flagged
+	maxClock      = 1 << 53                // highest Lamport time a message may carry
+	maxClockJump  = 1 << 20                // most one received message moves our Lamport clock
+	maxHeld       = 64                     // messages held back per sender before its gap is given up on
+	senderIdle    = 10 * time.Minute       // senders quiet this long are forgotten
+)
+
+// arrival is a chat message together with when it reached us.
+type arrival struct {
+	m   Message
+	at  time.Time
+	src *pubsub.Message // as received, for --transcript
+}
+
+type senderQueue struct {
+	next  uint64 // next expected sequence number; 0 until the first message
+	held  map[uint64]arrival
+	timer *time.Timer
+	last  time.Time // when its latest message arrived
+}
+
+// reorderer releases each sender's chat messages in sequence order. When one
+// is missing, later ones are held back for up to reorderWindow, or until
+// maxHeld are waiting; after that the gap is given up on. Messages without
+// a sequence number (older clients) pass straight through. Senders that go
+// quiet for senderIdle are forgotten, so a long session with churning peers
+// doesn't keep a queue for everyone who ever spoke.
+type reorderer struct {
+	mu      sync.Mutex
+	senders map[peer.ID]*senderQueue
+	swept   time.Time // when idle senders were last dropped
+}
+
+// push hands a from-sender message to the reorderer. release is called, in
+// order and with the reorderer locked, for every message that is ready.
+func (r *reorderer) push(from peer.ID, a arrival, release func(arrival)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a.m.Seq == 0 {
+		release(a)
+		return
+	}
+	if r.senders == nil {
+		r.senders = make(map[peer.ID]*senderQueue)
+	}
+	now := time.Now()
+	r.sweep(now)
+	q := r.senders[from]
+	if q == nil {
+		q = &senderQueue{held: make(map[uint64]arrival)}
+		r.senders[from] = q
+	}
+	q.last = now
+
+	switch {
+	case q.next == 0:
+		q.next = a.m.Seq // we joined mid-conversation
+	case a.m.Seq == 1 && q.next > 1:
+		r.flush(q, release) // the sender restarted
+		q.next = 1
+	}
+
+	switch {
+	case a.m.Seq < q.next:
+		release(a) // too late to reorder; show it anyway
+		return
+	case a.m.Seq > q.next:
+		q.held[a.m.Seq] = a
+		if len(q.held) > maxHeld {
+			r.flush(q, release) // the gap is too wide to wait out
+		}
+	default:
+		release(a)
+		q.next++
+		for {
+			h, ok := q.held[q.next]
+			if !ok {
+				break
+			}
+			delete(q.held, q.next)
+			release(h)
+			q.next++
+		}
+	}
+
+	switch {
+	case len(q.held) == 0 && q.timer != nil:
+		q.timer.Stop()
+		q.timer = nil
+	case len(q.held) > 0 && q.timer == nil:
+		q.timer = time.AfterFunc(reorderWindow, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.flush(q, release)
+		})
+	}
+}
+
+// flush gives up on missing messages and releases everything held.
+func (r *reorderer) flush(q *senderQueue, release func(arrival)) {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	seqs := make([]uint64, 0, len(q.held))
+	for s := range q.held {
+		seqs = append(seqs, s)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for _, s := range seqs {
+		release(q.held[s])
+		delete(q.held, s)
+		q.next = s + 1
+	}
+}
+
+// sweep forgets senders that have been quiet for senderIdle and hold
+// nothing back. It walks the map at most once per senderIdle.
+func (r *reorderer) sweep(now time.Time) {
+	if now.Sub(r.swept) < senderIdle {
+		return
+	}
+	r.swept = now
+	for id, q := range r.senders {
+		if len(q.held) == 0 && now.Sub(q.last) >= senderIdle {
+			delete(r.senders, id)
+		}
+	}
+}
+
+// skewNote flags a message whose sender clock is far from ours, or "".
+func skewNote(sent, received time.Time) string {
+	if sent.IsZero() {
+		return ""
+	}
+	skew := sent.Sub(received)
+	switch {
+	case skew > maxSkew:
+		return fmt.Sprintf(T("sender clock %s ahead"), skew.Round(time.Second))
+	case skew < -maxSkew:
+		return fmt.Sprintf(T("sender clock %s behind"), (-skew).Round(time.Second))
+	}
+	return ""
+}
+
+// lamport is a Lamport clock. Every message carries the sender's tick, so
+// copies merged from different peers sort the same way everywhere and a
+// reply always sorts after what it answers, whatever the wall clocks say.
+type lamport struct {
+	mu sync.Mutex
+	t  uint64
+}
+
+// tick advances the clock for a message we are about to send.
+func (l *lamport) tick() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.t++
+	return l.t
+}
+
+// witness moves the clock past a tick seen on a received message. It moves
+// at most maxClockJump at a time, so a peer announcing a huge tick can't
+// drag everyone's clock toward overflow; a genuinely busy room still
+// catches up within a few messages. checkMessage already refused ticks
+// beyond maxClock.
+func (l *lamport) witness(t uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.t = max(l.t, min(t, l.t+maxClockJump)) + 1
+}
+
+// causalLess orders messages by Lamport time, breaking ties by timestamp
+// and then ID so every replica agrees. Messages from clients without a
+// clock sort after all those with one, by timestamp; comparing clocks only
+// where both have one wouldn't be a consistent order.
+func causalLess(a, b Message) bool {
+	if (a.Clock == 0) != (b.Clock == 0) {
+		return a.Clock != 0
+	}
+	if a.Clock != b.Clock {
+		return a.Clock < b.Clock
+	}
+	if !a.Ts.Equal(b.Ts) {
+		return a.Ts.Before(b.Ts)
+	}
+	return a.ID < b.ID
+}
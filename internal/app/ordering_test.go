@@ -0,0 +1,89 @@
+package app
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestLamportWitness(t *testing.T) {
+	var l lamport
+	l.witness(41)
+	if got := l.tick(); got != 43 {
+		t.Fatalf("after witnessing 41, tick() = %d, want 43", got)
+	}
+	l.witness(math.MaxUint64)
+	if l.t > 43+maxClockJump+1 {
+		t.Fatalf("one message moved the clock from 43 to %d", l.t)
+	}
+	for range 3 {
+		l.witness(3 * maxClockJump)
+	}
+	if l.t <= 3*maxClockJump {
+		t.Fatalf("clock %d didn't catch up with a busy room at %d", l.t, 3*maxClockJump)
+	}
+	if err := checkMessage(&Message{Clock: maxClock + 1}); err == nil {
+		t.Fatal("checkMessage accepted a clock beyond maxClock")
+	}
+}
+
+func TestCausalLessIsTotal(t *testing.T) {
+	at := func(s int) time.Time { return time.Date(2026, 10, 16, 9, 30, s, 0, time.UTC) }
+	msgs := []Message{
+		{ID: "a", Clock: 1, Ts: at(3)},
+		{ID: "b", Ts: at(2)},
+		{ID: "c", Clock: 2, Ts: at(1)},
+		{ID: "d", Clock: 2, Ts: at(1)},
+		{ID: "e", Ts: at(2)},
+		{ID: "f", Clock: 2, Ts: at(0)},
+	}
+	for _, a := range msgs {
+		if causalLess(a, a) {
+			t.Errorf("%s < itself", a.ID)
+		}
+		for _, b := range msgs {
+			if a.ID != b.ID && causalLess(a, b) == causalLess(b, a) {
+				t.Errorf("%s and %s aren't ordered", a.ID, b.ID)
+			}
+			for _, c := range msgs {
+				if causalLess(a, b) && causalLess(b, c) && !causalLess(a, c) {
+					t.Errorf("%s < %s < %s but not %s < %s", a.ID, b.ID, c.ID, a.ID, c.ID)
+				}
+			}
+		}
+	}
+}
+
+func TestReordererBounds(t *testing.T) {
+	var r reorderer
+	var got []uint64
+	release := func(a arrival) { got = append(got, a.m.Seq) }
+	push := func(from peer.ID, seq uint64) {
+		r.push(from, arrival{m: Message{Seq: seq}}, release)
+	}
+
+	// seq 2 never arrives; the gap is given up on once maxHeld are waiting
+	push("a", 1)
+	for s := uint64(3); s <= maxHeld+3; s++ {
+		push("a", s)
+	}
+	if len(got) != maxHeld+2 || got[len(got)-1] != maxHeld+3 {
+		t.Fatalf("released %d messages ending at %d, want %d ending at %d", len(got), got[len(got)-1], maxHeld+2, maxHeld+3)
+	}
+	if q := r.senders["a"]; len(q.held) != 0 || q.timer != nil {
+		t.Fatalf("still holding %d messages after the flush", len(q.held))
+	}
+
+	// a sender quiet for senderIdle is forgotten on the next sweep
+	r.senders["a"].last = time.Now().Add(-senderIdle)
+	r.swept = time.Time{}
+	push("b", 1)
+	if _, ok := r.senders["a"]; ok {
+		t.Fatal("idle sender a was kept")
+	}
+	if _, ok := r.senders["b"]; !ok {
+		t.Fatal("active sender b was dropped")
+	}
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	outboxSize     = 64              // chat lines waiting to be published
+	publishTries   = 3               // attempts before a line is reported unsent
+	publishBackoff = 1 * time.Second // doubled after each failed attempt
+	maxUnsent      = 20              // failed lines kept for /retry
+)
+
+var (
+	errOutboxFull = errors.New("too many messages waiting to be sent")
+	errInLobby    = errors.New("in the lobby until the room operator admits us")
+)
+
+// queue hands a typed chat line to the outbox, which publishes it in the
+// background so a slow or failing publish never blocks the prompt.
+func (c *Chat) queue(m Message) {
+	if len(m.Text) > maxTextLen {
+		c.reportTooLong(m)
+		return
+	}
+	select {
+	case c.outbox <- m:
+	default:
+		c.unsent.add(m)
+		c.reportUnsent(m, errOutboxFull)
+	}
+}
+
+// post sends text from an integration (--api, bridges) as if it had been
+// typed. Unlike queue it never holds a line back as unsent: the caller
+// hears about failures and can retry.
+func (c *Chat) post(text string) (Message, error) {
+	if !c.lobby.allows(c.n.Host.ID()) {
+		return Message{}, errInLobby
+	}
+	if len(text) > maxTextLen {
+		return Message{}, errTooLong
+	}
+	m := Message{ID: makeID(), Nick: c.myNick(), Text: text, Ts: time.Now().UTC(), Seq: c.seq.Add(1)}
+	select {
+	case c.outbox <- m:
+		return m, nil
+	default:
+		return Message{}, errOutboxFull
+	}
+}
+
+// runOutbox publishes queued chat lines in order until ctx is done. While
+// nobody is subscribed to the room a line would reach no one, so lines are
+// held back and flushed once the first peer joins the topic.
+func (c *Chat) runOutbox(ctx context.Context) error {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	var waiting []Message
+	for {
+		select {
+		case <-ctx.Done():
+			c.flushOutbox(ctx, waiting)
+			return nil
+		case m := <-c.outbox:
+			if len(waiting) > 0 || len(c.n.Topic.ListPeers()) == 0 {
+				waiting = append(waiting, m)
+				c.held.Store(int64(len(waiting)))
+				c.ui.Print(ansiDim + fmt.Sprintf(T("Waiting for peers… (%d queued)"), len(waiting)) + ansiReset)
+				continue
+			}
+			c.publishWithRetry(ctx, m)
+		case <-tick.C:
+			if len(waiting) == 0 || len(c.n.Topic.ListPeers()) == 0 {
+				continue
+			}
+			c.ui.Print(fmt.Sprintf(T("Peers found, sending %d queued message(s)"), len(waiting)))
+			for _, m := range waiting {
+				c.publishWithRetry(ctx, m)
+			}
+			waiting = nil
+			c.held.Store(0)
+		}
+	}
+}
+
+// flushOutbox makes one last attempt, within shutdownTimeout, at the lines
+// still queued when the chat ends, so a line typed just before /quit isn't
+// lost. ctx is the chat's, already done. Lines that can't go out now are
+// reported; they don't outlive the session.
+func (c *Chat) flushOutbox(ctx context.Context, waiting []Message) {
+	for len(c.outbox) > 0 {
+		waiting = append(waiting, <-c.outbox) // we are the only reader
+	}
+	if len(waiting) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	lost := 0
+	for _, m := range waiting {
+		if len(c.n.Topic.ListPeers()) == 0 || c.send(ctx, m) != nil {
+			lost++
+		}
+	}
+	if lost > 0 {
+		c.ui.Print("\033[31m" + fmt.Sprintf(T("✗ %d message(s) not sent before leaving"), lost) + ansiReset)
+	}
+}
+
+func (c *Chat) publishWithRetry(ctx context.Context, m Message) {
+	backoff := publishBackoff
+	for try := 1; ; try++ {
+		err := c.send(ctx, m)
+		switch {
+		case err == nil:
+			return
+		case ctx.Err() != nil:
+			return
+		case try == publishTries:
+			c.unsent.add(m)
+			c.reportUnsent(m, err)
+			return
+		case try == 1:
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("Sending #%s failed (%v), retrying…"), shortMsgID(m.ID), err) + ansiReset)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// reportTooLong explains why a line was refused; it isn't kept for /retry,
+// which would only refuse it again.
+func (c *Chat) reportTooLong(m Message) {
+	c.ui.Print("\033[31m" + fmt.Sprintf(T("✗ Not sent: the message is %d KiB, the limit is %d KiB"), len(m.Text)>>10, maxTextLen>>10) + ansiReset)
+}
+
+func (c *Chat) reportUnsent(m Message, err error) {
+	c.ui.Print("\033[31m" + fmt.Sprintf(T("✗ #%s not sent: %v (/retry to try again)"), shortMsgID(m.ID), T(err.Error())) +
+		ansiReset + "\n» " + m.Text)
+}
+
+// runRetry implements /retry: every unsent line goes back into the outbox.
+func (c *Chat) runRetry() {
+	msgs := c.unsent.take()
+	if len(msgs) == 0 {
+		c.ui.Print(T("Nothing to retry"))
+		return
+	}
+	c.ui.Print(fmt.Sprintf(T("Retrying %d message(s)"), len(msgs)))
+	for _, m := range msgs {
+		c.queue(m)
+	}
+}
+
+// unsentList holds chat lines that could not be published, oldest first.
+type unsentList struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+func (u *unsentList) add(m Message) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.msgs = append(u.msgs, m)
+	if len(u.msgs) > maxUnsent {
+		u.msgs = u.msgs[len(u.msgs)-maxUnsent:]
+	}
+}
+
+func (u *unsentList) len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.msgs)
+}
+
+func (u *unsentList) take() []Message {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	msgs := u.msgs
+	u.msgs = nil
+	return msgs
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"io"
+	"strings"
+)
+
+// Terminals wrap pasted text in these markers once bracketed paste mode is on.
+const (
+	pasteOn    = "\x1b[?2004h"
+	pasteOff   = "\x1b[?2004l"
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+
+	// pastedNewline stands in for line breaks inside a paste so readline
+	// keeps the whole paste on one input line instead of submitting each.
+	pastedNewline = "␤"
+)
+
+// pasteFilter sits between stdin and readline, stripping bracketed paste
+// markers and replacing newlines inside a paste with pastedNewline.
+type pasteFilter struct {
+	r       io.ReadCloser
+	in      bool   // inside a paste
+	esc     []byte // bytes that may be the start of a marker
+	lastCR  bool
+	pending []byte // filtered bytes not yet handed to readline
+	raw     []byte
+}
+
+func newPasteFilter(r io.ReadCloser) *pasteFilter {
+	return &pasteFilter{r: r, raw: make([]byte, 256)}
+}
+
+func (f *pasteFilter) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 {
+		n, err := f.r.Read(f.raw)
+		for _, b := range f.raw[:n] {
+			f.feed(b)
+		}
+		if err != nil {
+			f.pending = append(f.pending, f.esc...)
+			f.esc = nil
+			if len(f.pending) == 0 {
+				return 0, err
+			}
+		}
+	}
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+func (f *pasteFilter) Close() error { return f.r.Close() }
+
+func (f *pasteFilter) feed(b byte) {
+	if len(f.esc) > 0 || b == 0x1b {
+		f.esc = append(f.esc, b)
+		switch s := string(f.esc); {
+		case s == pasteStart:
+			f.in, f.esc = true, nil
+		case s == pasteEnd:
+			f.in, f.esc = false, nil
+		case strings.HasPrefix(pasteStart, s) || strings.HasPrefix(pasteEnd, s):
+			// keep collecting
+		default:
+			// not a marker: pass through, restarting if b opens a new escape
+			f.esc = f.esc[:len(f.esc)-1]
+			f.pending = append(f.pending, f.esc...)
+			f.esc = nil
+			if b == 0x1b {
+				f.esc = []byte{b}
+				return
+			}
+			f.emit(b)
+		}
+		return
+	}
+	f.emit(b)
+}
+
+func (f *pasteFilter) emit(b byte) {
+	cr := b == '\r'
+	defer func() { f.lastCR = cr }()
+	if !f.in || (b != '\r' && b != '\n') {
+		f.pending = append(f.pending, b)
+		return
+	}
+	if b == '\n' && f.lastCR {
+		return // second half of \r\n
+	}
+	f.pending = append(f.pending, pastedNewline...)
+}
+
+// pastedLines splits a line that holds a multi-line paste; ok is false for
+// ordinary typed input.
+func pastedLines(line string) (text string, lines int, ok bool) {
+	if !strings.Contains(line, pastedNewline) {
+		return line, 1, false
+	}
+	text = strings.TrimRight(strings.ReplaceAll(line, pastedNewline, "\n"), "\n")
+	return text, strings.Count(text, "\n") + 1, true
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// pingTTL is how long a /ping waits for pongs before it reports.
+const pingTTL = 10 * time.Second
+
+type pingSession struct {
+	sent     time.Time
+	expected int // topic peers when the ping went out
+	rtts     map[peer.ID]time.Duration
+}
+
+// pingTracker holds the /ping sessions still waiting for pongs. Several can
+// be in flight at once; each is dropped when it reports.
+type pingTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*pingSession
+}
+
+func (pt *pingTracker) start(id string, expected int, at time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if pt.sessions == nil {
+		pt.sessions = make(map[string]*pingSession)
+	}
+	pt.sessions[id] = &pingSession{sent: at, expected: expected, rtts: make(map[peer.ID]time.Duration)}
+}
+
+// reply records a pong from p. ok is false for unknown or expired sessions
+// and repeated pongs; done is true once every expected peer has answered.
+func (pt *pingTracker) reply(id string, p peer.ID, at time.Time) (rtt time.Duration, ok, done bool) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	s := pt.sessions[id]
+	if s == nil {
+		return 0, false, false
+	}
+	if _, dup := s.rtts[p]; dup {
+		return 0, false, false
+	}
+	rtt = at.Sub(s.sent)
+	s.rtts[p] = rtt
+	return rtt, true, len(s.rtts) >= s.expected
+}
+
+// finish removes a session and returns its summary, or "" if it has
+// already reported.
+func (pt *pingTracker) finish(id string) string {
+	pt.mu.Lock()
+	s := pt.sessions[id]
+	delete(pt.sessions, id)
+	pt.mu.Unlock()
+	if s == nil {
+		return ""
+	}
+
+	summary := fmt.Sprintf(T("Ping #%s: %d/%d peers responded"), shortMsgID(id), len(s.rtts), s.expected)
+	if len(s.rtts) == 0 {
+		return summary
+	}
+	var lo, hi, sum time.Duration
+	for _, d := range s.rtts {
+		if lo == 0 || d < lo {
+			lo = d
+		}
+		hi = max(hi, d)
+		sum += d
+	}
+	avg := sum / time.Duration(len(s.rtts))
+	return summary + fmt.Sprintf(T(", min/avg/max %d/%d/%d ms"), lo.Milliseconds(), avg.Milliseconds(), hi.Milliseconds())
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	pinPrefix    = "__PIN__"
+	maxRecent    = 500 // messages remembered so /pin can find them
+	shownIDChars = 6
+)
+
+// pinBoard holds the room's pinned messages and the recent messages /pin can
+// refer to. Pins form a grow-only set keyed by message ID, so replicas merge
+// by union no matter the order announcements arrive in.
+type pinBoard struct {
+	mu     sync.Mutex
+	pinned map[string]Message
+	recent []Message
+}
+
+// remember records a delivered message for later lookup by ID.
+func (pb *pinBoard) remember(m Message) {
+	if m.ID == "" {
+		return
+	}
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.recent = append(pb.recent, m)
+	if len(pb.recent) > maxRecent {
+		pb.recent = pb.recent[len(pb.recent)-maxRecent:]
+	}
+}
+
+// lookup finds a recent message by ID or by the prefix shown on screen.
+func (pb *pinBoard) lookup(id string) (Message, error) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	var found []Message
+	for _, m := range pb.recent {
+		if strings.HasPrefix(m.ID, id) {
+			found = append(found, m)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Message{}, fmt.Errorf("no recent message with ID %s", id)
+	case 1:
+		return found[0], nil
+	}
+	return Message{}, fmt.Errorf("ID %s is ambiguous, type more of it", id)
+}
+
+// merge adds pins and reports how many were new.
+func (pb *pinBoard) merge(pins []Message) int {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if pb.pinned == nil {
+		pb.pinned = make(map[string]Message)
+	}
+	added := 0
+	for _, m := range pins {
+		if _, ok := pb.pinned[m.ID]; m.ID != "" && !ok {
+			pb.pinned[m.ID] = m
+			added++
+		}
+	}
+	return added
+}
+
+// all returns the pinned messages in causal order.
+func (pb *pinBoard) all() []Message {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	out := make([]Message, 0, len(pb.pinned))
+	for _, m := range pb.pinned {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return causalLess(out[i], out[j]) })
+	return out
+}
+
+// report renders the board for /pins.
+func (pb *pinBoard) report() string {
+	pins := pb.all()
+	if len(pins) == 0 {
+		return T("No pinned messages")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Pinned (%d):"), len(pins))
+	for _, m := range pins {
+		fmt.Fprintf(&b, "\n  #%s [%s] <%s> %s",
+			shortMsgID(m.ID), m.Ts.Local().Format("2006-01-02 15:04:05"), m.Nick, m.Text)
+	}
+	return b.String()
+}
+
+// encodePins builds the control message text announcing pins.
+func encodePins(pins []Message) string {
+	b, _ := json.Marshal(pins)
+	return pinPrefix + string(b)
+}
+
+// decodePins reads a pin announcement, making the pinned lines safe to print.
+func decodePins(text string) ([]Message, error) {
+	var pins []Message
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(text, pinPrefix)), &pins); err != nil {
+		return nil, err
+	}
+	for i := range pins {
+		pins[i].ID = sanitize(pins[i].ID, false)
+		pins[i].Nick = sanitize(pins[i].Nick, false)
+		pins[i].Text = sanitize(pins[i].Text, true)
+		if pins[i].Clock > maxClock {
+			pins[i].Clock = 0 // sorts by timestamp instead
+		}
+	}
+	return pins, nil
+}
+
+// shortMsgID shortens a message ID for display to shownIDChars characters,
+// never cutting one in half.
+func shortMsgID(id string) string {
+	n := 0
+	for i := range id {
+		if n == shownIDChars {
+			return id[:i]
+		}
+		n++
+	}
+	return id
+}
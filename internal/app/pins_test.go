@@ -0,0 +1,53 @@
+package app
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzDecodePins(f *testing.F) {
+	f.Add(encodePins([]Message{sampleMessage()}))
+	f.Add(pinPrefix + `[{"id":"a1","nick":"\u001b[31mbob","text":"line\r\nline‮"}]`)
+	f.Add(pinPrefix + `[]`)
+	f.Add(pinPrefix + `null`)
+	f.Add(pinPrefix + `{"id":"a1"}`)
+
+	f.Fuzz(func(t *testing.T, text string) {
+		pins, err := decodePins(text)
+		if err != nil {
+			return
+		}
+		for _, m := range pins {
+			if sanitize(m.ID, false) != m.ID || sanitize(m.Nick, false) != m.Nick || sanitize(m.Text, true) != m.Text {
+				t.Fatalf("unsafe pin %+v", m)
+			}
+		}
+		back, err := decodePins(encodePins(pins))
+		if err != nil {
+			t.Fatalf("decode after encode: %v", err)
+		}
+		if len(back) != len(pins) {
+			t.Fatalf("round trip turned %d pins into %d", len(pins), len(back))
+		}
+		for i := range pins {
+			if !sameMessage(pins[i], back[i]) {
+				t.Fatalf("round trip changed %+v into %+v", pins[i], back[i])
+			}
+		}
+	})
+}
+
+func TestShortMsgID(t *testing.T) {
+	for _, tt := range []struct{ id, want string }{
+		{"3f9a0c1d2e4b5a69", "3f9a0c"},
+		{"3f9a", "3f9a"},
+		{"", ""},
+		{"ñandúes", "ñandúe"},
+		{"日本語のメッセージ", "日本語のメッ"},
+	} {
+		got := shortMsgID(tt.id)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("shortMsgID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	joinText         = "__JOIN__"
+	leaveText        = "__LEAVE__"          // sent once on the way out
+	presenceInterval = 30 * time.Second     // heartbeat period
+	presenceJitter   = 10 * time.Second     // ± spread so peers don't announce in lockstep
+	presenceMinGap   = 5 * time.Second      // never announce more often than this
+	presenceTTL      = 3 * presenceInterval // unheard for this long counts as gone
+)
+
+// presence remembers when each peer last announced itself, so repeated JOIN
+// heartbeats are shown only the first time (or after the peer went quiet),
+// and what it announced: nick, wire format, capabilities and version.
+type presence struct {
+	mu   sync.Mutex
+	seen map[peer.ID]presenceEntry
+}
+
+type presenceEntry struct {
+	at      time.Time
+	nick    string
+	wire    string
+	caps    uint64
+	version string
+	former  []string // nicks it went by before, most recent last
+}
+
+// arrived records JOIN m from p and reports whether p is new to us.
+func (pr *presence) arrived(p peer.ID, m Message, at time.Time) bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if pr.seen == nil {
+		pr.seen = make(map[peer.ID]presenceEntry)
+	}
+	last, ok := pr.seen[p]
+	pr.seen[p] = presenceEntry{at: at, nick: m.Nick, wire: m.Wire, caps: m.Caps, version: m.Version, former: last.former}
+	for id, e := range pr.seen {
+		if at.Sub(e.at) > presenceTTL {
+			delete(pr.seen, id)
+		}
+	}
+	return !ok || at.Sub(last.at) > presenceTTL
+}
+
+// left forgets p after its LEAVE and returns the label it was shown under,
+// or false if we hadn't heard from it.
+func (pr *presence) left(p peer.ID) (string, bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	e, ok := pr.seen[p]
+	if !ok {
+		return "", false
+	}
+	label := pr.labelLocked(p, e.nick, "")
+	delete(pr.seen, p)
+	return label, true
+}
+
+// nicks lists the peers heard from within presenceTTL by label, sorted.
+func (pr *presence) nicks() []string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	var out []string
+	now := time.Now()
+	for p, e := range pr.seen {
+		if now.Sub(e.at) <= presenceTTL {
+			out = append(out, pr.labelLocked(p, e.nick, ""))
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// members maps the peers heard from within presenceTTL to their labels.
+func (pr *presence) members() map[peer.ID]string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	out := make(map[peer.ID]string)
+	now := time.Now()
+	for p, e := range pr.seen {
+		if now.Sub(e.at) <= presenceTTL {
+			out[p] = pr.labelLocked(p, e.nick, "")
+		}
+	}
+	return out
+}
+
+// nickTag is the end of p's ID, which tells apart peers sharing a nick.
+func nickTag(p peer.ID) string {
+	s := p.String()
+	return s[max(0, len(s)-4):]
+}
+
+// label is how p, going by nick, is shown: the nick alone, or nick~tag
+// (alice~3f9a) when another peer heard within presenceTTL, or we ourselves
+// as ours, goes by the same nick.
+func (pr *presence) label(p peer.ID, nick, ours string) string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return pr.labelLocked(p, nick, ours)
+}
+
+func (pr *presence) labelLocked(p peer.ID, nick, ours string) string {
+	shared := ours != "" && strings.EqualFold(nick, ours)
+	now := time.Now()
+	for id, e := range pr.seen {
+		if shared {
+			break
+		}
+		shared = id != p && now.Sub(e.at) <= presenceTTL && strings.EqualFold(e.nick, nick)
+	}
+	if !shared {
+		return nick
+	}
+	return nick + "~" + nickTag(p)
+}
+
+// named reports whether name picks out p, going by nick: its nick, its
+// nick~tag label or the start of its peer ID.
+func named(name string, p peer.ID, nick string) bool {
+	if n, tag, ok := strings.Cut(name, "~"); ok {
+		return n == nick && strings.HasSuffix(p.String(), tag)
+	}
+	return nick == name || strings.HasPrefix(p.String(), name)
+}
+
+// ambiguous is the error for a name that picks out several peers.
+func ambiguous(name string, labels []string) error {
+	slices.Sort(labels)
+	return fmt.Errorf(T("%s matches several peers (%s); add the ~tag shown after the nick"), name, strings.Join(labels, ", "))
+}
+
+// allSpeak reports whether everyone we have heard from, including each of
+// neighbours, announced the given wire format. A single unknown or older
+// peer keeps the room on JSON.
+func (pr *presence) allSpeak(neighbours []peer.ID, wire string) bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	for _, p := range neighbours {
+		if _, ok := pr.seen[p]; !ok {
+			return false
+		}
+	}
+	now := time.Now()
+	for _, e := range pr.seen {
+		if now.Sub(e.at) <= presenceTTL && e.wire != wire {
+			return false
+		}
+	}
+	return len(pr.seen) > 0
+}
+
+// announcePresence publishes a JOIN as soon as someone can hear it, again
+// whenever a new peer shows up in the topic, and otherwise every
+// presenceInterval ± presenceJitter as a heartbeat, until ctx is done.
+func (c *Chat) announcePresence(ctx context.Context) error {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	var (
+		last, next time.Time // zero next: due immediately
+		pending    bool      // a newcomer is waiting for an announcement
+		known      = make(map[peer.ID]bool)
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+
+		peers := c.n.Topic.ListPeers()
+		current := make(map[peer.ID]bool, len(peers))
+		for _, p := range peers {
+			current[p] = true
+			if !known[p] {
+				pending = true
+			}
+		}
+		known = current
+		if len(peers) == 0 {
+			continue
+		}
+
+		now := time.Now()
+		if (pending || !now.Before(next)) && now.Sub(last) >= presenceMinGap {
+			// a failed heartbeat is simply retried by the next one
+			_ = c.sendControl(ctx, Message{Nick: c.myNick(), Text: joinText, Ts: time.Now().UTC(), Wire: wireProto, Caps: c.n.caps()})
+			last, pending = now, false
+			next = now.Add(presenceInterval + rand.N(2*presenceJitter) - presenceJitter)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	spinnerFrames = "⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏"
+	spinnerTick   = 100 * time.Millisecond
+	stageSlow     = 5 * time.Second // show the stage's hint after this long
+)
+
+// stage prints "label… " and runs fn, with a spinner while it works when
+// stdout is a terminal. If fn takes longer than stageSlow the hint is shown
+// next to the spinner. The line is finished with fn's result, "ok" when
+// the result is empty, or "failed".
+func stage(label, hint string, fn func() (string, error)) error {
+	label = T(label) + "… "
+	fmt.Fprint(termOut, label)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if tty.redraw {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			spin(label, T(hint), done)
+		}()
+	}
+	res, err := fn()
+	close(done)
+	wg.Wait()
+
+	switch {
+	case err != nil:
+		res = "\033[31m" + T("failed") + ansiReset
+	case res == "":
+		res = "\033[32m" + T("ok") + ansiReset
+	}
+	fmt.Fprintln(termOut, termText(res))
+	return err
+}
+
+// spin redraws label with the next spinner frame until done is closed, then
+// leaves the cursor right after label for the result.
+func spin(label, hint string, done <-chan struct{}) {
+	tick := time.NewTicker(spinnerTick)
+	defer tick.Stop()
+	start := time.Now()
+	frames := []rune(spinnerFrames)
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+			fmt.Fprint(termOut, "\x1b[2K\r"+label)
+			return
+		case <-tick.C:
+		}
+		line := "\x1b[2K\r" + label + string(frames[i%len(frames)])
+		if hint != "" && time.Since(start) > stageSlow {
+			line += " " + termText(ansiDim+hint+ansiReset)
+		}
+		fmt.Fprint(termOut, line)
+	}
+}
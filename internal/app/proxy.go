@@ -0,0 +1,51 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	ma "github.com/multiformats/go-multiaddr"
+	"golang.org/x/net/proxy"
+)
+
+// proxyOptions routes every outbound connection through a SOCKS5 proxy
+// (e.g. Tor on socks5://127.0.0.1:9050) so peers never see our IP address.
+// QUIC cannot be proxied over SOCKS, so only the TCP transport is enabled,
+// and we advertise nothing but relay addresses.
+func (n *Node) proxyOptions() ([]libp2p.Option, error) {
+	u, err := url.Parse(n.proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", n.proxyURL, err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q (want socks5)", u.Scheme)
+	}
+	d, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %q: %w", n.proxyURL, err)
+	}
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("proxy %q does not support dial contexts", n.proxyURL)
+	}
+
+	return []libp2p.Option{
+		libp2p.Transport(n.throttle.tcpTransport, tcp.WithDialerForAddr(
+			func(ma.Multiaddr) (tcp.ContextDialer, error) { return cd, nil },
+		)),
+		libp2p.AddrsFactory(relayAddrsOnly),
+	}, nil
+}
+
+// relayAddrsOnly hides our direct addresses, keeping only circuit ones.
+func relayAddrsOnly(addrs []ma.Multiaddr) []ma.Multiaddr {
+	var out []ma.Multiaddr
+	for _, a := range addrs {
+		if isRelayed(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PurgeOptions selects what Purge removes. Exactly one of Peer, Room and
+// All is expected to be set.
+type PurgeOptions struct {
+	Peer   peer.ID
+	Room   string
+	All    bool
+	DryRun bool // only list what would be removed
+
+	// Files written with --transcript and --record, which can be anywhere,
+	// so Purge only looks at the ones named here.
+	Transcripts []string
+	Recordings  []string
+}
+
+// Purge deletes locally stored data selected by opts and returns one line
+// per item removed, or with DryRun, per item that would be.
+//
+// In the state directory that is the address book, our opinion of peers,
+// crash reports (which may quote message text), messages waiting in
+// /schedule, which feed items were already announced and the Nostr
+// bridge's secret; config.json holds settings and is kept. Messages also
+// reach the disk in transcripts and session recordings, which are purged
+// when opts names them.
+func Purge(opts PurgeOptions) ([]string, error) {
+	var removed []string
+
+	path, err := AddrBookPath()
+	if err != nil {
+		return nil, err
+	}
+	book, err := LoadAddrBook(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case opts.All && len(book) > 0:
+		removed = append(removed, fmt.Sprintf("address book (%d peers): %s", len(book), path))
+		if !opts.DryRun {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return removed, err
+			}
+		}
+	case opts.Peer != "":
+		e, ok := book[opts.Peer]
+		if !ok {
+			break
+		}
+		removed = append(removed, fmt.Sprintf("address book entry for %s (%d addresses, last seen %s)",
+			opts.Peer, len(e.Addrs), e.LastSeen.Format("2006-01-02 15:04")))
+		if !opts.DryRun {
+			delete(book, opts.Peer)
+			if err := book.Save(path); err != nil {
+				return removed, err
+			}
+		}
+	}
+
+	if opts.Peer != "" {
+		path, err := ReputationPath()
+		if err != nil {
+			return removed, err
+		}
+		reps, err := LoadReputations(path)
+		if err != nil {
+			return removed, err
+		}
+		if rep, ok := reps[opts.Peer]; ok {
+			removed = append(removed, fmt.Sprintf("reputation of %s (%d messages, last seen %s)",
+				opts.Peer, rep.Messages, rep.LastSeen.Format("2006-01-02 15:04")))
+			if !opts.DryRun {
+				delete(reps, opts.Peer)
+				if err := reps.Save(path); err != nil {
+					return removed, err
+				}
+			}
+		}
+	}
+
+	if opts.Room != "" {
+		r, err := purgeScheduled(opts)
+		removed = append(removed, r...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	for _, path := range opts.Transcripts {
+		r, err := purgeTranscript(path, opts)
+		removed = append(removed, r...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	for _, path := range opts.Recordings {
+		r, err := purgeRecording(path, opts)
+		removed = append(removed, r...)
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	if opts.All {
+		for _, f := range []struct{ name, what string }{
+			{reputationFile, "reputations (spam scores, throttling, verified peers)"},
+			{tofuFile, "nicks bound to the peers first seen with them"},
+			{feedsFile, "feed announcement state"},
+			{scheduleFile, "scheduled messages not sent yet"},
+			{nostrKeyFile, "Nostr bridge secret (nicks get new npubs)"},
+		} {
+			path, err := statePath(f.name)
+			if err != nil {
+				return removed, err
+			}
+			if _, err := os.Stat(path); err == nil {
+				removed = append(removed, f.what+": "+path)
+				if !opts.DryRun {
+					if err := os.Remove(path); err != nil {
+						return removed, err
+					}
+				}
+			}
+		}
+
+		dir, err := statePath("crashes")
+		if err != nil {
+			return removed, err
+		}
+		logs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+		if len(logs) > 0 {
+			removed = append(removed, fmt.Sprintf("crash reports (%d files): %s", len(logs), dir))
+			if !opts.DryRun {
+				if err := os.RemoveAll(dir); err != nil {
+					return removed, err
+				}
+			}
+		}
+	}
+	return removed, nil
+}
+
+// purgeScheduled removes the messages and reminders scheduled for
+// opts.Room, on any --network.
+func purgeScheduled(opts PurgeOptions) ([]string, error) {
+	var (
+		s     schedule
+		found []scheduled
+	)
+	_, err := s.update(func(items []scheduled) []scheduled {
+		found = slices.DeleteFunc(slices.Clone(items), func(it scheduled) bool { return it.Room != opts.Room })
+		if opts.DryRun {
+			return items
+		}
+		return slices.DeleteFunc(items, func(it scheduled) bool { return it.Room == opts.Room })
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("%d scheduled message(s) for %s", len(found), opts.Room)}, nil
+}
+
+// purgeTranscript removes the entries opts selects from the transcript at
+// path and its rotated parts, or with All the files themselves. The chain
+// is signed by a key we may no longer have, so it can't be mended: verify
+// reports the lines removed, as it should. When no entry is left in any of
+// them, the files are deleted.
+func purgeTranscript(path string, opts PurgeOptions) ([]string, error) {
+	var (
+		removed []string
+		files   = append([]string{path}, transcriptParts(path)...)
+		kept    = make([][]byte, len(files))
+		dropped = make([]int, len(files))
+		left    = 0
+	)
+	for i, f := range files {
+		data, err := os.ReadFile(f)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for line := range bytes.Lines(data) {
+			var e TranscriptEntry
+			if err := json.Unmarshal(line, &e); err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+			if opts.All || (opts.Room != "" && e.Room == opts.Room) || (opts.Peer != "" && e.From == opts.Peer) {
+				dropped[i]++
+				continue
+			}
+			kept[i] = append(kept[i], line...)
+			left++
+		}
+	}
+	for i, f := range files {
+		switch {
+		case dropped[i] == 0:
+			continue
+		case left == 0:
+			removed = append(removed, fmt.Sprintf("transcript (%d entries): %s", dropped[i], f))
+			if !opts.DryRun {
+				if err := os.Remove(f); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return removed, err
+				}
+			}
+		default:
+			removed = append(removed, fmt.Sprintf("%d transcript entries from %s (verify will report them missing)", dropped[i], f))
+			if !opts.DryRun {
+				if err := writeFileAtomic(f, kept[i], 0o600); err != nil {
+					return removed, err
+				}
+			}
+		}
+	}
+	return removed, nil
+}
+
+// purgeRecording deletes the --record file at path if opts selects it: with
+// All, when it recorded opts.Room, or when opts.Peer sent anything in it.
+// A recording replays the screen, which quotes messages too, so it goes
+// whole rather than losing single events.
+func purgeRecording(path string, opts PurgeOptions) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	match := opts.All
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, transcriptMaxLine)
+	if !match && sc.Scan() {
+		var h recHeader
+		if err := json.Unmarshal(sc.Bytes(), &h); err != nil || h.Format != recordFormat {
+			f.Close()
+			return nil, fmt.Errorf("%s: not a quichat session recording", path)
+		}
+		match = opts.Room != "" && h.Room == opts.Room
+	}
+	for !match && opts.Peer != "" && sc.Scan() {
+		var e recEvent
+		match = json.Unmarshal(sc.Bytes(), &e) == nil && e.Kind == recRecv && e.From == opts.Peer.String()
+	}
+	err = sc.Err()
+	f.Close()
+	if err != nil || !match {
+		return nil, err
+	}
+	removed := []string{"session recording: " + path}
+	if !opts.DryRun {
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	network "github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	protocol "github.com/libp2p/go-libp2p/core/protocol"
+	record "github.com/libp2p/go-libp2p/core/record"
+)
+
+// Peer exchange: whenever a peer joins our room we ask it for the other
+// members it knows, so the mesh heals even while the DHT is slow to answer.
+// Addresses travel as the members' own signed peer records, as received in
+// identify, so a peer can vouch only for addresses their owners published.
+const (
+	pxProtocol  = protocol.ID("/quichat/px/1.0.0")
+	pxMaxPeers  = 32                     // records per response
+	pxMaxBytes  = 256 << 10              // upper bound on one request or response
+	pxDials     = 8                      // new members dialed per exchange
+	pxTimeout   = 10 * time.Second       // per exchange, including the reply
+	pxInterval  = 10 * time.Minute       // ask the same peer at most this often
+	pxAddrTTL   = 10 * time.Minute       // exchanged addresses are kept this long unless confirmed
+	pxDialDelay = 200 * time.Millisecond // between dials, so a burst of joins stays gentle
+	pxRecords   = 1024                   // signed records kept; beyond this arbitrary ones are dropped
+)
+
+type pxRequest struct {
+	Room string `json:"room"`
+}
+
+type pxResponse struct {
+	Records [][]byte `json:"records"` // marshaled peer record envelopes
+}
+
+// initPeerExchange answers PX requests and asks every peer that joins the
+// room for the members it knows. Serve-mode nodes are in no room and skip it.
+func (n *Node) initPeerExchange() error {
+	if n.serve {
+		return nil
+	}
+	events, err := n.Topic.EventHandler()
+	if err != nil {
+		return err
+	}
+	n.Host.SetStreamHandler(pxProtocol, n.handlePX)
+
+	go func() {
+		defer events.Cancel()
+		asked := make(map[peer.ID]time.Time)
+		for {
+			ev, err := events.NextPeerEvent(n.ctx)
+			if err != nil {
+				return
+			}
+			if ev.Type != pubsub.PeerJoin {
+				continue
+			}
+			if time.Since(asked[ev.Peer]) > pxInterval {
+				asked[ev.Peer] = time.Now()
+				go n.requestPX(ev.Peer)
+			}
+		}
+	}()
+	return nil
+}
+
+// handlePX replies with the signed records of our other room members.
+func (n *Node) handlePX(s network.Stream) {
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(pxTimeout))
+
+	var req pxRequest
+	if err := readPX(s, &req); err != nil {
+		s.Reset()
+		return
+	}
+	var resp pxResponse
+	if req.Room == n.topic {
+		resp.Records = n.memberRecords(s.Conn().RemotePeer())
+	}
+	json.NewEncoder(s).Encode(resp)
+}
+
+// memberRecords returns up to pxMaxPeers signed records of room members
+// other than except.
+func (n *Node) memberRecords(except peer.ID) [][]byte {
+	var out [][]byte
+	for _, p := range n.Topic.ListPeers() {
+		if p == except || len(out) == pxMaxPeers {
+			continue
+		}
+		if b := n.records.get(p); b != nil {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// requestPX asks p for the room members it knows and dials the ones we are
+// not connected to yet. Peers that don't speak the protocol are ignored.
+func (n *Node) requestPX(p peer.ID) {
+	ctx, cancel := context.WithTimeout(n.ctx, pxTimeout)
+	defer cancel()
+
+	s, err := n.Host.NewStream(ctx, p, pxProtocol)
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(pxTimeout))
+	if err := json.NewEncoder(s).Encode(pxRequest{Room: n.topic}); err != nil {
+		s.Reset()
+		return
+	}
+	s.CloseWrite()
+	var resp pxResponse
+	if err := readPX(s, &resp); err != nil {
+		s.Reset()
+		return
+	}
+
+	dialed := 0
+	for _, b := range resp.Records {
+		if dialed == pxDials {
+			break
+		}
+		info, ok := verifyRecord(b)
+		if !ok || info.ID == n.Host.ID() || n.Host.Network().Connectedness(info.ID) == network.Connected {
+			continue
+		}
+		n.Host.Peerstore().AddAddrs(info.ID, info.Addrs, pxAddrTTL)
+		dialed++
+		go n.Host.Connect(n.ctx, info)
+		time.Sleep(pxDialDelay)
+	}
+}
+
+// readPX decodes one request or response of at most pxMaxBytes.
+func readPX(r io.Reader, v any) error {
+	return json.NewDecoder(io.LimitReader(r, pxMaxBytes)).Decode(v)
+}
+
+// verifyRecord opens a marshaled peer record envelope and checks that it was
+// signed by the peer it describes.
+func verifyRecord(b []byte) (peer.AddrInfo, bool) {
+	env, rec, err := record.ConsumeEnvelope(b, peer.PeerRecordEnvelopeDomain)
+	if err != nil {
+		return peer.AddrInfo{}, false
+	}
+	pr, ok := rec.(*peer.PeerRecord)
+	if !ok {
+		return peer.AddrInfo{}, false
+	}
+	signer, err := peer.IDFromPublicKey(env.PublicKey)
+	if err != nil || signer != pr.PeerID {
+		return peer.AddrInfo{}, false
+	}
+	return peer.AddrInfo{ID: pr.PeerID, Addrs: pr.Addrs}, true
+}
+
+// signedRecords keeps the latest signed peer record each peer sent us in
+// identify, marshaled and ready to pass on.
+type signedRecords struct {
+	mu sync.Mutex
+	m  map[peer.ID][]byte
+}
+
+func (r *signedRecords) put(p peer.ID, env *record.Envelope) {
+	if env == nil {
+		return
+	}
+	b, err := env.Marshal()
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.m == nil {
+		r.m = make(map[peer.ID][]byte)
+	}
+	r.m[p] = b
+	if len(r.m) > pxRecords {
+		// a fresh record arrives with every identify, so losing a few is cheap
+		for id := range r.m {
+			if id != p {
+				delete(r.m, id)
+				break
+			}
+		}
+	}
+}
+
+func (r *signedRecords) get(p peer.ID) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.m[p]
+}
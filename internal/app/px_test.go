@@ -0,0 +1,62 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	record "github.com/libp2p/go-libp2p/core/record"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func FuzzReadPX(f *testing.F) {
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	id, _ := peer.IDFromPrivateKey(key)
+	rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: id, Addrs: []ma.Multiaddr{ma.StringCast("/ip4/192.0.2.1/udp/4001/quic-v1")}})
+	env, err := record.Seal(rec, key)
+	if err != nil {
+		f.Fatal(err)
+	}
+	signed, err := env.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	resp, _ := json.Marshal(pxResponse{Records: [][]byte{signed, []byte("junk")}})
+	f.Add(resp)
+	f.Add([]byte(`{"room":"quichat/lobby"}`))
+	f.Add([]byte(`{"records":null}`))
+	f.Add([]byte(`{"records":["AAAA"]}`))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var resp pxResponse
+		if err := readPX(bytes.NewReader(b), &resp); err != nil {
+			return
+		}
+		for _, r := range resp.Records {
+			if info, ok := verifyRecord(r); ok && info.ID == "" {
+				t.Fatal("verified a record without a peer ID")
+			}
+		}
+		enc, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back pxResponse
+		if err := readPX(bytes.NewReader(enc), &back); err != nil {
+			if len(enc) > pxMaxBytes {
+				return // the input hid its size in a sparser form
+			}
+			t.Fatalf("decode after encode: %v", err)
+		}
+		if !reflect.DeepEqual(resp, back) {
+			t.Fatalf("round trip changed %+v into %+v", resp, back)
+		}
+	})
+}
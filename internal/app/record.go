@@ -0,0 +1,190 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const recordFormat = "qcr1"
+
+// recHeader is the first line of a session recording.
+type recHeader struct {
+	Format  string    `json:"format"`
+	Version string    `json:"version"` // quichat release that recorded it
+	Nick    string    `json:"nick"`
+	Room    string    `json:"room"`
+	Started time.Time `json:"started"`
+}
+
+// Kinds of recorded event.
+const (
+	recRecv   = "recv"   // a message decoded from the room
+	recSend   = "send"   // a message we published
+	recInput  = "input"  // a line typed at the prompt
+	recPrint  = "print"  // a block shown to the user
+	recStatus = "status" // a new status bar
+)
+
+// recEvent is one line after the header.
+type recEvent struct {
+	At   time.Time `json:"at"`
+	Kind string    `json:"kind"`
+	From string    `json:"from,omitempty"` // sending peer, on recv
+	Msg  *Message  `json:"msg,omitempty"`  // on recv and send
+	Text string    `json:"text,omitempty"` // on input, print and status
+}
+
+// recorder appends a session's events to a --record file as JSON lines.
+// Each line is flushed as it is written, so a crash loses nothing.
+type recorder struct {
+	mu       sync.Mutex
+	f        *os.File
+	w        *bufio.Writer
+	err      error // first write error, until reported
+	reported bool
+}
+
+// openRecorder creates path, replacing an earlier recording there.
+func openRecorder(path string) (*recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &recorder{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// start writes the header.
+func (r *recorder) start(nick, room string) error {
+	return r.put(recHeader{Format: recordFormat, Version: Version, Nick: nick, Room: room, Started: time.Now()})
+}
+
+// add records an event. A nil recorder records nothing.
+func (r *recorder) add(e recEvent) {
+	if r == nil {
+		return
+	}
+	e.At = time.Now()
+	r.put(e)
+}
+
+func (r *recorder) put(v any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.Marshal(v)
+	if err == nil {
+		r.w.Write(append(b, '\n'))
+		err = r.w.Flush()
+	}
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+// failure returns the first write error, once.
+func (r *recorder) failure() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil || r.reported {
+		return nil
+	}
+	r.reported = true
+	return r.err
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w.Flush()
+	return r.f.Close()
+}
+
+// recordUI passes everything on to the UI underneath and records it.
+type recordUI struct {
+	UI
+	rec *recorder
+}
+
+func (u recordUI) Print(s string) {
+	u.rec.add(recEvent{Kind: recPrint, Text: s})
+	u.UI.Print(s)
+	if err := u.rec.failure(); err != nil {
+		u.UI.Print("\033[31m" + fmt.Sprintf(T("Recording stopped: %v"), err) + ansiReset)
+	}
+}
+
+func (u recordUI) SetStatus(s string) {
+	if ui, ok := u.UI.(statusUI); ok {
+		u.rec.add(recEvent{Kind: recStatus, Text: s})
+		ui.SetStatus(s)
+	}
+}
+
+// ReplayOptions controls how a recording is played back.
+type ReplayOptions struct {
+	Speed    float64       // 2 plays twice as fast as recorded
+	MaxPause time.Duration // longer silences are cut to this; 0 keeps them
+	Events   bool          // also show the messages sent and received
+}
+
+// Replay plays a --record file back to out with the recorded timing, as
+// the user saw it: shown blocks and typed lines, optionally with the raw
+// message traffic underneath.
+func Replay(ctx context.Context, in io.Reader, out io.Writer, opts ReplayOptions) error {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	sc := bufio.NewScanner(in)
+	sc.Buffer(nil, transcriptMaxLine)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("empty recording")
+	}
+	var h recHeader
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil || h.Format != recordFormat {
+		return fmt.Errorf("not a quichat session recording")
+	}
+	fmt.Fprintln(out, termText(ansiDim+fmt.Sprintf(T("Replaying %s in %s, recorded %s by quichat %s"),
+		h.Nick, h.Room, h.Started.Local().Format("2006-01-02 15:04"), h.Version)+ansiReset))
+
+	last := h.Started
+	for line := 2; sc.Scan(); line++ {
+		var e recEvent
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		wait := time.Duration(float64(e.At.Sub(last)) / opts.Speed)
+		if opts.MaxPause > 0 && wait > opts.MaxPause {
+			wait = opts.MaxPause
+		}
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+		last = e.At
+		switch {
+		case e.Kind == recPrint:
+			fmt.Fprintln(out, termText(e.Text))
+		case e.Kind == recInput:
+			fmt.Fprintln(out, "> "+e.Text)
+		case opts.Events && e.Msg != nil:
+			arrow := "→"
+			if e.Kind == recRecv {
+				arrow = "←"
+			}
+			fmt.Fprintln(out, termText(ansiDim+fmt.Sprintf("%s %s <%s> %q", arrow, e.Kind, e.Msg.Nick, e.Msg.Text)+ansiReset))
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/metrics"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// RelayConfig controls which relays we prefer and how much we may use them.
+type RelayConfig struct {
+	// Static lists relay multiaddrs (with /p2p/ IDs) to try before any
+	// relay discovered through the DHT.
+	Static []string `json:"static,omitempty"`
+	// BudgetMB caps the traffic exchanged over relayed connections per
+	// session. Once spent, relayed connections are closed and refused.
+	// Zero means unlimited.
+	BudgetMB int64 `json:"budget_mb,omitempty"`
+}
+
+const relayBudgetInterval = 10 * time.Second
+
+// relayState tracks relayed traffic against the configured budget.
+type relayState struct {
+	static []peer.AddrInfo
+	budget int64 // bytes; 0 = unlimited
+	used   atomic.Int64
+	last   map[peer.ID]int64 // per-peer byte totals at the previous tick
+	bw     *metrics.BandwidthCounter
+}
+
+// parseStaticRelays turns configured relay multiaddrs into AddrInfos.
+func parseStaticRelays(addrs []string) ([]peer.AddrInfo, error) {
+	var maddrs []ma.Multiaddr
+	for _, s := range addrs {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", invalidAddr("relay", s, err))
+		}
+		maddrs = append(maddrs, a)
+	}
+	infos, err := peer.AddrInfosFromP2pAddrs(maddrs...)
+	if err != nil {
+		return nil, fmt.Errorf("config: relays must include /p2p/<id>: %w", err)
+	}
+	return infos, nil
+}
+
+// isRelayed reports whether a is a circuit-relay address.
+func isRelayed(a ma.Multiaddr) bool {
+	_, err := a.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
+
+// relayOf returns the relay's peer ID for a circuit address.
+func relayOf(a ma.Multiaddr) peer.ID {
+	relay, _ := ma.SplitFunc(a, func(c ma.Component) bool {
+		return c.Protocol().Code == ma.P_CIRCUIT
+	})
+	_, id := peer.SplitAddr(relay)
+	return id
+}
+
+// watchRelayBudget periodically charges relayed traffic to the budget and
+// cuts relays off once it is exhausted.
+func (n *Node) watchRelayBudget() {
+	rs := n.relay
+	rs.last = make(map[peer.ID]int64)
+	ticker := time.NewTicker(relayBudgetInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		byPeer := rs.bw.GetBandwidthByPeer()
+		for _, p := range n.relayedPeers() {
+			st := byPeer[p]
+			rs.used.Add(st.TotalIn + st.TotalOut - rs.last[p])
+		}
+		for p, st := range byPeer {
+			rs.last[p] = st.TotalIn + st.TotalOut
+		}
+		if rs.used.Load() >= rs.budget {
+			n.gater.blockRelays()
+			for _, c := range n.Host.Network().Conns() {
+				if isRelayed(c.RemoteMultiaddr()) {
+					_ = c.Close()
+				}
+			}
+			n.notify("Relay budget of %d MB used up; relayed connections closed", rs.budget>>20)
+			return
+		}
+	}
+}
+
+// relayedPeers lists peers we currently reach only through a relay.
+func (n *Node) relayedPeers() []peer.ID {
+	var out []peer.ID
+	for _, p := range n.Host.Network().Peers() {
+		conns := n.Host.Network().ConnsToPeer(p)
+		if len(conns) > 0 && !slices.ContainsFunc(conns, func(c network.Conn) bool {
+			return !isRelayed(c.RemoteMultiaddr())
+		}) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// RelayReport describes which relays carry our traffic, for /relays.
+func (n *Node) RelayReport() string {
+	var b strings.Builder
+
+	reserved := map[peer.ID]bool{}
+	for _, a := range n.Host.Addrs() {
+		if isRelayed(a) {
+			reserved[relayOf(a)] = true
+		}
+	}
+	carried := map[peer.ID][]peer.ID{}
+	for _, c := range n.Host.Network().Conns() {
+		if a := c.RemoteMultiaddr(); isRelayed(a) {
+			r := relayOf(a)
+			carried[r] = append(carried[r], c.RemotePeer())
+		}
+	}
+
+	relays := make([]peer.ID, 0, len(reserved)+len(carried))
+	for r := range reserved {
+		relays = append(relays, r)
+	}
+	for r := range carried {
+		if !reserved[r] {
+			relays = append(relays, r)
+		}
+	}
+	slices.Sort(relays)
+
+	if len(relays) == 0 {
+		b.WriteString(T("Not using any relay."))
+	}
+	for i, r := range relays {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s", shortID(r))
+		if slices.ContainsFunc(n.relay.static, func(ai peer.AddrInfo) bool { return ai.ID == r }) {
+			b.WriteString(" (preferred)")
+		}
+		if reserved[r] {
+			b.WriteString(" reservation held")
+		}
+		if peers := carried[r]; len(peers) > 0 {
+			names := make([]string, len(peers))
+			for i, p := range peers {
+				names[i] = shortID(p)
+			}
+			fmt.Fprintf(&b, " carrying %s", strings.Join(names, ", "))
+		}
+	}
+	if budget := n.relay.budget; budget > 0 {
+		fmt.Fprintf(&b, "\nRelay budget: %.1f of %d MB used",
+			float64(n.relay.used.Load())/(1<<20), budget>>20)
+	}
+	return b.String()
+}
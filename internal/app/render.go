@@ -0,0 +1,62 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ANSI fragments for chat lines, kept as constants so rendering only
+// appends and never concatenates.
+const (
+	ansiReset   = "\033[0m"
+	ansiNick    = "\033[32m"
+	ansiMention = "\033[1;35m"
+	ansiDim     = "\033[2m"
+	ansiWarn    = "\033[33m"
+)
+
+// renderBufs recycles the buffers chat lines are built in; a busy room
+// renders many lines a second and each would otherwise grow a fresh one.
+var renderBufs = sync.Pool{New: func() any {
+	b := make([]byte, 0, 512)
+	return &b
+}}
+
+// appendMessage appends the chip-stack form of a chat line to buf:
+//
+//	> [2006-01-02 15:04:05] [nick] #id ⚠ note
+//	» text
+//	» more text
+//
+// id and note are left out when empty.
+func appendMessage(buf []byte, at time.Time, nickColor, nick, id, note, text string) []byte {
+	buf = append(buf, "> ["...)
+	buf = at.AppendFormat(buf, "2006-01-02 15:04:05")
+	buf = append(buf, "] ["...)
+	buf = append(buf, nickColor...)
+	buf = append(buf, nick...)
+	buf = append(buf, ansiReset+"]"...)
+	if id != "" {
+		buf = append(buf, " "+ansiDim+"#"...)
+		buf = append(buf, id...)
+		buf = append(buf, ansiReset...)
+	}
+	if note != "" {
+		buf = append(buf, " "+ansiWarn+"⚠ "...)
+		buf = append(buf, note...)
+		buf = append(buf, ansiReset...)
+	}
+	buf = append(buf, "\n» "...)
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			break
+		}
+		buf = append(buf, text[:i+1]...)
+		buf = append(buf, "» "...)
+		text = text[i+1:]
+	}
+	buf = append(buf, text...)
+	return append(buf, '\n')
+}
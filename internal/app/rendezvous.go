@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	network "github.com/libp2p/go-libp2p/core/network"
+	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
+)
+
+// Room rendezvous: every member advertises the room's topic as a provider
+// record in the DHT and looks the others up there, so members find each
+// other even when nobody they are connected to is in the room. Provider
+// records expire, so both are repeated for as long as we stay, each wait
+// jittered so that members who started together drift apart.
+const (
+	rendezvousRepublish = 0.7              // of the advertised TTL, after which we advertise again
+	rendezvousRefresh   = 10 * time.Minute // between lookups of other members
+	rendezvousRetry     = time.Minute      // after a failed advertisement, doubled up to rendezvousRefresh
+	rendezvousJitter    = 0.1              // ± this fraction of every wait
+	rendezvousTimeout   = time.Minute      // per advertisement or lookup
+	rendezvousDials     = 8                // members dialed per lookup
+)
+
+// rendezvousState is what /debug shows about the maintenance loop.
+type rendezvousState struct {
+	mu             sync.Mutex
+	advertised     time.Time // last successful advertisement
+	nextAdvertise  time.Time
+	lookedUp       time.Time
+	found, dialed  int // by the last lookup
+	lastErr        error
+	advertisements int
+}
+
+// maintainRendezvous advertises the room and looks up its members until
+// n.ctx is done. Every round is traced and, with --verbose, reported.
+func (n *Node) maintainRendezvous() {
+	disc := drouting.NewRoutingDiscovery(n.DHT)
+	now := time.Now()
+	advertiseAt, lookupAt := now, now
+	retry := rendezvousRetry
+	for {
+		if now = time.Now(); !now.Before(advertiseAt) {
+			var ttl time.Duration
+			err := traced(n.ctx, "rendezvous.advertise", func() (err error) {
+				ctx, cancel := context.WithTimeout(n.ctx, rendezvousTimeout)
+				defer cancel()
+				ttl, err = disc.Advertise(ctx, n.topic)
+				return err
+			})
+			if err != nil {
+				advertiseAt = now.Add(jitter(retry))
+				retry = min(2*retry, rendezvousRefresh)
+				n.verboseNotify("Room advertisement failed: %v; retrying in %s", err, time.Until(advertiseAt).Round(time.Second))
+			} else {
+				advertiseAt = now.Add(jitter(time.Duration(rendezvousRepublish * float64(ttl))))
+				retry = rendezvousRetry
+				n.verboseNotify("Advertised the room in the DHT; again in %s", time.Until(advertiseAt).Round(time.Minute))
+			}
+			n.rendezvous.mu.Lock()
+			n.rendezvous.nextAdvertise, n.rendezvous.lastErr = advertiseAt, err
+			if err == nil {
+				n.rendezvous.advertised = now
+				n.rendezvous.advertisements++
+			}
+			n.rendezvous.mu.Unlock()
+		}
+		if !now.Before(lookupAt) {
+			traced(n.ctx, "rendezvous.lookup", func() error { return n.lookupRoom(disc) })
+			lookupAt = now.Add(jitter(rendezvousRefresh))
+		}
+
+		next := advertiseAt
+		if lookupAt.Before(next) {
+			next = lookupAt
+		}
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// lookupRoom dials up to rendezvousDials of the members the DHT lists that
+// we are not connected to yet.
+func (n *Node) lookupRoom(disc *drouting.RoutingDiscovery) error {
+	ctx, cancel := context.WithTimeout(n.ctx, rendezvousTimeout)
+	defer cancel()
+	peers, err := disc.FindPeers(ctx, n.topic)
+	if err != nil {
+		return err
+	}
+	found, dialed := 0, 0
+	for info := range peers {
+		if info.ID == n.Host.ID() || len(info.Addrs) == 0 {
+			continue
+		}
+		found++
+		if dialed < rendezvousDials && n.Host.Network().Connectedness(info.ID) != network.Connected {
+			dialed++
+			go n.Host.Connect(n.ctx, info)
+		}
+	}
+	n.rendezvous.mu.Lock()
+	n.rendezvous.lookedUp, n.rendezvous.found, n.rendezvous.dialed = time.Now(), found, dialed
+	n.rendezvous.mu.Unlock()
+	if dialed > 0 {
+		n.verboseNotify("Found %d room members in the DHT, dialing %d", found, dialed)
+	}
+	return nil
+}
+
+// jitter spreads d by ±rendezvousJitter.
+func jitter(d time.Duration) time.Duration {
+	spread := time.Duration(rendezvousJitter * float64(d))
+	if spread <= 0 {
+		return d
+	}
+	return d + rand.N(2*spread) - spread
+}
+
+// report renders the rendezvous lines of /debug.
+func (r *rendezvousState) report() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	switch {
+	case r.advertised.IsZero() && r.lastErr == nil:
+		b.WriteString(T("Room advertisement: not yet"))
+	case r.advertised.IsZero():
+		fmt.Fprintf(&b, T("Room advertisement: failing (%v), next try in %s"), r.lastErr, time.Until(r.nextAdvertise).Round(time.Second))
+	default:
+		fmt.Fprintf(&b, T("Room advertisement: %d so far, last %s ago, next in %s"), r.advertisements,
+			time.Since(r.advertised).Round(time.Second), time.Until(r.nextAdvertise).Round(time.Second))
+		if r.lastErr != nil {
+			fmt.Fprintf(&b, T(" (last attempt failed: %v)"), r.lastErr)
+		}
+	}
+	if !r.lookedUp.IsZero() {
+		fmt.Fprintf(&b, "\n  "+T("Room lookup: %d members found, %d dialed, %s ago"), r.found, r.dialed, time.Since(r.lookedUp).Round(time.Second))
+	}
+	return b.String()
+}
@@ -0,0 +1,256 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Reputation is our own opinion of peers, formed locally and never sent
+// anywhere. It is kept between runs, so a peer throttled for flooding
+// yesterday is still throttled when it comes back today.
+const (
+	reputationFile     = "reputation.json"
+	reputationMaxAge   = 90 * 24 * time.Hour // forget ordinary peers not seen for this long
+	reputationInterval = time.Minute         // between saves while something changed
+
+	spamHalfLife    = time.Minute      // a peer's spam score halves this often
+	spamLimit       = 20.0             // score (about messages in the last minute) that gets a peer throttled
+	spamThrottle    = 10 * time.Minute // the first throttle; each further one lasts twice as long
+	spamMaxThrottle = 7 * 24 * time.Hour
+)
+
+// Reputation is what we think of one peer.
+type Reputation struct {
+	Nick      string    `json:"nick,omitempty"` // last nick seen
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Messages  int       `json:"messages"`
+	Spam      float64   `json:"spam"`                     // decaying message count, as of LastSeen
+	Strikes   int       `json:"strikes,omitempty"`        // times throttled
+	Throttled time.Time `json:"throttled_until,omitzero"` // messages are hidden until then
+	Blocked   bool      `json:"blocked,omitempty"`        // banned with /banpeer
+	Verified  bool      `json:"verified,omitempty"`       // confirmed with /verify
+}
+
+// Reputations maps peer IDs to our opinion of them.
+type Reputations map[peer.ID]Reputation
+
+// ReputationPath is the default location of the persisted reputations.
+func ReputationPath() (string, error) { return statePath(reputationFile) }
+
+// LoadReputations reads a reputation file; a missing file is empty.
+func LoadReputations(path string) (Reputations, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Reputations{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := Reputations{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Save writes the reputations to path.
+func (r Reputations) Save(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes(), 0o600)
+}
+
+// Find returns the peer whose ID is s or starts with it.
+func (r Reputations) Find(s string) (peer.ID, Reputation, error) {
+	if id, err := peer.Decode(s); err == nil {
+		rep, ok := r[id]
+		if !ok {
+			return "", Reputation{}, fmt.Errorf("nothing known about %s", s)
+		}
+		return id, rep, nil
+	}
+	var found []peer.ID
+	for id := range r {
+		if strings.HasPrefix(id.String(), s) {
+			found = append(found, id)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", Reputation{}, fmt.Errorf("nothing known about %s", s)
+	case 1:
+		return found[0], r[found[0]], nil
+	}
+	return "", Reputation{}, fmt.Errorf("%s matches %d peers; give more of the ID", s, len(found))
+}
+
+// Report renders rep for /whois and quichat peers show.
+func (rep Reputation) Report(now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, T("First seen %s, last seen %s, %d messages"),
+		rep.FirstSeen.Local().Format("2006-01-02 15:04"), rep.LastSeen.Local().Format("2006-01-02 15:04"), rep.Messages)
+	fmt.Fprintf(&b, "\n  "+T("Spam score: %.1f (throttled at %.0f)"), rep.spamAt(now), spamLimit)
+	if now.Before(rep.Throttled) {
+		fmt.Fprintf(&b, "\n  "+T("Throttled until %s (%d times so far)"), rep.Throttled.Local().Format("2006-01-02 15:04"), rep.Strikes)
+	} else if rep.Strikes > 0 {
+		fmt.Fprintf(&b, "\n  "+T("Throttled %d times before"), rep.Strikes)
+	}
+	if rep.Blocked {
+		b.WriteString("\n  " + T("Blocked"))
+	}
+	if rep.Verified {
+		b.WriteString("\n  " + T("Verified"))
+	}
+	return b.String()
+}
+
+// spamAt is the spam score decayed to now.
+func (rep Reputation) spamAt(now time.Time) float64 {
+	return rep.Spam * math.Exp2(-float64(now.Sub(rep.LastSeen))/float64(spamHalfLife))
+}
+
+// reputation is the node's live, persisted set of opinions.
+type reputation struct {
+	mu    sync.Mutex
+	path  string
+	m     Reputations
+	dirty bool
+}
+
+// initReputation loads the saved opinions and keeps the file up to date
+// while we run.
+func (n *Node) initReputation() error {
+	path, err := ReputationPath()
+	if err != nil {
+		return err
+	}
+	m, err := LoadReputations(path)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", path, err)
+	}
+	n.reputation = &reputation{path: path, m: m}
+	go n.persistReputation()
+	return nil
+}
+
+// message counts a chat message from p and reports whether p is throttled,
+// and whether this message is what got it throttled.
+func (r *reputation) message(p peer.ID, nick string, now time.Time) (throttled, started bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := r.touch(p, nick, now)
+	rep.Spam++
+	rep.Messages++
+	if rep.Spam > spamLimit && !now.Before(rep.Throttled) {
+		rep.Strikes++
+		rep.Throttled = now.Add(min(spamThrottle<<(rep.Strikes-1), spamMaxThrottle))
+		started = true
+	}
+	r.m[p] = rep
+	return now.Before(rep.Throttled), started
+}
+
+// seen notes that p announced itself under nick.
+func (r *reputation) seen(p peer.ID, nick string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.m[p] = r.touch(p, nick, now)
+}
+
+// touch returns p's entry brought up to now, with its spam score decayed.
+// Callers hold r.mu.
+func (r *reputation) touch(p peer.ID, nick string, now time.Time) Reputation {
+	rep, ok := r.m[p]
+	if !ok {
+		rep.FirstSeen = now
+	}
+	rep.Spam = rep.spamAt(now)
+	rep.Nick, rep.LastSeen = nick, now
+	r.dirty = true
+	return rep
+}
+
+// throttled reports until when p's messages are hidden, if they are.
+func (r *reputation) throttled(p peer.ID, now time.Time) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	until := r.m[p].Throttled
+	return until, now.Before(until)
+}
+
+// update changes p's entry with fn, creating it if needed.
+func (r *reputation) update(p peer.ID, fn func(*Reputation)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep, ok := r.m[p]
+	if !ok {
+		rep.FirstSeen, rep.LastSeen = time.Now(), time.Now()
+	}
+	fn(&rep)
+	r.m[p] = rep
+	r.dirty = true
+}
+
+// get returns p's entry.
+func (r *reputation) get(p peer.ID) (Reputation, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep, ok := r.m[p]
+	return rep, ok
+}
+
+// blocked reports whether p was banned with /banpeer. The gater only
+// refuses p's own connections; its messages can still reach us relayed
+// through other peers.
+func (r *reputation) blocked(p peer.ID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.m[p].Blocked
+}
+
+// persistReputation saves changes every reputationInterval and on shutdown.
+func (n *Node) persistReputation() {
+	tick := time.NewTicker(reputationInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			n.reputation.save()
+			return
+		case <-tick.C:
+			n.reputation.save()
+		}
+	}
+}
+
+func (r *reputation) save() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return
+	}
+	cutoff := time.Now().Add(-reputationMaxAge)
+	for id, rep := range r.m {
+		if rep.LastSeen.Before(cutoff) && !rep.Blocked && !rep.Verified && !rep.Throttled.After(time.Now()) {
+			delete(r.m, id)
+		}
+	}
+	if r.m.Save(r.path) == nil {
+		r.dirty = false
+	}
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	protocol "github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// meshTracer counts gossipsub events for /roomstats.
+type meshTracer struct {
+	grafts, prunes       atomic.Int64
+	delivered, rejected  atomic.Int64
+	duplicates, dropped  atomic.Int64
+	ihaveSent, ihaveRecv atomic.Int64
+	iwantSent, iwantRecv atomic.Int64
+
+	mu   sync.Mutex
+	mesh map[string]map[peer.ID]struct{} // topic → grafted peers
+}
+
+var _ pubsub.RawTracer = (*meshTracer)(nil)
+
+func newMeshTracer() *meshTracer {
+	return &meshTracer{mesh: make(map[string]map[peer.ID]struct{})}
+}
+
+func (t *meshTracer) Graft(p peer.ID, topic string) {
+	t.grafts.Add(1)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.mesh[topic] == nil {
+		t.mesh[topic] = make(map[peer.ID]struct{})
+	}
+	t.mesh[topic][p] = struct{}{}
+}
+
+func (t *meshTracer) Prune(p peer.ID, topic string) {
+	t.prunes.Add(1)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.mesh[topic], p)
+}
+
+func (t *meshTracer) RemovePeer(p peer.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, peers := range t.mesh {
+		delete(peers, p)
+	}
+}
+
+func (t *meshTracer) meshSize(topic string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.mesh[topic])
+}
+
+func (t *meshTracer) DeliverMessage(*pubsub.Message)        { t.delivered.Add(1) }
+func (t *meshTracer) RejectMessage(*pubsub.Message, string) { t.rejected.Add(1) }
+func (t *meshTracer) DuplicateMessage(*pubsub.Message)      { t.duplicates.Add(1) }
+func (t *meshTracer) UndeliverableMessage(*pubsub.Message)  { t.dropped.Add(1) }
+func (t *meshTracer) DropRPC(*pubsub.RPC, peer.ID)          { t.dropped.Add(1) }
+func (t *meshTracer) AddPeer(peer.ID, protocol.ID)          {}
+func (t *meshTracer) Join(string)                           {}
+func (t *meshTracer) Leave(string)                          {}
+func (t *meshTracer) ValidateMessage(*pubsub.Message)       {}
+func (t *meshTracer) ThrottlePeer(peer.ID)                  {}
+func (t *meshTracer) RecvRPC(rpc *pubsub.RPC)               { countGossip(rpc, &t.ihaveRecv, &t.iwantRecv) }
+func (t *meshTracer) SendRPC(rpc *pubsub.RPC, _ peer.ID) {
+	countGossip(rpc, &t.ihaveSent, &t.iwantSent)
+}
+
+// countGossip adds the message IDs advertised (IHAVE) and requested (IWANT)
+// in rpc to the given counters.
+func countGossip(rpc *pubsub.RPC, ihave, iwant *atomic.Int64) {
+	ctl := rpc.GetControl()
+	if ctl == nil {
+		return
+	}
+	for _, ih := range ctl.GetIhave() {
+		ihave.Add(int64(len(ih.GetMessageIDs())))
+	}
+	for _, iw := range ctl.GetIwant() {
+		iwant.Add(int64(len(iw.GetMessageIDs())))
+	}
+}
+
+// traceFile is a pubsub event tracer backed by a file.
+type traceFile interface {
+	pubsub.EventTracer
+	Close()
+}
+
+// newTraceFile writes gossipsub trace events to path: length-delimited
+// protobuf for *.pb (the format of the libp2p tracer tools), JSON otherwise.
+func newTraceFile(path string) (traceFile, error) {
+	if strings.HasSuffix(path, ".pb") {
+		return pubsub.NewPBTracer(path)
+	}
+	return pubsub.NewJSONTracer(path)
+}
+
+// activity counts chat messages per minute and per nick for the last hour.
+type activity struct {
+	mu     sync.Mutex
+	perMin map[int64]int // unix minute → messages
+	byNick map[string]int
+}
+
+func (a *activity) add(nick string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.perMin == nil {
+		a.perMin, a.byNick = make(map[int64]int), make(map[string]int)
+	}
+	minute := at.Unix() / 60
+	a.perMin[minute]++
+	a.byNick[nick]++
+	for m := range a.perMin {
+		if m <= minute-60 {
+			delete(a.perMin, m)
+		}
+	}
+}
+
+// within returns how many messages arrived in the last d.
+func (a *activity) within(d time.Duration) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	from := time.Now().Add(-d).Unix() / 60
+	total := 0
+	for m, c := range a.perMin {
+		if m > from {
+			total += c
+		}
+	}
+	return total
+}
+
+// top returns up to k nicks by message count, busiest first.
+func (a *activity) top(k int) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	nicks := make([]string, 0, len(a.byNick))
+	for n := range a.byNick {
+		nicks = append(nicks, n)
+	}
+	sort.Slice(nicks, func(i, j int) bool {
+		if a.byNick[nicks[i]] != a.byNick[nicks[j]] {
+			return a.byNick[nicks[i]] > a.byNick[nicks[j]]
+		}
+		return nicks[i] < nicks[j]
+	})
+	if len(nicks) > k {
+		nicks = nicks[:k]
+	}
+	out := make([]string, len(nicks))
+	for i, n := range nicks {
+		out[i] = fmt.Sprintf("%s (%d)", n, a.byNick[n])
+	}
+	return out
+}
+
+// roomStats renders /roomstats.
+func (c *Chat) roomStats() string {
+	t := c.n.mesh
+	var b strings.Builder
+	fmt.Fprintf(&b, T("Room %s"), c.n.room)
+	fmt.Fprintf(&b, "\n  "+T("Messages: %d last minute, %d last 10 minutes, %d last hour"),
+		c.activity.within(time.Minute), c.activity.within(10*time.Minute), c.activity.within(time.Hour))
+	if top := c.activity.top(5); len(top) > 0 {
+		fmt.Fprintf(&b, "\n  "+T("Most active: %s"), strings.Join(top, ", "))
+	}
+	fmt.Fprintf(&b, "\n  "+T("Mesh: %d peers grafted, %d in topic; %d grafts, %d prunes"),
+		t.meshSize(c.n.topic), len(c.n.Topic.ListPeers()), t.grafts.Load(), t.prunes.Load())
+	fmt.Fprintf(&b, "\n  "+T("Gossip: IHAVE %d sent / %d received, IWANT %d sent / %d received"),
+		t.ihaveSent.Load(), t.ihaveRecv.Load(), t.iwantSent.Load(), t.iwantRecv.Load())
+	fmt.Fprintf(&b, "\n  "+T("Messages: %d delivered, %d duplicates, %d rejected, %d dropped"),
+		t.delivered.Load(), t.duplicates.Load(), t.rejected.Load(), t.dropped.Load())
+	fmt.Fprintf(&b, "\n  "+T("Inbox: %d/%d waiting to be shown, %d dropped because the display fell behind"),
+		len(c.inbox), cap(c.inbox), c.dropped.Load())
+	return b.String()
+}
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	scheduleFile  = "scheduled.json"
+	scheduleGrace = 10 * time.Minute // a message this late after a restart is still sent
+	scheduleCheck = 30 * time.Second // also picks up messages another process scheduled
+)
+
+// scheduled is a message or reminder waiting for its time in
+// ~/.quichat/scheduled.json.
+type scheduled struct {
+	ID      string    `json:"id"`
+	Network string    `json:"network,omitempty"` // "" is the public network, as with --network
+	Room    string    `json:"room"`
+	At      time.Time `json:"at"`
+	Text    string    `json:"text"`
+	// Remind is who a /remind is for: "me" shows it only here, a nick
+	// posts it to the room addressed to them. Empty for /schedule.
+	Remind string `json:"remind,omitempty"`
+}
+
+// of reports whether s belongs to n's room: the same room name on another
+// --network is a different room.
+func (s scheduled) of(n *Node) bool { return s.Network == n.network && s.Room == n.room }
+
+// add stores item and wakes the scheduler.
+func (s *schedule) add(item scheduled) error {
+	if _, err := s.update(func(items []scheduled) []scheduled { return append(items, item) }); err != nil {
+		return err
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// schedule reads the file afresh for every change, holding its lock, so
+// nodes in other rooms sharing it don't overwrite each other's messages and
+// two nodes in the same room don't both send one.
+type schedule struct {
+	mu   sync.Mutex
+	wake chan struct{}
+}
+
+// update applies fn to the stored messages and saves the result; with a
+// nil fn it only reads them.
+func (s *schedule) update(fn func([]scheduled) []scheduled) ([]scheduled, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, err := statePath(scheduleFile)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockPath(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	var items []scheduled
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if fn == nil {
+		return items, nil
+	}
+	before := len(items)
+	if items = fn(items); len(items) == before {
+		return items, nil // every change adds or removes a message
+	}
+	b, err = json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return items, writeFileAtomic(path, append(b, '\n'), 0o600)
+}
+
+// parseWhen reads the time at the start of args: "09:00" (the next time
+// the clock shows it), "2026-10-17 09:00" or a delay such as "15m". It
+// returns how many arguments it used.
+func parseWhen(args []string, now time.Time) (time.Time, int, error) {
+	if len(args) == 0 {
+		return time.Time{}, 0, errors.New("missing time")
+	}
+	if d, err := time.ParseDuration(args[0]); err == nil && d > 0 {
+		return now.Add(d), 1, nil
+	}
+	if t, err := time.ParseInLocation("15:04", args[0], now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, 1, nil
+	}
+	if len(args) >= 2 {
+		if at, err := time.ParseInLocation("2006-01-02 15:04", args[0]+" "+args[1], now.Location()); err == nil {
+			if !at.After(now) {
+				return time.Time{}, 0, fmt.Errorf("%s %s is in the past", args[0], args[1])
+			}
+			return at, 2, nil
+		}
+	}
+	return time.Time{}, 0, fmt.Errorf("%q is not a time like 09:00, 2026-10-17 09:00 or 15m", args[0])
+}
+
+// afterFields returns line without its first n whitespace-separated fields,
+// keeping the spacing of the rest.
+func afterFields(line string, n int) string {
+	rest := strings.TrimSpace(line)
+	for range n {
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			return ""
+		}
+		rest = strings.TrimSpace(rest[i:])
+	}
+	return rest
+}
+
+// runSchedule handles /schedule <when> <text>.
+func (c *Chat) runSchedule(line string, args []string) {
+	usage := fmt.Sprintf(T("Usage: %s"), `/schedule <09:00|2026-10-17 09:00|15m> "message"`)
+	at, used, err := parseWhen(args, time.Now())
+	if err != nil {
+		c.ui.Print(usage + "\n" + err.Error())
+		return
+	}
+	text := afterFields(line, used+1)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	if strings.TrimSpace(text) == "" {
+		c.ui.Print(usage)
+		return
+	}
+	item := scheduled{ID: makeID()[:shownIDChars], Network: c.n.network, Room: c.n.room, At: at, Text: text}
+	if err := c.sched.add(item); err != nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Not scheduled: %v"), err) + ansiReset)
+		return
+	}
+	c.ui.Print(fmt.Sprintf(T("Scheduled #%s for %s"), item.ID, at.Format("Mon 2006-01-02 15:04")))
+}
+
+// runRemind handles /remind <who> in <duration> <text> and
+// /remind <who> at <time> <text>.
+func (c *Chat) runRemind(line string, args []string) {
+	usage := fmt.Sprintf(T("Usage: %s"), "/remind <me|nick> in <20m> <text>  or  /remind <me|nick> at <09:00> <text>")
+	if len(args) < 4 || (args[1] != "in" && args[1] != "at") {
+		c.ui.Print(usage)
+		return
+	}
+	who, whenArgs := args[0], args[2:]
+	var (
+		at   time.Time
+		used int
+		err  error
+	)
+	if args[1] == "in" {
+		var d time.Duration
+		if d, err = time.ParseDuration(whenArgs[0]); err == nil && d <= 0 {
+			err = errors.New("the delay must be positive")
+		}
+		at, used = time.Now().Add(d), 1
+	} else {
+		at, used, err = parseWhen(whenArgs, time.Now())
+	}
+	if err != nil {
+		c.ui.Print(usage + "\n" + err.Error())
+		return
+	}
+	text := afterFields(line, 3+used)
+	if text == "" {
+		c.ui.Print(usage)
+		return
+	}
+	if strings.EqualFold(who, c.myNick()) {
+		who = "me"
+	}
+	item := scheduled{ID: makeID()[:shownIDChars], Network: c.n.network, Room: c.n.room, At: at, Text: text, Remind: who}
+	if err := c.sched.add(item); err != nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Not scheduled: %v"), err) + ansiReset)
+		return
+	}
+	c.ui.Print(fmt.Sprintf(T("Reminder #%s for %s at %s"), item.ID, who, at.Format("Mon 2006-01-02 15:04")))
+}
+
+// fire sends a due message or reminder.
+func (c *Chat) fire(s scheduled) {
+	switch s.Remind {
+	case "":
+		c.sendTyped(s.Text, "")
+	case "me":
+		note := ""
+		if late := time.Since(s.At); late > scheduleGrace {
+			note = " " + fmt.Sprintf(T("(due %s)"), s.At.Local().Format("2006-01-02 15:04"))
+		}
+		c.mentions.add(mention{At: time.Now(), Room: c.n.room, Nick: c.myNick(), Text: s.Text})
+		c.ui.Print(ansiMention + "⏰ " + fmt.Sprintf(T("Reminder: %s"), s.Text) + note + ansiReset)
+	default:
+		c.sendTyped(fmt.Sprintf("⏰ %s, reminder: %s", s.Remind, s.Text), "")
+	}
+}
+
+// runScheduled handles /scheduled and /scheduled cancel <id>.
+func (c *Chat) runScheduled(args []string) {
+	if len(args) == 2 && args[0] == "cancel" {
+		id := strings.TrimPrefix(args[1], "#")
+		found := false
+		_, err := c.sched.update(func(items []scheduled) []scheduled {
+			return slices.DeleteFunc(items, func(s scheduled) bool {
+				if s.of(c.n) && s.ID == id {
+					found = true
+				}
+				return s.of(c.n) && s.ID == id
+			})
+		})
+		switch {
+		case err != nil:
+			c.ui.Print(ansiWarn + err.Error() + ansiReset)
+		case !found:
+			c.ui.Print(fmt.Sprintf(T("No scheduled message #%s in this room"), id))
+		default:
+			c.ui.Print(fmt.Sprintf(T("Cancelled #%s"), id))
+		}
+		return
+	}
+	if len(args) != 0 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/scheduled [cancel <id>]"))
+		return
+	}
+	items, err := c.sched.update(nil)
+	if err != nil {
+		c.ui.Print(ansiWarn + err.Error() + ansiReset)
+		return
+	}
+	items = slices.DeleteFunc(items, func(s scheduled) bool { return !s.of(c.n) })
+	if len(items) == 0 {
+		c.ui.Print(T("No scheduled messages in this room"))
+		return
+	}
+	slices.SortFunc(items, func(a, b scheduled) int { return a.At.Compare(b.At) })
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(T("Scheduled messages (%d):"), len(items)))
+	for _, s := range items {
+		text := s.Text
+		if s.Remind != "" {
+			text = fmt.Sprintf(T("reminder for %s: %s"), s.Remind, s.Text)
+		}
+		fmt.Fprintf(&b, "\n  #%s  %s  %s", s.ID, s.At.Local().Format("Mon 2006-01-02 15:04"), text)
+	}
+	c.ui.Print(b.String())
+}
+
+// runScheduler sends this room's scheduled messages and reminders when
+// they fall due, including ones stored before a restart. Messages missed by
+// more than scheduleGrace are dropped with a notice rather than sent out of
+// context; our own reminders are shown late instead.
+func (c *Chat) runScheduler(ctx context.Context) error {
+	reported := false
+	for {
+		now := time.Now()
+		var due, missed []scheduled
+		next := now.Add(scheduleCheck)
+		inLobby := !c.lobby.allows(c.n.Host.ID())
+		_, err := c.sched.update(func(items []scheduled) []scheduled {
+			return slices.DeleteFunc(items, func(s scheduled) bool {
+				switch {
+				case !s.of(c.n):
+					return false
+				case now.Sub(s.At) > scheduleGrace && s.Remind != "me":
+					missed = append(missed, s)
+					return true
+				case !s.At.After(now) && (!inLobby || s.Remind == "me"):
+					due = append(due, s)
+					return true
+				case s.At.After(now) && s.At.Before(next):
+					next = s.At
+				}
+				return false
+			})
+		})
+		if err != nil {
+			// nothing was taken off the file, so sending now would repeat later
+			due, missed = nil, nil
+			if !reported {
+				c.ui.Print(ansiWarn + fmt.Sprintf(T("Scheduled messages can't be read: %v"), err) + ansiReset)
+				reported = true
+			}
+		}
+		slices.SortFunc(due, func(a, b scheduled) int { return a.At.Compare(b.At) })
+		for _, s := range due {
+			c.fire(s)
+		}
+		for _, s := range missed {
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("Scheduled message #%s was due at %s and not sent: %s"),
+				s.ID, s.At.Local().Format("2006-01-02 15:04"), s.Text) + ansiReset)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.sched.wake:
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"text/template"
+)
+
+// Service describes a background quichat process managed by the OS service
+// manager: systemd (user units) on Linux, launchd (agents) on macOS.
+type Service struct {
+	Mode    string   // "daemon" or "serve"
+	Profile string   // profile it runs in, "" for the default
+	Args    []string // extra flags passed to the mode
+}
+
+const systemdUnit = `[Unit]
+Description=Quichat {{.Mode}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart={{range $i, $a := .Exec}}{{if $i}} {{end}}{{printf "%q" $a}}{{end}}
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`
+
+const launchdPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>{{xml .Label}}</string>
+  <key>ProgramArguments</key>
+  <array>
+{{- range .Exec}}
+    <string>{{xml .}}</string>
+{{- end}}
+  </array>
+  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <true/>
+</dict>
+</plist>
+`
+
+var unitFuncs = template.FuncMap{
+	"xml": func(s string) string {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	},
+}
+
+// name is the unit name (Linux) or launchd label (macOS).
+func (s Service) name() string {
+	mode := s.Mode
+	if s.Profile != "" {
+		mode = s.Profile + "-" + mode
+	}
+	if runtime.GOOS == "darwin" {
+		return "io.quichat." + mode
+	}
+	return "quichat-" + mode + ".service"
+}
+
+// Path is where the unit file or plist is installed.
+func (s Service) Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "linux":
+		return filepath.Join(home, ".config", "systemd", "user", s.name()), nil
+	case "darwin":
+		return filepath.Join(home, "Library", "LaunchAgents", s.name()+".plist"), nil
+	}
+	return "", fmt.Errorf("services are not supported on %s", runtime.GOOS)
+}
+
+// Render produces the unit file or plist for this service.
+func (s Service) Render() ([]byte, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, err
+	}
+	data := struct {
+		Mode, Label string
+		Exec        []string
+	}{s.Mode, s.name(), append([]string{exe, s.Mode}, s.Args...)}
+
+	tmpl := systemdUnit
+	if runtime.GOOS == "darwin" {
+		tmpl = launchdPlist
+	}
+	var buf bytes.Buffer
+	if err := template.Must(template.New("unit").Funcs(unitFuncs).Parse(tmpl)).Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Install writes the service definition and starts it.
+func (s Service) Install() error {
+	path, err := s.Path()
+	if err != nil {
+		return err
+	}
+	data, err := s.Render()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		return runTool("launchctl", "load", "-w", path)
+	}
+	if err := runTool("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return runTool("systemctl", "--user", "enable", "--now", s.name())
+}
+
+// Uninstall stops the service and removes its definition.
+func (s Service) Uninstall() error {
+	path, err := s.Path()
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		_ = runTool("launchctl", "unload", "-w", path)
+	} else {
+		_ = runTool("systemctl", "--user", "disable", "--now", s.name())
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		return nil
+	}
+	return runTool("systemctl", "--user", "daemon-reload")
+}
+
+// Status writes the service manager's view of the service to w.
+func (s Service) Status(w io.Writer) error {
+	if _, err := s.Path(); err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("launchctl", "list", s.name())
+	} else {
+		cmd = exec.Command("systemctl", "--user", "status", "--no-pager", s.name())
+	}
+	cmd.Stdout, cmd.Stderr = w, w
+	err := cmd.Run()
+	if _, ok := err.(*exec.ExitError); ok {
+		return nil // non-zero just means "not running"; the output says why
+	}
+	return err
+}
+
+// runTool runs a service-manager command, folding its output into the error.
+func runTool(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v: %w: %s", name, args, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// profile is the name selected with --profile or $QUICHAT_PROFILE; ""
+// keeps state directly in ~/.quichat.
+var profile string
+
+// profileName is what a profile may be called: it becomes a directory name.
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// SetProfile selects the profile whose state StateDir returns. An empty
+// name takes $QUICHAT_PROFILE, and if that is unset too, the default
+// profile in ~/.quichat itself.
+func SetProfile(name string) error {
+	if name == "" {
+		name = os.Getenv("QUICHAT_PROFILE")
+	}
+	if name != "" && !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile %q: use up to 64 letters, digits, '.', '_' or '-'", name)
+	}
+	profile = name
+	return nil
+}
+
+// Profile returns the selected profile, "" for the default one.
+func Profile() string { return profile }
+
+// Profiles lists the profiles created so far, not counting the default.
+func Profiles() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(home, ".quichat", "profiles"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && profileName.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// StateDir returns the directory quichat keeps data in between runs
+// (~/.quichat, or ~/.quichat/profiles/<name> for a profile), creating it
+// if needed.
+func StateDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".quichat")
+	if profile != "" {
+		dir = filepath.Join(dir, "profiles", profile)
+	}
+	return dir, os.MkdirAll(dir, 0o700)
+}
+
+// statePath joins name onto the state directory.
+func statePath(name string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// lockPath waits until this process is the only quichat one holding
+// path's lock, kept in path.lock, and returns what releases it. Files that
+// several processes read, change and write back take it around the whole
+// update, so none of them loses another's change.
+func lockPath(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return func() { f.Close() }, nil
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename,
+// so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
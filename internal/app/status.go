@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+const statusTick = time.Second // how often the status bar looks at the room
+
+// statusUI is implemented by UIs that can keep a status line on screen.
+type statusUI interface {
+	// SetStatus replaces the status line.
+	SetStatus(s string)
+}
+
+// reachStatus describes how peers reach us, from AutoNAT's verdict and the
+// relays AutoRelay picked.
+func (n *Node) reachStatus() string {
+	switch network.Reachability(n.reach.Load()) {
+	case network.ReachabilityPublic:
+		return T("public")
+	case network.ReachabilityPrivate:
+		if n.relaysInUse.Load() > 0 {
+			return T("NAT, via relay")
+		}
+		return T("NAT, no relay yet")
+	}
+	return T("reachability unknown")
+}
+
+// statusLine is what the status bar shows:
+//
+//	alice │ acme:global │ 3 peers │ public │ 2 unread, 1 mention
+//
+// A room in a --network is shown as network/room.
+func (c *Chat) statusLine() string {
+	room := c.n.room
+	if c.n.network != "" {
+		room = c.n.network + "/" + room
+	}
+	parts := []string{
+		c.myNick(),
+		room,
+		fmt.Sprintf(T("%d peers"), len(c.presence.nicks())),
+		c.n.reachStatus(),
+	}
+	if unread := c.unread.Load(); unread > 0 {
+		s := fmt.Sprintf(T("%d unread"), unread)
+		if m := c.unreadMentions.Load(); m > 0 {
+			s += ", " + fmt.Sprintf(T("%d mention(s)"), m)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " │ ")
+}
+
+// markRead clears the unread counts; anything typed counts as having read
+// the room.
+func (c *Chat) markRead() {
+	c.unread.Store(0)
+	c.unreadMentions.Store(0)
+	c.refreshStatus()
+}
+
+// refreshStatus asks runStatus to redraw now rather than at the next tick.
+func (c *Chat) refreshStatus() {
+	select {
+	case c.statusWake <- struct{}{}:
+	default:
+	}
+}
+
+// runStatus keeps ui's status line current until ctx is done: it looks at
+// presence and reachability every statusTick and redraws at once when a
+// message arrives or the user types.
+func (c *Chat) runStatus(ctx context.Context, ui statusUI) error {
+	tick := time.NewTicker(statusTick)
+	defer tick.Stop()
+	last := ""
+	for {
+		if s := c.statusLine(); s != last {
+			ui.SetStatus(s)
+			last = s
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		case <-c.statusWake:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A message storm (history replay, a flood, a busy bridge) would scroll
+// everything off the screen and keep the terminal redrawing. Once more
+// than stormLimit messages arrive within stormWindow, the rest of that
+// window is folded away: they are kept for /more, and a single line per
+// window says how many there were and from whom.
+const (
+	stormWindow = time.Second
+	stormLimit  = 30   // messages shown one by one per window
+	stormKeep   = 2000 // folded messages kept for /more; older ones are dropped
+	morePage    = 50   // messages /more shows at a time
+)
+
+// foldedMsg is a chat message held back during a storm, with what is
+// needed to render it later.
+type foldedMsg struct {
+	at        time.Time
+	nick      string
+	nickColor string
+	id, note  string
+	text      string
+	mentioned bool
+}
+
+// storm counts the messages shown per window and holds the ones folded.
+type storm struct {
+	mu      sync.Mutex
+	start   time.Time      // of the current window
+	shown   int            // messages shown in the current window
+	held    []foldedMsg    // waiting for /more, oldest first
+	lost    int            // folded messages dropped beyond stormKeep
+	fresh   int            // folded since the last summary
+	senders map[string]int // nick → messages folded since the last summary
+}
+
+// admit reports whether a message arriving at now may be shown as usual.
+func (s *storm) admit(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.start) >= stormWindow {
+		s.start, s.shown = now, 0
+	}
+	s.shown++
+	return s.shown <= stormLimit
+}
+
+// fold keeps m for /more.
+func (s *storm) fold(m foldedMsg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = append(s.held, m)
+	if over := len(s.held) - stormKeep; over > 0 {
+		s.held = append(s.held[:0], s.held[over:]...)
+		s.lost += over
+	}
+	s.fresh++
+	if s.senders == nil {
+		s.senders = map[string]int{}
+	}
+	s.senders[m.nick]++
+}
+
+// summary returns the line announcing what was folded since it was last
+// called, or "" if nothing was.
+func (s *storm) summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fresh == 0 {
+		return ""
+	}
+	nicks := make([]string, 0, len(s.senders))
+	for nick := range s.senders {
+		nicks = append(nicks, nick)
+	}
+	sort.Slice(nicks, func(i, j int) bool { return s.senders[nicks[i]] > s.senders[nicks[j]] })
+	var from []string
+	for i, nick := range nicks {
+		if i == 3 {
+			from = append(from, "…")
+			break
+		}
+		from = append(from, fmt.Sprintf("%s %d", nick, s.senders[nick]))
+	}
+	line := fmt.Sprintf(T("… %d more messages (%s), /more to show them"), s.fresh, strings.Join(from, ", "))
+	s.fresh, s.senders = 0, nil
+	return line
+}
+
+// take removes and returns up to n of the oldest held messages, the
+// number still held after them, and how many were dropped for lack of room
+// since the last call.
+func (s *storm) take(n int) (msgs []foldedMsg, left, lost int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n = min(n, len(s.held))
+	msgs = append(msgs, s.held[:n]...)
+	s.held = append(s.held[:0], s.held[n:]...)
+	lost, s.lost = s.lost, 0
+	return msgs, len(s.held), lost
+}
+
+// runStorm prints the summary of each window that had messages folded.
+func (c *Chat) runStorm(ctx context.Context) error {
+	tick := time.NewTicker(stormWindow)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+			if line := c.storm.summary(); line != "" {
+				c.ui.Print(ansiDim + line + ansiReset)
+			}
+		}
+	}
+}
+
+// runMore implements /more [n|all]: show folded messages, oldest first.
+// Consecutive messages from one sender go under a single header.
+func (c *Chat) runMore(args []string) {
+	n := morePage
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "all":
+		n = stormKeep
+	case len(args) == 1:
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/more [n|all]"))
+			return
+		}
+		n = v
+	default:
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/more [n|all]"))
+		return
+	}
+	msgs, left, lost := c.storm.take(n)
+	if lost > 0 {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("%d older messages were not kept"), lost) + ansiReset)
+	}
+	if len(msgs) == 0 {
+		c.ui.Print(T("No more messages"))
+		return
+	}
+	var buf []byte
+	for i, m := range msgs {
+		switch {
+		case accessible:
+			buf = append(buf, plainMessage(m.at, m.nick, m.id, m.note, m.text, m.mentioned)+"\n"...)
+		case i > 0 && msgs[i-1].nick == m.nick && m.note == "":
+			buf = append(buf, "» "...)
+			buf = append(buf, strings.ReplaceAll(isolateRTL(m.text), "\n", "\n» ")...)
+			buf = append(buf, '\n')
+		default:
+			buf = appendMessage(buf, m.at, m.nickColor, isolateRTL(m.nick), m.id, m.note, isolateRTL(m.text))
+		}
+	}
+	if left > 0 {
+		buf = append(buf, ansiDim+fmt.Sprintf(T("… %d more, /more to continue"), left)+ansiReset...)
+	}
+	c.ui.Print(strings.TrimSuffix(string(buf), "\n"))
+}
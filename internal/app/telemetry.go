@@ -0,0 +1,417 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// Spans are created through the OpenTelemetry API and go nowhere unless
+// OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) points
+// at a collector, in which case they are posted in batches as OTLP/HTTP JSON.
+var tracer = otel.Tracer("github.com/ViciousEagle03/P2P_QUICHAT")
+
+const (
+	otlpBatch    = 256              // spans per export request
+	otlpMaxQueue = 4 * otlpBatch    // spans kept while the collector is unreachable
+	otlpInterval = 5 * time.Second  // export at least this often
+	otlpTimeout  = 10 * time.Second // per export request
+	otlpFlush    = 5 * time.Second  // how long shutdown waits for the last export
+)
+
+// startTracing installs the OTLP exporter when the standard OTEL_* variables
+// ask for one. The returned func flushes buffered spans; it is a no-op when
+// tracing is off.
+func startTracing() (shutdown func()) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return func() {}
+		}
+		endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "quichat"
+	}
+
+	p := &otlpProvider{
+		endpoint: endpoint,
+		headers:  parseOTLPHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		resource: []attribute.KeyValue{
+			attribute.String("service.name", service),
+			attribute.String("service.version", Version),
+		},
+		client: &http.Client{Timeout: otlpTimeout},
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	otel.SetTracerProvider(p)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	go p.loop()
+
+	var once sync.Once
+	return func() { once.Do(p.shutdown) }
+}
+
+// parseOTLPHeaders reads OTEL_EXPORTER_OTLP_HEADERS: "k1=v1,k2=v2".
+func parseOTLPHeaders(s string) map[string]string {
+	h := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			h[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return h
+}
+
+// traced runs one step of node setup inside a child span of ctx.
+func traced(ctx context.Context, name string, fn func() error) error {
+	_, span := tracer.Start(ctx, name)
+	defer span.End()
+	err := fn()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// traceParent returns the W3C traceparent of the span in ctx, or "" when
+// tracing is off, so a message can carry its publish span to receivers.
+func traceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier["traceparent"]
+}
+
+// withTraceParent returns ctx with the remote span named by tp, if any.
+func withTraceParent(ctx context.Context, tp string) context.Context {
+	if tp == "" {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{"traceparent": tp})
+}
+
+// otlpProvider is a minimal trace.TracerProvider that records every span and
+// exports finished ones to an OTLP/HTTP endpoint.
+type otlpProvider struct {
+	embedded.TracerProvider
+
+	endpoint string
+	headers  map[string]string
+	resource []attribute.KeyValue
+	client   *http.Client
+
+	mu    sync.Mutex
+	queue []*otlpSpan
+
+	kick   chan struct{}
+	done   chan struct{}
+	exited chan struct{}
+}
+
+func (p *otlpProvider) Tracer(name string, _ ...trace.TracerOption) trace.Tracer {
+	return &otlpTracer{p: p, scope: name}
+}
+
+func (p *otlpProvider) enqueue(s *otlpSpan) {
+	p.mu.Lock()
+	p.queue = append(p.queue, s)
+	if len(p.queue) > otlpMaxQueue {
+		p.queue = p.queue[len(p.queue)-otlpMaxQueue:]
+	}
+	full := len(p.queue) >= otlpBatch
+	p.mu.Unlock()
+	if full {
+		select {
+		case p.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (p *otlpProvider) loop() {
+	defer close(p.exited)
+	tick := time.NewTicker(otlpInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-p.done:
+			p.export()
+			return
+		case <-tick.C:
+		case <-p.kick:
+		}
+		p.export()
+	}
+}
+
+func (p *otlpProvider) shutdown() {
+	close(p.done)
+	select {
+	case <-p.exited:
+	case <-time.After(otlpFlush):
+	}
+}
+
+// export posts queued spans in batches. Failed batches are dropped: tracing
+// must never get in the way of chatting.
+func (p *otlpProvider) export() {
+	for {
+		p.mu.Lock()
+		n := min(len(p.queue), otlpBatch)
+		batch := p.queue[:n:n]
+		p.queue = p.queue[n:]
+		p.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		body, err := json.Marshal(p.request(batch))
+		if err != nil {
+			continue
+		}
+		req, err := http.NewRequest(http.MethodPost, p.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range p.headers {
+			req.Header.Set(k, v)
+		}
+		if resp, err := p.client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// request builds an ExportTraceServiceRequest in the OTLP JSON encoding.
+func (p *otlpProvider) request(spans []*otlpSpan) map[string]any {
+	byScope := make(map[string][]map[string]any)
+	var scopes []string
+	for _, s := range spans {
+		if _, ok := byScope[s.scope]; !ok {
+			scopes = append(scopes, s.scope)
+		}
+		byScope[s.scope] = append(byScope[s.scope], s.json())
+	}
+	scopeSpans := make([]map[string]any, 0, len(scopes))
+	for _, name := range scopes {
+		scopeSpans = append(scopeSpans, map[string]any{
+			"scope": map[string]any{"name": name},
+			"spans": byScope[name],
+		})
+	}
+	return map[string]any{"resourceSpans": []map[string]any{{
+		"resource":   map[string]any{"attributes": otlpAttrs(p.resource)},
+		"scopeSpans": scopeSpans,
+	}}}
+}
+
+type otlpTracer struct {
+	embedded.Tracer
+	p     *otlpProvider
+	scope string
+}
+
+func (t *otlpTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	parent := trace.SpanContextFromContext(ctx)
+	if cfg.NewRoot() {
+		parent = trace.SpanContext{}
+	}
+	var tid trace.TraceID
+	var sid trace.SpanID
+	if parent.IsValid() {
+		tid = parent.TraceID()
+	} else {
+		rand.Read(tid[:])
+	}
+	rand.Read(sid[:])
+
+	start := cfg.Timestamp()
+	if start.IsZero() {
+		start = time.Now()
+	}
+	kind := cfg.SpanKind()
+	if kind == trace.SpanKindUnspecified {
+		kind = trace.SpanKindInternal
+	}
+	s := &otlpSpan{
+		tracer: t,
+		scope:  t.scope,
+		name:   name,
+		kind:   kind,
+		start:  start,
+		attrs:  cfg.Attributes(),
+		sc: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    tid,
+			SpanID:     sid,
+			TraceFlags: trace.FlagsSampled,
+		}),
+	}
+	if parent.IsValid() {
+		s.parent = parent.SpanID()
+	}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type otlpEvent struct {
+	name  string
+	at    time.Time
+	attrs []attribute.KeyValue
+}
+
+// otlpSpan is a recording span; it is queued for export when it ends.
+type otlpSpan struct {
+	embedded.Span
+	tracer *otlpTracer
+
+	mu        sync.Mutex
+	scope     string
+	name      string
+	kind      trace.SpanKind
+	sc        trace.SpanContext
+	parent    trace.SpanID
+	start     time.Time
+	end       time.Time
+	attrs     []attribute.KeyValue
+	events    []otlpEvent
+	status    codes.Code
+	statusMsg string
+}
+
+func (s *otlpSpan) End(opts ...trace.SpanEndOption) {
+	cfg := trace.NewSpanEndConfig(opts...)
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = cfg.Timestamp()
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+	s.mu.Unlock()
+	s.tracer.p.enqueue(s)
+}
+
+func (s *otlpSpan) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, otlpEvent{name: name, at: cfg.Timestamp(), attrs: cfg.Attributes()})
+}
+
+func (s *otlpSpan) AddLink(trace.Link) {}
+
+func (s *otlpSpan) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.end.IsZero()
+}
+
+func (s *otlpSpan) RecordError(err error, opts ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	opts = append(opts, trace.WithAttributes(attribute.String("exception.message", err.Error())))
+	s.AddEvent("exception", opts...)
+}
+
+func (s *otlpSpan) SpanContext() trace.SpanContext { return s.sc }
+
+func (s *otlpSpan) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.statusMsg = code, description
+}
+
+func (s *otlpSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+func (s *otlpSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, kv...)
+}
+
+func (s *otlpSpan) TracerProvider() trace.TracerProvider { return s.tracer.p }
+
+// json encodes the span as an OTLP JSON Span object.
+func (s *otlpSpan) json() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]any{
+		"traceId":           s.sc.TraceID().String(),
+		"spanId":            s.sc.SpanID().String(),
+		"name":              s.name,
+		"kind":              int(s.kind),
+		"startTimeUnixNano": unixNano(s.start),
+		"endTimeUnixNano":   unixNano(s.end),
+		"attributes":        otlpAttrs(s.attrs),
+	}
+	if s.parent.IsValid() {
+		out["parentSpanId"] = s.parent.String()
+	}
+	if len(s.events) > 0 {
+		events := make([]map[string]any, len(s.events))
+		for i, e := range s.events {
+			events[i] = map[string]any{
+				"name":         e.name,
+				"timeUnixNano": unixNano(e.at),
+				"attributes":   otlpAttrs(e.attrs),
+			}
+		}
+		out["events"] = events
+	}
+	// OTLP numbers status codes differently from the Go API.
+	switch s.status {
+	case codes.Ok:
+		out["status"] = map[string]any{"code": 1}
+	case codes.Error:
+		out["status"] = map[string]any{"code": 2, "message": s.statusMsg}
+	}
+	return out
+}
+
+func unixNano(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+// otlpAttrs encodes attributes as OTLP JSON KeyValues.
+func otlpAttrs(kvs []attribute.KeyValue) []map[string]any {
+	out := make([]map[string]any, 0, len(kvs))
+	for _, kv := range kvs {
+		var v map[string]any
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			v = map[string]any{"boolValue": kv.Value.AsBool()}
+		case attribute.INT64:
+			v = map[string]any{"intValue": strconv.FormatInt(kv.Value.AsInt64(), 10)}
+		case attribute.FLOAT64:
+			v = map[string]any{"doubleValue": kv.Value.AsFloat64()}
+		default:
+			v = map[string]any{"stringValue": kv.Value.Emit()}
+		}
+		out = append(out, map[string]any{"key": string(kv.Key), "value": v})
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTLPSpanGolden(t *testing.T) {
+	tid, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	sid, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	parent, _ := trace.SpanIDFromHex("53995c3f42cd8ad8")
+	start := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+
+	p := &otlpProvider{resource: []attribute.KeyValue{
+		attribute.String("service.name", "quichat"),
+		attribute.String("service.version", "v1.2.3"),
+	}}
+	tr := &otlpTracer{p: p, scope: "quichat/chat"}
+	s := &otlpSpan{
+		tracer: tr, scope: tr.scope, name: "chat.receive", kind: trace.SpanKindConsumer,
+		sc:     trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled}),
+		parent: parent, start: start, end: start.Add(1500 * time.Microsecond),
+		attrs: []attribute.KeyValue{
+			attribute.String("msg.id", "3f9a0c1d2e4b5a69"),
+			attribute.Int("msg.bytes", 182),
+			attribute.Bool("msg.edit", false),
+			attribute.Float64("queue.seconds", 0.25),
+		},
+	}
+	s.RecordError(errors.New("decode failed"), trace.WithTimestamp(start.Add(time.Millisecond)))
+	s.SetStatus(codes.Error, "decode failed")
+
+	got, err := json.MarshalIndent(p.request([]*otlpSpan{s}), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "otlp_span", append(got, '\n'))
+}
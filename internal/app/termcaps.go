@@ -0,0 +1,46 @@
+package app
+
+import (
+	"io"
+	"os"
+
+	"github.com/chzyer/readline"
+)
+
+// termCaps is what the terminal on stdout can do with ANSI escapes.
+type termCaps struct {
+	color  bool // SGR colours and bold
+	redraw bool // erasing lines and moving the cursor, so the prompt can be redrawn
+	clear  bool // clearing the screen, for re-wrapping after a resize
+	paste  bool // bracketed paste mode
+}
+
+// tty is what SetupTerminal found. Until then everything is assumed, as for
+// the ANSI terminals most people run quichat in.
+var tty = termCaps{color: true, redraw: true, clear: true, paste: true}
+
+// termOut is where output that bypasses the prompt goes. readline's stdout
+// translates the escapes we use into console calls on Windows consoles that
+// predate VT support.
+var termOut io.Writer = readline.Stdout
+
+// SetupTerminal works out what stdout can display. Pipes, CI logs and
+// TERM=dumb get plain text; NO_COLOR drops colour but keeps the prompt
+// redrawing; --a11y (accessible) turns everything off. On Windows it asks
+// the console to interpret escapes itself and, on consoles too old for
+// that, keeps to the subset readline can translate.
+func SetupTerminal(a11y bool) {
+	accessible = a11y
+	switch {
+	case accessible, !readline.IsTerminal(int(os.Stdout.Fd())), os.Getenv("TERM") == "dumb":
+		tty = termCaps{}
+		return
+	}
+	vt := enableVT()
+	tty = termCaps{
+		color:  os.Getenv("NO_COLOR") == "",
+		redraw: true,
+		clear:  vt,
+		paste:  vt,
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package app
+
+// enableVT reports whether the terminal processes escape sequences, which
+// everywhere but Windows it always has.
+func enableVT() bool { return true }
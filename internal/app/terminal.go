@@ -0,0 +1,472 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
+)
+
+const (
+	termHistory  = 200 // blocks kept for re-wrapping after a resize
+	resizeSettle = 150 * time.Millisecond
+	termQueue    = 256                   // blocks waiting for the writer; Print blocks beyond this
+	termFrame    = 33 * time.Millisecond // output arriving within one frame is written together (~30 fps)
+)
+
+// termUI prints above a readline prompt without mangling the user's input.
+// It wraps output to the terminal width and re-wraps what is on screen when
+// the window is resized.
+//
+// Everything reaches the terminal through one writer goroutine, which
+// collects what arrives within a frame and hands it to readline as a single
+// write, so concurrent output can't interleave with the prompt redraw or
+// split an escape sequence.
+type termUI struct {
+	mu      sync.Mutex
+	rl      *readline.Instance
+	cols    int
+	history []string
+	resize  *time.Timer
+	closed  sync.Once
+
+	status string // status bar text; "" until the first SetStatus
+	rows   int    // terminal height the status bar was placed for
+
+	out  chan termWrite
+	stop chan struct{} // closed by Close: flush what is queued and exit
+	done chan struct{} // closed when the writer has exited
+}
+
+// termWrite is one item for the writer: a block to print above the prompt,
+// or raw bytes written as they are (erasing an echo, a full redraw).
+type termWrite struct {
+	text string
+	raw  bool
+}
+
+// newTermUI creates the prompt with bracketed paste enabled, so a paste
+// arrives as one line rather than one message per line.
+func newTermUI() (*termUI, error) {
+	t := &termUI{
+		cols: readline.GetScreenWidth(),
+		out:  make(chan termWrite, termQueue),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	cfg := &readline.Config{
+		Prompt: "> ",
+		Stdin:  newPasteFilter(readline.NewCancelableStdin(os.Stdin)),
+		FuncOnWidthChanged: func(f func()) {
+			readline.DefaultOnWidthChanged(func() {
+				f()
+				t.widthChanged()
+			})
+		},
+	}
+	if !tty.redraw {
+		// leave line editing and echo to the terminal, so readline never
+		// redraws the prompt: for screen readers, dumb terminals and pipes
+		cfg.FuncIsTerminal = func() bool { return false }
+		cfg.FuncMakeRaw = func() error { return nil }
+		cfg.FuncExitRaw = func() error { return nil }
+		cfg.FuncOnWidthChanged = func(func()) {}
+	}
+	rl, err := readline.NewEx(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init readline: %w", err)
+	}
+	t.rl = rl
+	if readline.DefaultIsTerminal() && tty.paste {
+		rl.Write([]byte(pasteOn))
+	}
+	go t.writer()
+	return t, nil
+}
+
+// Close writes out what is still queued, turns bracketed paste off and
+// returns the terminal to cooked mode. It is safe to call more than once.
+func (t *termUI) Close() {
+	t.closed.Do(func() {
+		close(t.stop)
+		<-t.done
+		t.mu.Lock()
+		if t.rows > 0 {
+			// give the bottom line back to the scrolling area
+			t.rl.Write([]byte(fmt.Sprintf("\x1b7\x1b[r\x1b[%d;1H\x1b[2K\x1b8", t.rows)))
+		}
+		t.mu.Unlock()
+		if readline.DefaultIsTerminal() && tty.paste {
+			t.rl.Write([]byte(pasteOff))
+		}
+		t.rl.Close()
+	})
+}
+
+// Print queues a block to be shown above the prompt. Blocks are shown in
+// the order they were printed; after Close they are dropped.
+func (t *termUI) Print(s string) {
+	if tty.redraw {
+		t.mu.Lock()
+		t.history = append(t.history, s)
+		if len(t.history) > termHistory {
+			t.history = t.history[len(t.history)-termHistory:]
+		}
+		t.mu.Unlock()
+	}
+	t.enqueue(termWrite{text: s})
+}
+
+// write queues raw bytes for the terminal, in order with printed blocks.
+func (t *termUI) write(s string) {
+	t.enqueue(termWrite{text: s, raw: true})
+}
+
+func (t *termUI) enqueue(w termWrite) {
+	select {
+	case t.out <- w:
+	case <-t.done:
+	}
+}
+
+// writer is the only goroutine that writes to the terminal. It waits for
+// output, gives the rest of the frame's output termFrame to arrive, and
+// writes it in one go.
+func (t *termUI) writer() {
+	defer close(t.done)
+	var batch []termWrite
+	for {
+		select {
+		case w := <-t.out:
+			batch = append(batch[:0], w)
+		case <-t.stop:
+			t.flush(t.drain(nil))
+			return
+		}
+		frame := time.NewTimer(termFrame)
+	collect:
+		for {
+			select {
+			case w := <-t.out:
+				batch = append(batch, w)
+			case <-frame.C:
+				break collect
+			case <-t.stop:
+				frame.Stop()
+				t.flush(t.drain(batch))
+				return
+			}
+		}
+		t.flush(batch)
+	}
+}
+
+// drain adds whatever is still queued to batch.
+func (t *termUI) drain(batch []termWrite) []termWrite {
+	for {
+		select {
+		case w := <-t.out:
+			batch = append(batch, w)
+		default:
+			return batch
+		}
+	}
+}
+
+// flush writes a batch: each run of blocks clears the prompt line once,
+// goes above it and redraws the prompt once; raw writes go out between
+// runs as they are.
+func (t *termUI) flush(batch []termWrite) {
+	var b strings.Builder
+	t.mu.Lock()
+	cols := t.cols
+	t.mu.Unlock()
+	inRun := false
+	endRun := func() {
+		if inRun && tty.redraw {
+			b.WriteString(t.rl.Config.Prompt)
+		}
+		inRun = false
+		if b.Len() > 0 {
+			t.rl.Write([]byte(b.String()))
+			b.Reset()
+		}
+	}
+	for _, w := range batch {
+		if w.raw {
+			endRun()
+			t.rl.Write([]byte(w.text))
+			continue
+		}
+		if !tty.redraw {
+			b.WriteString(termText(w.text) + "\n")
+			continue
+		}
+		if !inRun {
+			b.WriteString("\x1b[2K\r")
+			inRun = true
+		}
+		render(&b, termText(w.text), cols)
+	}
+	endRun()
+}
+
+// render writes one block wrapped to cols. Message lines continue under
+// their text, indented past the » marker.
+func render(b *strings.Builder, s string, cols int) {
+	for _, line := range strings.Split(s, "\n") {
+		indent := ""
+		if strings.HasPrefix(line, "» ") {
+			indent = strings.Repeat(" ", displayWidth("» "))
+		}
+		for _, part := range wrapLine(line, cols, indent) {
+			b.WriteString(part + "\n")
+		}
+	}
+}
+
+// widthChanged waits for a drag-resize to settle, then redraws the recent
+// output wrapped to the new width.
+func (t *termUI) widthChanged() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resize != nil {
+		t.resize.Stop()
+	}
+	t.resize = time.AfterFunc(resizeSettle, func() {
+		t.mu.Lock()
+		cols := readline.GetScreenWidth()
+		rows := termRows()
+		if (cols == t.cols && (t.rows == 0 || rows == t.rows)) || !tty.clear {
+			t.cols = cols // only what comes next is wrapped to the new width
+			t.mu.Unlock()
+			return
+		}
+		t.cols = cols
+		var b strings.Builder
+		b.WriteString("\x1b[H\x1b[2J")
+		if t.rows > 0 {
+			t.rows = rows
+			b.WriteString(t.statusBar(true))
+		}
+		for _, s := range t.history {
+			render(&b, termText(s), cols)
+		}
+		b.WriteString(t.rl.Config.Prompt)
+		t.mu.Unlock()
+		t.write(b.String())
+	})
+}
+
+// SetStatus shows s on the terminal's bottom line, kept out of the
+// scrolling area so output and the prompt pass above it. Terminals that
+// can't position the cursor, and --a11y, go without.
+func (t *termUI) SetStatus(s string) {
+	if !tty.redraw || !tty.clear {
+		return
+	}
+	t.mu.Lock()
+	first := t.rows == 0
+	if first {
+		if t.rows = termRows(); t.rows < 3 {
+			t.rows = 0
+			t.mu.Unlock()
+			return
+		}
+	}
+	t.status = s
+	out := t.statusBar(first)
+	t.mu.Unlock()
+	t.write(out)
+}
+
+// statusBar returns the bytes that draw the status line, first confining
+// scrolling to the rows above it if place is set. The cursor is left where
+// it was. t.mu must be held.
+func (t *termUI) statusBar(place bool) string {
+	var b strings.Builder
+	if place {
+		// make room for the bar below the prompt, then fence it off
+		b.WriteString("\n\x1b[1A")
+		fmt.Fprintf(&b, "\x1b7\x1b[1;%dr\x1b8", t.rows-1)
+	}
+	line := t.status
+	if w := displayWidth(line); w < t.cols {
+		line += strings.Repeat(" ", t.cols-w)
+	} else if parts := wrapLine(line, t.cols, ""); len(parts) > 0 {
+		line = parts[0]
+	}
+	fmt.Fprintf(&b, "\x1b7\x1b[%d;1H\x1b[2K", t.rows)
+	b.WriteString(termText("\x1b[7m" + line + ansiReset))
+	b.WriteString("\x1b8")
+	return b.String()
+}
+
+// termRows is the height of the terminal on stdout, or 0 if unknown.
+func termRows() int {
+	_, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return rows
+}
+
+// ChatLoop runs the chat engine in this terminal until the user quits.
+func ChatLoop(ctx context.Context, n *Node, nick string) error {
+	// single shared readline instance
+	ui, err := newTermUI()
+	if err != nil {
+		return err
+	}
+	defer ui.Close()
+
+	chat := NewChat(n, nick, ui)
+
+	g, ctx := errgroup.WithContext(ctx)
+	ran := make(chan struct{})
+	g.Go(func() error {
+		defer close(ran)
+		return chat.Run(ctx)
+	})
+
+	// ─── Sender ─────────────────────────────────────────────────────────────────
+	g.Go(guard("sender", func() error {
+		return readLines(ctx, ui, func(line string) (bool, error) {
+			return chat.Handle(ctx, line)
+		})
+	}))
+
+	// if the receiver fails, don't leave the prompt waiting for Enter; on
+	// the way out, wait for the chat to flush the outbox and say goodbye,
+	// so what it prints meanwhile still reaches the screen
+	g.Go(func() error {
+		<-ran
+		ui.Close()
+		return nil
+	})
+
+	// leaving, by /quit or a signal, is not a failure
+	if err := g.Wait(); err != nil && !errors.Is(err, errQuit) && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// errQuit ends a session's errgroup when the user types /quit.
+var errQuit = errors.New("quit")
+
+// readLines feeds prompt input to handle until it reports quit.
+func readLines(ctx context.Context, ui *termUI, handle func(string) (bool, error)) error {
+	rl := ui.rl
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue // re-prompt on Ctrl+C
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return nil // the chat ended and closed the prompt
+			}
+			if errors.Is(err, io.EOF) {
+				return errQuit // Ctrl+D leaves like /quit
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if text, lines, ok := pastedLines(line); ok {
+			if lines > 1 && !ui.confirm(fmt.Sprintf(T("send %d lines as one message? [y/N] "), lines)) {
+				ui.Print(T("Paste discarded"))
+				continue
+			}
+			ui.eraseEcho() // drop the echoed paste, like a typed line
+			line = text
+		} else if strings.EqualFold(strings.TrimSpace(line), "/compose") {
+			text, err := compose()
+			if err != nil {
+				ui.Print(fmt.Sprintf(T("Compose failed: %v"), err))
+				continue
+			}
+			if text == "" {
+				ui.Print(T("Compose cancelled (empty message)"))
+				continue
+			}
+			line = text
+		} else if len(line) > 0 && line[0] != '/' {
+			// the message comes back through the topic; drop the echo
+			ui.eraseEcho()
+		}
+		quit, err := handle(line)
+		if err != nil {
+			return err
+		}
+		if quit {
+			ui.write(T("👋  Bye!") + "\n")
+			return errQuit
+		}
+	}
+}
+
+// confirm asks a yes/no question on the prompt line; anything but y is no.
+func (t *termUI) confirm(question string) bool {
+	rl := t.rl
+	rl.SetPrompt(question)
+	defer rl.SetPrompt(rl.Config.Prompt)
+	answer, err := rl.Readline()
+	t.eraseEcho()
+	return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+}
+
+// eraseEcho removes the line just entered from the screen. Where the
+// terminal can't redraw, or with --a11y, it stays.
+func (t *termUI) eraseEcho() {
+	if tty.redraw {
+		t.write("\x1b[1A\x1b[2K\r")
+	}
+}
+
+// compose lets the user write a message in $VISUAL or $EDITOR and returns
+// what they saved. readline only reads the terminal while a prompt is
+// active, so the editor has it to itself between Readline calls.
+func compose() (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	f, err := os.CreateTemp("", "quichat-*.txt")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	argv := append(strings.Fields(editor), f.Name())
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", argv[0], err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), " \t\r\n"), nil
+}
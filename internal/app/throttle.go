@@ -0,0 +1,299 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/transport"
+	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
+	"github.com/libp2p/go-libp2p/p2p/transport/quicreuse"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcpreuse"
+	libp2pwebrtc "github.com/libp2p/go-libp2p/p2p/transport/webrtc"
+	ws "github.com/libp2p/go-libp2p/p2p/transport/websocket"
+	webtransport "github.com/libp2p/go-libp2p/p2p/transport/webtransport"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Bandwidth limits (--max-upload, --max-download, /throttle): every byte
+// libp2p sends or receives draws from one token bucket per direction, below
+// encryption and multiplexing, so chat, gossip, the DHT and the circuits we
+// carry as a relay all share the limit. TCP and WebSocket connections are
+// wrapped before the upgrader sees them and the UDP transports (QUIC,
+// WebTransport, WebRTC) get wrapped sockets from quicreuse, but only when
+// a limit is set at startup: a wrapped socket costs QUIC its batched reads,
+// GSO and ECN.
+const (
+	throttleBurst = 100 * time.Millisecond // of traffic at the limit allowed in one go
+	throttleChunk = 16 << 10               // largest write made at once, so a big one trickles out
+	throttleMin   = 1 << 10                // lowest limit; below it libp2p's keepalives time out
+)
+
+// bucket is a token bucket over bytes. Takers go into debt and sleep it
+// off, so concurrent connections share the rate in the order they asked.
+type bucket struct {
+	mu     sync.Mutex
+	rate   int64 // bytes per second; 0 is unlimited
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) limit() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate
+}
+
+func (b *bucket) set(rate int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rate, b.tokens, b.last = rate, 0, time.Now()
+}
+
+// take waits until n bytes may pass.
+func (b *bucket) take(n int) {
+	b.mu.Lock()
+	if b.rate == 0 {
+		b.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	rate := float64(b.rate)
+	burst := max(rate*throttleBurst.Seconds(), throttleChunk)
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate) - float64(n)
+	b.last = now
+	wait := time.Duration(-b.tokens / rate * float64(time.Second))
+	b.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// throttle holds the node's upload and download limits.
+type throttle struct {
+	up, down bucket
+	udp      bool // UDP sockets are wrapped too
+}
+
+// limitedConn is a stream connection drawing from t's buckets.
+type limitedConn struct {
+	manet.Conn
+	t *throttle
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.t.down.take(n)
+	return n, err
+}
+
+func (c *limitedConn) Write(p []byte) (int, error) {
+	var done int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), throttleChunk)]
+		c.t.up.take(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		done += n
+		if err != nil {
+			return done, err
+		}
+		p = p[n:]
+	}
+	return done, nil
+}
+
+type limitedListener struct {
+	manet.Listener
+	t *throttle
+}
+
+func (l *limitedListener) Accept() (manet.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedConn{c, l.t}, nil
+}
+
+// limitedUpgrader hands the upgrader limited connections instead of raw ones.
+type limitedUpgrader struct {
+	transport.Upgrader
+	t *throttle
+}
+
+func (u limitedUpgrader) UpgradeListener(tpt transport.Transport, l manet.Listener) transport.Listener {
+	return u.Upgrader.UpgradeListener(tpt, &limitedListener{l, u.t})
+}
+
+func (u limitedUpgrader) Upgrade(ctx context.Context, tpt transport.Transport, c manet.Conn, dir network.Direction, p peer.ID, scope network.ConnManagementScope) (transport.CapableConn, error) {
+	return u.Upgrader.Upgrade(ctx, tpt, &limitedConn{c, u.t}, dir, p, scope)
+}
+
+// limitedPacketConn is a UDP socket drawing from t's buckets. Packets over
+// the limit wait rather than drop; QUIC's congestion control sees the delay
+// and slows down.
+//
+// It passes on the buffer sizes and the raw socket, which quic-go uses for
+// socket options, but not ReadMsgUDP and WriteMsgUDP: with those quic-go
+// reads batches straight off the raw socket, around ReadFrom and the limit.
+type limitedPacketConn struct {
+	net.PacketConn
+	udp *net.UDPConn
+	t   *throttle
+}
+
+func (c *limitedPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	c.t.down.take(n)
+	return n, addr, err
+}
+
+func (c *limitedPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.t.up.take(len(p))
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func (c *limitedPacketConn) SetReadBuffer(n int) error  { return c.udp.SetReadBuffer(n) }
+func (c *limitedPacketConn) SetWriteBuffer(n int) error { return c.udp.SetWriteBuffer(n) }
+
+func (c *limitedPacketConn) SyscallConn() (syscall.RawConn, error) { return c.udp.SyscallConn() }
+
+// tcpTransport is tcp.NewTCPTransport over limited connections.
+func (t *throttle) tcpTransport(u transport.Upgrader, rcmgr network.ResourceManager, shared *tcpreuse.ConnMgr, opts ...tcp.Option) (*tcp.TcpTransport, error) {
+	return tcp.NewTCPTransport(limitedUpgrader{u, t}, rcmgr, shared, opts...)
+}
+
+func (t *throttle) wsTransport(u transport.Upgrader, rcmgr network.ResourceManager, shared *tcpreuse.ConnMgr) (*ws.WebsocketTransport, error) {
+	return ws.New(limitedUpgrader{u, t}, rcmgr, shared)
+}
+
+func (t *throttle) listenUDP(network string, laddr *net.UDPAddr) (net.PacketConn, error) {
+	conn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedPacketConn{conn, conn, t}, nil
+}
+
+// transportOptions are libp2p's default transports with every connection
+// going through n's limits. TCP and WebSocket are wrapped even without
+// limits, which costs nothing and lets /throttle add some later; UDP only
+// when a limit is already set.
+func (n *Node) transportOptions() []libp2p.Option {
+	t := &n.throttle
+	t.udp = t.up.limit() > 0 || t.down.limit() > 0
+	opts := []libp2p.Option{
+		libp2p.Transport(t.tcpTransport),
+		libp2p.Transport(libp2pquic.NewTransport),
+		libp2p.Transport(t.wsTransport),
+		libp2p.Transport(webtransport.New),
+		libp2p.Transport(libp2pwebrtc.New),
+	}
+	if t.udp {
+		opts = append(opts, libp2p.QUICReuse(quicreuse.NewConnManager, quicreuse.OverrideListenUDP(t.listenUDP)))
+	}
+	return opts
+}
+
+// ParseSize reads a size such as 500K, 2M or 1.5GB in bytes. K, M and G
+// are powers of 1024; "", "0" and "off" mean no limit.
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	if t == "" || t == "OFF" {
+		return 0, nil
+	}
+	t = strings.TrimSuffix(t, "B")
+	mult := 1.0
+	if i := strings.IndexAny(t, "KMG"); i >= 0 && i == len(t)-1 {
+		mult = map[byte]float64{'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30}[t[i]]
+		t = t[:i]
+	}
+	v, err := strconv.ParseFloat(t, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q: want a number of bytes such as 500K or 2M", s)
+	}
+	return int64(v * mult), nil
+}
+
+// ParseRate reads a rate such as 500K, 2M or 1.5MB/s as bytes per second,
+// like ParseSize.
+func ParseRate(s string) (int64, error) {
+	r, err := ParseSize(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: want a number of bytes per second such as 500K or 2M", s)
+	}
+	if r > 0 && r < throttleMin {
+		return 0, fmt.Errorf("rate %q is too low: the least is 1K", s)
+	}
+	return r, nil
+}
+
+// formatSize renders a number of bytes.
+func formatSize(b int64) string {
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(b)/(1<<30))
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.0f KiB", float64(b)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", b)
+}
+
+// formatRate renders bytes per second.
+func formatRate(r int64) string { return formatSize(r) + "/s" }
+
+// formatLimit renders a limit from ParseRate.
+func formatLimit(r int64) string {
+	if r == 0 {
+		return T("unlimited")
+	}
+	return formatRate(r)
+}
+
+// throttleReport renders /throttle: the limits and what is flowing now.
+func (n *Node) throttleReport() string {
+	st := n.relay.bw.GetBandwidthTotals()
+	return fmt.Sprintf(T("Upload: %s (now %s)\n  Download: %s (now %s)"),
+		formatLimit(n.throttle.up.limit()), formatRate(int64(st.RateOut)),
+		formatLimit(n.throttle.down.limit()), formatRate(int64(st.RateIn)))
+}
+
+// runThrottle handles /throttle [up|down <rate>|off].
+func (c *Chat) runThrottle(args []string) {
+	t := &c.n.throttle
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "off":
+		t.up.set(0)
+		t.down.set(0)
+	case len(args) == 2 && (args[0] == "up" || args[0] == "down"):
+		rate, err := ParseRate(args[1])
+		if err != nil {
+			c.ui.Print(ansiWarn + err.Error() + ansiReset)
+			return
+		}
+		if args[0] == "up" {
+			t.up.set(rate)
+		} else {
+			t.down.set(rate)
+		}
+	default:
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/throttle [up|down <rate>|off]"))
+		return
+	}
+	c.ui.Print(c.n.throttleReport())
+	if !t.udp && (t.up.limit() > 0 || t.down.limit() > 0) {
+		c.ui.Print(ansiWarn + T("QUIC, WebTransport and WebRTC aren't limited: start with --max-upload or --max-download to limit them too") + ansiReset)
+	}
+}
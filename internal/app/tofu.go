@@ -0,0 +1,206 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Trust on first use: the first peer seen under a nick in a room is
+// remembered, and any other peer later claiming that nick is flagged until
+// /trust accepts it. Only peers that keep their key (--keep-key, announced
+// with capStableKey) bind a nick: a peer with a fresh key every start
+// would otherwise look like an impostor of itself after each restart.
+// Such peers can't claim a bound nick unflagged, though.
+const (
+	tofuFile = "tofu.json"
+	keysDir  = "keys"
+)
+
+// TOFUBinding is the peer a nick was first seen with.
+type TOFUBinding struct {
+	Peer  peer.ID   `json:"peer"`
+	First time.Time `json:"first_seen"`
+}
+
+// TOFUStore maps a room's topic and a lower-cased nick to its binding.
+type TOFUStore map[string]map[string]TOFUBinding
+
+// tofu is the node's persisted TOFUStore, with the warnings already shown.
+type tofu struct {
+	mu     sync.Mutex
+	path   string
+	m      TOFUStore
+	warned map[peer.ID]string // impostors warned about, by the nick they claimed
+}
+
+// initTOFU loads the nick bindings made in earlier runs.
+func (n *Node) initTOFU() error {
+	path, err := statePath(tofuFile)
+	if err != nil {
+		return err
+	}
+	t := &tofu{path: path, m: TOFUStore{}, warned: map[peer.ID]string{}}
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(b, &t.m); err != nil {
+			return fmt.Errorf("load %s: %w", path, err)
+		}
+	}
+	n.tofu = t
+	return nil
+}
+
+// check looks up nick in topic for p. It binds the nick to p if nobody has
+// it yet and p keeps its key, and otherwise returns the peer it is bound to
+// and whether that is someone else.
+func (t *tofu) check(topic, nick string, p peer.ID, stable bool, now time.Time) (peer.ID, bool) {
+	key := strings.ToLower(nick)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if b, ok := t.m[topic][key]; ok {
+		return b.Peer, b.Peer != p
+	}
+	if stable {
+		t.bindLocked(topic, key, p, now)
+	}
+	return "", false
+}
+
+// trust binds nick in topic to p, replacing whoever had it.
+func (t *tofu) trust(topic, nick string, p peer.ID, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.warned, p)
+	return t.bindLocked(topic, strings.ToLower(nick), p, now)
+}
+
+// warn reports whether p claiming nick hasn't been warned about yet, and
+// notes that it now has.
+func (t *tofu) warn(p peer.ID, nick string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.warned[p] == nick {
+		return false
+	}
+	t.warned[p] = nick
+	return true
+}
+
+// bindLocked records the binding and saves the store. Callers hold t.mu.
+func (t *tofu) bindLocked(topic, key string, p peer.ID, now time.Time) error {
+	if t.m[topic] == nil {
+		t.m[topic] = map[string]TOFUBinding{}
+	}
+	t.m[topic][key] = TOFUBinding{Peer: p, First: now.UTC()}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t.m); err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path, buf.Bytes(), 0o600)
+}
+
+// impostor checks a message from p under nick against the bindings and
+// warns, once per peer and nick, when the nick belongs to someone else.
+func (c *Chat) impostor(p peer.ID, nick string) bool {
+	if c.n.tofu == nil {
+		return false
+	}
+	bound, other := c.n.tofu.check(c.n.topic, nick, p, c.presence.supports(p, capStableKey), time.Now())
+	if other && c.n.tofu.warn(p, nick) {
+		c.ui.Print("\033[1;31m" + fmt.Sprintf(T("⚠ WARNING: %s is not the %s first seen in this room (%s, now %s). It may be an impostor. If they changed keys, check with them and /trust %s"),
+			c.presence.label(p, nick, c.myNick()), nick, shortID(bound), shortID(p), c.presence.label(p, nick, c.myNick())) + ansiReset)
+	}
+	return other
+}
+
+// runTrust implements /trust <nick|peer-id>: accept the peer now going by
+// a nick as its owner in this room.
+func (c *Chat) runTrust(args []string) {
+	if len(args) != 1 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/trust <nick|peer-id>"))
+		return
+	}
+	p, label, err := c.presence.resolve(args[0])
+	if err != nil {
+		c.ui.Print(err.Error())
+		return
+	}
+	nick, _, _ := strings.Cut(label, "~")
+	if err := c.n.tofu.trust(c.n.topic, nick, p, time.Now()); err != nil {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Trust not saved: %v"), err) + ansiReset)
+		return
+	}
+	c.ui.Print(fmt.Sprintf(T("%s now belongs to %s in this room"), nick, p))
+}
+
+// roomKey returns the key this node uses in topic with --keep-key,
+// creating it on first use. Each room gets its own, so a kept key still
+// doesn't link one room to another.
+func roomKey(topic string) (crypto.PrivKey, error) {
+	sum := sha256.Sum256([]byte(topic))
+	return loadKey(hex.EncodeToString(sum[:8]) + ".key")
+}
+
+// identityKey returns the keypair of the identity whose key is called name,
+// creating it on first use. The prefix keeps names apart from room keys.
+func identityKey(name string) (crypto.PrivKey, error) {
+	return loadKey("identity-" + name + ".key")
+}
+
+// validKeyName reports whether name can name an identity's keypair: a
+// single file name in keysDir.
+func validKeyName(name string) error {
+	if len(name) > 64 || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) ||
+		strings.ContainsFunc(name, unicode.IsControl) {
+		return errors.New("must be a plain name of at most 64 characters")
+	}
+	return nil
+}
+
+// loadKey reads the private key in file under keysDir, generating and
+// saving an Ed25519 key if there is none yet.
+func loadKey(file string) (crypto.PrivKey, error) {
+	path, err := statePath(filepath.Join(keysDir, file))
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err == nil {
+		return crypto.UnmarshalPrivateKey(b)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = crypto.MarshalPrivateKey(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	return key, writeFileAtomic(path, b, 0o600)
+}
@@ -0,0 +1,434 @@
+package app
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	transcriptMaxLine = 1 << 20 // bytes; far above any chat message
+	transcriptSplit   = 4       // a --transcript-max quota holds this many parts, the live file included
+)
+
+// TranscriptEntry is one line of a --transcript file. Each entry carries the
+// room message exactly as its sender signed it, the hash of the line before
+// it and the recording node's signature over the rest of the entry, so
+// edits, reordering and removed lines all break verification.
+type TranscriptEntry struct {
+	Seq      uint64    `json:"seq"`  // 1 for the first line; rotated parts keep counting
+	Prev     string    `json:"prev"` // hex SHA-256 of the previous line, "" for the first
+	At       time.Time `json:"at"`   // when the message was delivered to us
+	Room     string    `json:"room"`
+	Topic    string    `json:"topic,omitempty"` // pubsub topic the message came on, when it isn't Room
+	From     peer.ID   `json:"from"`
+	ID       string    `json:"id,omitempty"`
+	Nick     string    `json:"nick"`
+	Text     string    `json:"text"`
+	Msg      []byte    `json:"msg"`      // the sender's signed pubsub message
+	Recorder peer.ID   `json:"recorder"` // node that wrote the line; its key is in the ID
+	Sig      []byte    `json:"sig,omitempty"`
+}
+
+// signedBytes is what the recorder signs: the entry without its signature.
+func (e TranscriptEntry) signedBytes() ([]byte, error) {
+	e.Sig = nil
+	return json.Marshal(e)
+}
+
+// transcript appends delivered chat lines to a --transcript file, continuing
+// the hash chain of whatever the file already holds. With a quota, the file
+// is rotated into path.1, path.2, … (newest first) as it fills, and the
+// oldest parts are deleted so that all of them together stay within it.
+type transcript struct {
+	mu    sync.Mutex
+	path  string
+	f     *os.File
+	key   crypto.PrivKey
+	self  peer.ID
+	seq   uint64
+	prev  string
+	max   int64 // bytes for the live file and its parts; 0 is unlimited
+	size  int64 // of the live file
+	older int64 // of the rotated parts
+
+	warned bool
+	warn   func(format string, args ...any)
+}
+
+// openTranscript opens path for appending, creating it if needed. When the
+// file is empty but rotated parts exist, the chain goes on from the newest.
+func openTranscript(path string, key crypto.PrivKey, self peer.ID, max int64, warn func(string, ...any)) (*transcript, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	t := &transcript{path: path, f: f, key: key, self: self, max: max, warn: warn}
+	t.seq, t.prev, t.size, err = lastEntry(f)
+	if err == nil && t.seq == 0 {
+		if part, perr := os.Open(transcriptPart(path, 1)); perr == nil {
+			t.seq, t.prev, _, err = lastEntry(part)
+			part.Close()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("transcript %s: %w", path, err)
+	}
+	for _, p := range transcriptParts(path) {
+		if fi, err := os.Stat(p); err == nil {
+			t.older += fi.Size()
+		}
+	}
+	return t, nil
+}
+
+// lastEntry reads a transcript file to its end and returns the number and
+// hash of its last line and the file's size.
+func lastEntry(f *os.File) (seq uint64, prev string, size int64, err error) {
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, transcriptMaxLine)
+	for line := 1; sc.Scan(); line++ {
+		var e TranscriptEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return 0, "", 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		seq, prev = e.Seq, lineHash(sc.Bytes())
+		size += int64(len(sc.Bytes())) + 1
+	}
+	return seq, prev, size, sc.Err()
+}
+
+// transcriptPart is the path of the i-th rotated part, 1 being the newest.
+func transcriptPart(path string, i int) string { return fmt.Sprintf("%s.%d", path, i) }
+
+// transcriptParts lists the rotated parts of the transcript at path,
+// newest first.
+func transcriptParts(path string) []string {
+	var parts []string
+	for i := 1; ; i++ {
+		p := transcriptPart(path, i)
+		if _, err := os.Stat(p); err != nil {
+			return parts
+		}
+		parts = append(parts, p)
+	}
+}
+
+// record appends one delivered chat line.
+func (t *transcript) record(room string, a arrival) error {
+	if a.src == nil {
+		return nil
+	}
+	raw, err := a.src.Message.Marshal()
+	if err != nil {
+		return err
+	}
+	// the entry keeps what was sent, not the copy sanitized for display,
+	// so verify can match it against the signed message
+	m, err := decodeMessage(a.src.Data)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := TranscriptEntry{
+		Seq: t.seq + 1, Prev: t.prev, At: a.at.UTC(), Room: room,
+		From: a.src.GetFrom(), ID: m.ID, Nick: m.Nick, Text: m.Text,
+		Msg: raw, Recorder: t.self,
+	}
+	if topic := a.src.GetTopic(); topic != room {
+		e.Topic = topic
+	}
+	b, err := e.signedBytes()
+	if err == nil {
+		e.Sig, err = t.key.Sign(b)
+	}
+	if err == nil {
+		b, err = json.Marshal(e)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := t.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	t.seq, t.prev = e.Seq, lineHash(b)
+	t.size += int64(len(b)) + 1
+	if t.max > 0 && t.size >= t.max/transcriptSplit {
+		return t.rotate()
+	}
+	return nil
+}
+
+// rotate moves the live file to path.1, shifting older parts up, and
+// deletes the oldest parts until a new live file fits in the quota. The
+// rotation before the first deletion warns that it is coming.
+// Callers hold t.mu.
+func (t *transcript) rotate() error {
+	parts := transcriptParts(t.path)
+	for i := len(parts); i >= 1; i-- {
+		if err := os.Rename(transcriptPart(t.path, i), transcriptPart(t.path, i+1)); err != nil {
+			return err
+		}
+	}
+	t.f.Close()
+	renamed := os.Rename(t.path, transcriptPart(t.path, 1))
+	f, err := os.OpenFile(t.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	t.f = f
+	if renamed != nil {
+		return renamed
+	}
+	t.older += t.size
+	t.size = 0
+
+	part := t.max / transcriptSplit
+	parts = transcriptParts(t.path)
+	for len(parts) > 0 && t.older+part > t.max {
+		oldest := parts[len(parts)-1]
+		fi, err := os.Stat(oldest)
+		if err == nil {
+			err = os.Remove(oldest)
+		}
+		if err != nil {
+			return err
+		}
+		t.older -= fi.Size()
+		parts = parts[:len(parts)-1]
+		t.warned = true
+		t.warn("Transcript quota of %s reached: deleted %s, its oldest part", formatSize(t.max), filepath.Base(oldest))
+	}
+	if !t.warned && t.older+2*part > t.max {
+		t.warned = true
+		t.warn("The transcript is using %s of its %s quota; its oldest entries will be deleted to make room",
+			formatSize(t.older), formatSize(t.max))
+	}
+	return nil
+}
+
+func (t *transcript) Close() error { return t.f.Close() }
+
+// historyQuery selects transcript entries for the history API.
+type historyQuery struct {
+	Room  string    // "" for every room the file has seen
+	Since time.Time // zero for no limit
+	Terms []string  // lowercased words that must all occur in the nick or text
+	After uint64    // resume after this entry
+	Limit int
+}
+
+func (q historyQuery) match(e *TranscriptEntry) bool {
+	if e.Seq <= q.After || (q.Room != "" && e.Room != q.Room) || e.At.Before(q.Since) {
+		return false
+	}
+	hay := strings.ToLower(e.Nick + " " + e.Text)
+	for _, term := range q.Terms {
+		if !strings.Contains(hay, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// history returns up to q.Limit matching entries, oldest first, and whether
+// more follow. It reads the rotated parts and what the live file held when
+// called from separate handles, so recording carries on meanwhile.
+func (t *transcript) history(q historyQuery) ([]TranscriptEntry, bool, error) {
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	t.mu.Lock()
+	size := t.size
+	parts := transcriptParts(t.path)
+	var err error
+	for i := len(parts) - 1; i >= -1 && err == nil; i-- {
+		var f *os.File
+		if i >= 0 {
+			f, err = os.Open(parts[i])
+		} else {
+			f, err = os.Open(t.path)
+		}
+		if err == nil {
+			files = append(files, f)
+		}
+	}
+	t.mu.Unlock()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var out []TranscriptEntry
+	for i, f := range files {
+		var r io.Reader = f
+		if i == len(files)-1 {
+			r = io.LimitReader(f, size)
+		}
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, transcriptMaxLine)
+		for line := 1; sc.Scan(); line++ {
+			var e TranscriptEntry
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				return nil, false, fmt.Errorf("%s line %d: %w", filepath.Base(f.Name()), line, err)
+			}
+			if !q.match(&e) {
+				continue
+			}
+			if len(out) == q.Limit {
+				return out, true, nil
+			}
+			out = append(out, e)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, false, err
+		}
+	}
+	return out, false, nil
+}
+
+func lineHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// TranscriptReport summarises a verified transcript.
+type TranscriptReport struct {
+	First     uint64 // number of the first entry; above 1 when earlier parts are missing
+	Entries   int
+	Recorders []peer.ID // in order of first appearance
+	Gaps      []string  // chat lines a sender published that never reached the recorder
+}
+
+// VerifyTranscript checks every line of a transcript: the hash chain and
+// numbering, each recorder's signature, and each sender's signature over the
+// message text. It stops at the first line that fails. Rotated parts are
+// checked by reading them oldest first; a reader starting at a later part
+// verifies from there and reports where it began. Lines cut off the end
+// of the file leave no trace; compare Entries with a copy kept elsewhere.
+func VerifyTranscript(r io.Reader) (TranscriptReport, error) {
+	var rep TranscriptReport
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, transcriptMaxLine)
+	prev := ""
+	lastSeq := make(map[peer.ID]uint64) // per sender and recorder
+	for line := 1; sc.Scan(); line++ {
+		fail := func(format string, args ...any) (TranscriptReport, error) {
+			return rep, fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+		}
+		var e TranscriptEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fail("%v", err)
+		}
+		if line == 1 {
+			rep.First = 1
+			if e.Seq > 1 && e.Prev != "" {
+				rep.First, prev = e.Seq, e.Prev // continues a part we were not given
+			}
+		}
+		switch {
+		case e.Seq != rep.First+uint64(line)-1:
+			return fail("entry %d where %d was expected: lines were removed or reordered", e.Seq, rep.First+uint64(line)-1)
+		case e.Prev != prev:
+			return fail("hash chain broken: the line before it was changed")
+		}
+		prev = lineHash(sc.Bytes())
+
+		if err := verifyRecorder(e); err != nil {
+			return fail("recorder signature: %v", err)
+		}
+		m, err := verifySender(e)
+		if err != nil {
+			return fail("sender signature: %v", err)
+		}
+
+		if len(rep.Recorders) == 0 || rep.Recorders[len(rep.Recorders)-1] != e.Recorder {
+			rep.Recorders = append(rep.Recorders, e.Recorder)
+			clear(lastSeq) // a new session joins mid-conversation
+		}
+		if last := lastSeq[e.From]; m.Seq > last+1 && last != 0 {
+			rep.Gaps = append(rep.Gaps, fmt.Sprintf("line %d: %d message(s) from %s (%s) missing before it",
+				line, m.Seq-last-1, e.Nick, shortID(e.From)))
+		}
+		lastSeq[e.From] = m.Seq
+		rep.Entries++
+	}
+	return rep, sc.Err()
+}
+
+func verifyRecorder(e TranscriptEntry) error {
+	pub, err := e.Recorder.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	b, err := e.signedBytes()
+	if err != nil {
+		return err
+	}
+	if ok, err := pub.Verify(b, e.Sig); err != nil || !ok {
+		return errors.New("does not match the entry")
+	}
+	return nil
+}
+
+// verifySender checks the pubsub signature on e.Msg and that the entry
+// quotes the message it carries.
+func verifySender(e TranscriptEntry) (Message, error) {
+	var msg pb.Message
+	if err := msg.Unmarshal(e.Msg); err != nil {
+		return Message{}, err
+	}
+	from, err := peer.IDFromBytes(msg.From)
+	if err != nil || from != e.From {
+		return Message{}, errors.New("message is from another peer")
+	}
+	pub, err := from.ExtractPublicKey()
+	if len(msg.Key) > 0 {
+		pub, err = crypto.UnmarshalPublicKey(msg.Key)
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	if !from.MatchesPublicKey(pub) {
+		return Message{}, errors.New("key does not match the sender")
+	}
+	unsigned := msg
+	unsigned.Signature, unsigned.Key = nil, nil
+	b, err := unsigned.Marshal()
+	if err != nil {
+		return Message{}, err
+	}
+	if ok, err := pub.Verify(append([]byte(pubsub.SignPrefix), b...), msg.Signature); err != nil || !ok {
+		return Message{}, errors.New("does not match the message")
+	}
+	m, err := decodeMessage(msg.Data)
+	if err != nil {
+		return Message{}, err
+	}
+	topic := e.Room
+	if e.Topic != "" {
+		topic = e.Topic
+	}
+	if msg.GetTopic() != topic || m.ID != e.ID || m.Nick != e.Nick || m.Text != e.Text {
+		return Message{}, errors.New("entry text differs from the signed message")
+	}
+	return m, nil
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// signedArrival is m as the owner of key would publish it to room, signed
+// the way pubsub signs it.
+func signedArrival(tb testing.TB, key crypto.PrivKey, room string, m Message) arrival {
+	tb.Helper()
+	from, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	data, err := encodeMessage(m, true)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	msg := &pb.Message{From: []byte(from), Data: data, Seqno: binary.BigEndian.AppendUint64(nil, m.Seq), Topic: &room}
+	b, err := msg.Marshal()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if msg.Signature, err = key.Sign(append([]byte(pubsub.SignPrefix), b...)); err != nil {
+		tb.Fatal(err)
+	}
+	return arrival{m: m, at: m.Ts, src: &pubsub.Message{Message: msg}}
+}
+
+func testKey(tb testing.TB) (crypto.PrivKey, peer.ID) {
+	tb.Helper()
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return key, id
+}
+
+// BenchmarkTranscriptRecord measures appending one line to the chain:
+// hashing, signing and writing.
+func BenchmarkTranscriptRecord(b *testing.B) {
+	key, self := testKey(b)
+	sender, _ := testKey(b)
+	t, err := openTranscript(filepath.Join(b.TempDir(), "room.log"), key, self, 0, b.Logf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer t.Close()
+	a := signedArrival(b, sender, "lobby", sampleMessage())
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := t.record("lobby", a); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVerifyTranscript measures checking a 1000-line transcript.
+func BenchmarkVerifyTranscript(b *testing.B) {
+	key, self := testKey(b)
+	sender, _ := testKey(b)
+	path := filepath.Join(b.TempDir(), "room.log")
+	t, err := openTranscript(path, key, self, 0, b.Logf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m := sampleMessage()
+	for i := range 1000 {
+		m.Seq, m.Ts = uint64(i+1), m.Ts.Add(time.Second)
+		if err := t.record("lobby", signedArrival(b, sender, "lobby", m)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	t.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		rep, err := VerifyTranscript(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if rep.Entries != 1000 {
+			b.Fatalf("verified %d entries, want 1000", rep.Entries)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transform rewrites the lines we type in a room before they are sent.
+type Transform struct {
+	// Templates are inserted where a line says {{name}}; {{nick}},
+	// {{room}}, {{time}} and {{date}} are always available.
+	Templates map[string]string `json:"templates,omitempty"`
+	// Footer is added on a line of its own under every message.
+	Footer string `json:"footer,omitempty"`
+}
+
+// transform returns the transform for room, falling back to "default".
+func (c *Config) transform(room string) (Transform, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []string{room, "default"} {
+		if t, ok := c.Transforms[key]; ok {
+			return t, true
+		}
+	}
+	return Transform{}, false
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// apply runs the pipeline over one typed line: templates, then the footer.
+// Placeholders nobody defined are left as typed.
+func (t Transform) apply(text, nick, room string, now time.Time) string {
+	expand := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			name := placeholder.FindStringSubmatch(m)[1]
+			switch name {
+			case "nick":
+				return nick
+			case "room":
+				return room
+			case "time":
+				return now.Format("15:04")
+			case "date":
+				return now.Format("2006-01-02")
+			}
+			if v, ok := t.Templates[name]; ok {
+				return v
+			}
+			return m
+		})
+	}
+	text = expand(text)
+	if t.Footer != "" {
+		text += "\n" + expand(t.Footer)
+	}
+	return text
+}
+
+// lastSent is the last line we typed, kept for /s corrections. Raw is the
+// line before transforms, so a correction runs the pipeline afresh rather
+// than stacking a second footer.
+type lastSent struct {
+	mu  sync.Mutex
+	id  string
+	raw string
+}
+
+func (l *lastSent) set(id, raw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.id, l.raw = id, raw
+}
+
+func (l *lastSent) get() (id, raw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.id, l.raw
+}
+
+// isCorrection reports whether line is meant as a /s correction: "/s"
+// followed straight away by a punctuation separator.
+func isCorrection(line string) bool {
+	return len(line) > 2 && strings.HasPrefix(line, "/s") && strings.ContainsRune("/|#,:;!@%+=~", rune(line[2]))
+}
+
+// parseCorrection reads "/s/old/new/" with an optional trailing g to
+// replace every occurrence. Other separators such as | work as in sed.
+func parseCorrection(line string) (old, repl string, all bool, ok bool) {
+	if !isCorrection(line) {
+		return "", "", false, false
+	}
+	parts := strings.Split(line[3:], line[2:3])
+	switch {
+	case len(parts) == 2:
+	case len(parts) == 3 && (parts[2] == "" || parts[2] == "g"):
+		all = parts[2] == "g"
+	default:
+		return "", "", false, false
+	}
+	if parts[0] == "" {
+		return "", "", false, false
+	}
+	return parts[0], parts[1], all, true
+}
+
+// sendTyped queues a typed line after running the room's transforms; edit is
+// the ID of the message it corrects, if any.
+func (c *Chat) sendTyped(raw, edit string) {
+	text := raw
+	if t, ok := c.n.cfg.transform(c.n.room); ok {
+		text = t.apply(raw, c.myNick(), c.n.room, time.Now())
+	}
+	m := Message{ID: makeID(), Nick: c.myNick(), Text: text, Ts: time.Now().UTC(), Seq: c.seq.Add(1), Edit: edit}
+	c.last.set(m.ID, raw)
+	c.queue(m)
+}
+
+// runCorrection applies a /s/old/new/ correction to our last message and
+// sends the result as an edit of it.
+func (c *Chat) runCorrection(line string) {
+	old, repl, all, ok := parseCorrection(line)
+	if !ok {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/s/old/new/[g]"))
+		return
+	}
+	id, raw := c.last.get()
+	if id == "" {
+		c.ui.Print(T("Nothing to correct: you haven't sent a message yet"))
+		return
+	}
+	if !strings.Contains(raw, old) {
+		c.ui.Print(fmt.Sprintf(T("%q is not in your last message"), old))
+		return
+	}
+	n := 1
+	if all {
+		n = -1
+	}
+	c.sendTyped(strings.Replace(raw, old, repl, n), id)
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	translateQueue   = 64 // messages waiting for auto-translation; beyond this new ones are skipped
+	translateTimeout = 15 * time.Second
+)
+
+// TranslateConfig points /translate at a LibreTranslate-compatible
+// backend: a self-hosted server or local model behind the same API, or a
+// hosted service.
+type TranslateConfig struct {
+	URL    string `json:"url,omitempty"` // e.g. http://localhost:5000/translate
+	APIKey string `json:"api_key,omitempty"`
+	// Lang is the language translations are made into; default the UI's.
+	Lang string `json:"lang,omitempty"`
+	// Auto lists rooms whose incoming messages are all translated.
+	Auto []string `json:"auto,omitempty"`
+}
+
+// translation returns a copy of the translation settings.
+func (c *Config) translation() TranslateConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.Translate
+	t.Auto = slices.Clone(t.Auto)
+	return t
+}
+
+// validateTranslate rejects a backend URL that could never be reached.
+func validateTranslate(t TranslateConfig) error {
+	if t.URL != "" && !strings.HasPrefix(t.URL, "https://") && !strings.HasPrefix(t.URL, "http://") {
+		return fmt.Errorf("translate: url must start with http:// or https://, not %q", t.URL)
+	}
+	return nil
+}
+
+// translateJob is a message waiting for the backend.
+type translateJob struct {
+	m      Message
+	target string
+	auto   bool // skip quietly if the message is already in target
+}
+
+// translateText sends text to the backend and returns the translation and
+// the language it detected.
+func translateText(ctx context.Context, client *http.Client, cfg TranslateConfig, text, target string) (string, string, error) {
+	body, err := json.Marshal(map[string]string{
+		"q": text, "source": "auto", "target": target, "format": "text", "api_key": cfg.APIKey,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "quichat/"+Version)
+	resp, err := client.Do(req)
+	if uerr, ok := err.(*url.Error); ok {
+		return "", "", uerr.Err // the URL may carry a key
+	}
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && resp.StatusCode/100 == 2 {
+		return "", "", fmt.Errorf("unreadable reply: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		if out.Error != "" {
+			return "", "", fmt.Errorf("%s: %s", resp.Status, out.Error)
+		}
+		return "", "", fmt.Errorf("%s", resp.Status)
+	}
+	return out.TranslatedText, out.DetectedLanguage.Language, nil
+}
+
+// runTranslate handles /translate <id> [lang] and /translate auto on|off.
+func (c *Chat) runTranslate(args []string) {
+	cfg := c.n.cfg.translation()
+	if cfg.URL == "" {
+		c.ui.Print(T("No translation backend: set translate.url in the config"))
+		return
+	}
+	if len(args) == 2 && args[0] == "auto" && (args[1] == "on" || args[1] == "off") {
+		c.autoTranslate.Store(args[1] == "on")
+		if args[1] == "on" {
+			c.ui.Print(fmt.Sprintf(T("Translating every message in this room into %s"), translateTarget(cfg, "")))
+		} else {
+			c.ui.Print(T("Auto-translation off"))
+		}
+		return
+	}
+	if len(args) < 1 || len(args) > 2 {
+		c.ui.Print(fmt.Sprintf(T("Usage: %s"), "/translate <message-id> [lang]  |  /translate auto on|off"))
+		return
+	}
+	m, err := c.pins.lookup(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		c.ui.Print(err.Error())
+		return
+	}
+	job := translateJob{m: m, target: translateTarget(cfg, "")}
+	if len(args) == 2 {
+		job.target = translateTarget(cfg, args[1])
+	}
+	c.queueTranslation(job)
+}
+
+// translateTarget picks the language to translate into.
+func translateTarget(cfg TranslateConfig, asked string) string {
+	switch {
+	case asked != "":
+		return langBase(asked)
+	case cfg.Lang != "":
+		return cfg.Lang
+	}
+	return lang
+}
+
+// autoTranslateMessage queues an incoming message when the room is in
+// auto-translate mode.
+func (c *Chat) autoTranslateMessage(m Message) {
+	if !c.autoTranslate.Load() || m.Nick == c.myNick() || strings.TrimSpace(m.Text) == "" {
+		return
+	}
+	c.queueTranslation(translateJob{m: m, target: translateTarget(c.n.cfg.translation(), ""), auto: true})
+}
+
+func (c *Chat) queueTranslation(job translateJob) {
+	select {
+	case c.translations <- job:
+	default:
+		if !job.auto {
+			c.ui.Print(ansiWarn + T("Too many translations waiting, try again shortly") + ansiReset)
+		}
+	}
+}
+
+// runTranslations sends queued messages to the backend one at a time and
+// prints each translation, marked with the message it belongs to. Failures
+// in auto mode are reported once until a translation succeeds again.
+func (c *Chat) runTranslations(ctx context.Context) error {
+	client := &http.Client{Timeout: translateTimeout}
+	failing := false
+	for {
+		var job translateJob
+		select {
+		case <-ctx.Done():
+			return nil
+		case job = <-c.translations:
+		}
+		cfg := c.n.cfg.translation()
+		out, from, err := translateText(ctx, client, cfg, job.m.Text, job.target)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			if !job.auto || !failing {
+				c.ui.Print(ansiWarn + fmt.Sprintf(T("Translation of #%s failed: %v"), shortMsgID(job.m.ID), err) + ansiReset)
+			}
+			failing = true
+			continue
+		}
+		failing = false
+		if job.auto && (from == job.target || out == job.m.Text) {
+			continue
+		}
+		if from == "" {
+			from = "?"
+		}
+		c.ui.Print(ansiDim + fmt.Sprintf("  ↳ #%s %s→%s: ", shortMsgID(job.m.ID), from, job.target) + ansiReset + isolateRTL(out))
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// --tts modes.
+const (
+	TTSAll      = "all"      // speak every message from others
+	TTSMentions = "mentions" // speak only messages that name us
+)
+
+const (
+	ttsQueue   = 8   // lines waiting to be spoken; beyond this new ones are skipped
+	ttsMaxText = 300 // bytes of a message read out
+)
+
+// speaker returns the command that reads stdin aloud with the OS speech
+// synthesizer.
+func speaker() ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"say"}, nil
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-Command",
+			"Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())"}, nil
+	}
+	for _, cmd := range [][]string{{"espeak-ng", "--stdin"}, {"espeak", "--stdin"}, {"spd-say", "--wait", "--pipe-mode"}} {
+		if _, err := exec.LookPath(cmd[0]); err == nil {
+			return cmd, nil
+		}
+	}
+	return nil, errors.New("no speech synthesizer found: install espeak-ng or speech-dispatcher")
+}
+
+// speak queues a message to be read out if --tts asks for it.
+func (c *Chat) speak(m Message, mentioned bool) {
+	if c.speech == nil || m.Nick == c.myNick() || (c.n.tts == TTSMentions && !mentioned) {
+		return
+	}
+	text := m.Text
+	if len(text) > ttsMaxText {
+		text = text[:ttsMaxText]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	select {
+	case c.speech <- fmt.Sprintf(T("%s says: %s"), m.Nick, text):
+	default: // rather skip a line than fall minutes behind the chat
+	}
+}
+
+// runSpeech reads queued lines aloud one after another until ctx is done.
+func (c *Chat) runSpeech(ctx context.Context, argv []string) error {
+	failed := false
+	for {
+		var line string
+		select {
+		case <-ctx.Done():
+			return nil
+		case line = <-c.speech:
+		}
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Stdin = strings.NewReader(line)
+		if err := cmd.Run(); err != nil && ctx.Err() == nil && !failed {
+			c.ui.Print(ansiWarn + fmt.Sprintf(T("Text to speech failed: %v"), err) + ansiReset)
+			failed = true
+		}
+	}
+}
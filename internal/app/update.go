@@ -0,0 +1,194 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// Version is the running release, set at build time with
+// -ldflags "-X github.com/ViciousEagle03/P2P_QUICHAT/internal/app.Version=v1.2.3".
+var Version = "dev"
+
+// releaseKey is the base64 ed25519 key release manifests are signed with,
+// set at build time like Version. The config's updates.public_key overrides
+// it. Builds without either can't verify a release, so they never look for
+// one on their own.
+var releaseKey = ""
+
+const defaultReleaseFeed = "https://github.com/ViciousEagle03/P2P_QUICHAT/releases/latest/download/release.json"
+
+// Largest downloads fetch accepts. Everything is checked only once it is
+// in memory, so a feed or mirror serving endless data must not be able to
+// fill it first.
+const (
+	maxManifestBytes = 1 << 20   // release.json and its signature
+	maxBinaryBytes   = 256 << 20 // a release binary
+)
+
+// UpdateConfig controls the release feed and the startup update check,
+// which is off unless Check is set.
+type UpdateConfig struct {
+	Feed      string `json:"feed,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+	Check     bool   `json:"check,omitempty"`
+}
+
+// key returns the release signing key in use, or "" if there is none.
+func (cfg UpdateConfig) key() string {
+	if cfg.PublicKey != "" {
+		return cfg.PublicKey
+	}
+	return releaseKey
+}
+
+// Release is the signed manifest published alongside each release.
+// Its detached ed25519 signature lives at the feed URL plus ".sig".
+type Release struct {
+	Version string                  `json:"version"`
+	Assets  map[string]ReleaseAsset `json:"assets"` // keyed by GOOS/GOARCH
+}
+
+// ReleaseAsset is one downloadable binary.
+type ReleaseAsset struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Newer reports whether r is a later version than the running binary.
+// Development builds never consider themselves outdated.
+func (r *Release) Newer() bool {
+	return semver.IsValid(Version) && semver.Compare(r.Version, Version) > 0
+}
+
+// FetchRelease downloads the release manifest and verifies its signature.
+func FetchRelease(ctx context.Context, cfg UpdateConfig) (*Release, error) {
+	feed := cfg.Feed
+	if feed == "" {
+		feed = defaultReleaseFeed
+	}
+	keyText := cfg.key()
+	if keyText == "" {
+		return nil, errors.New("this build has no release signing key; set updates.public_key in the config")
+	}
+	key, err := base64.StdEncoding.DecodeString(keyText)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid release signing key")
+	}
+
+	manifest, err := fetch(ctx, feed, maxManifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	sigText, err := fetch(ctx, feed+".sig", maxManifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sigText)))
+	if err != nil {
+		return nil, fmt.Errorf("decode release signature: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), manifest, sig) {
+		return nil, errors.New("release manifest signature does not verify")
+	}
+
+	var r Release
+	if err := json.Unmarshal(manifest, &r); err != nil {
+		return nil, fmt.Errorf("parse release manifest: %w", err)
+	}
+	if !semver.IsValid(r.Version) {
+		return nil, fmt.Errorf("release manifest has invalid version %q", r.Version)
+	}
+	return &r, nil
+}
+
+// SelfUpdate downloads r's binary for this platform, checks its hash and
+// swaps it in for the running executable.
+func SelfUpdate(ctx context.Context, r *Release) error {
+	asset, ok := r.Assets[runtime.GOOS+"/"+runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("release %s has no build for %s/%s", r.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	bin, err := fetch(ctx, asset.URL, maxBinaryBytes)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(bin)
+	if hex.EncodeToString(sum[:]) != asset.SHA256 {
+		return errors.New("downloaded binary does not match the signed checksum")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, bin, 0o755); err != nil {
+		return err
+	}
+	// Move the old binary aside first: Windows refuses to overwrite a running one.
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
+
+// checkForUpdate tells the user once if a newer release is out.
+func (n *Node) checkForUpdate() {
+	ctx, cancel := context.WithTimeout(n.ctx, 15*time.Second)
+	defer cancel()
+	r, err := FetchRelease(ctx, n.cfg.Updates)
+	if err != nil || !r.Newer() {
+		return
+	}
+	n.notify("Quichat %s is available (you have %s) – run `quichat update`", r.Version, Version)
+}
+
+// fetch downloads url, failing rather than reading more than max bytes.
+func fetch(ctx context.Context, url string, max int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if resp.ContentLength > max {
+		return nil, fmt.Errorf("GET %s: %s is more than the %s allowed", url, formatSize(resp.ContentLength), formatSize(max))
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err == nil && int64(len(b)) > max {
+		err = fmt.Errorf("GET %s: more than the %s allowed", url, formatSize(max))
+	}
+	return b, err
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// parseVersion splits a "major.minor" protocol version.
+func parseVersion(v string) (major, minor int, ok bool) {
+	maj, min, _ := strings.Cut(v, ".")
+	major, err1 := strconv.Atoi(maj)
+	minor, err2 := strconv.Atoi(min)
+	return major, minor, err1 == nil && err2 == nil
+}
+
+// newerVersion reports whether protocol version a is newer than b.
+func newerVersion(a, b string) bool {
+	amaj, amin, aok := parseVersion(a)
+	bmaj, bmin, bok := parseVersion(b)
+	return aok && bok && (amaj > bmaj || amaj == bmaj && amin > bmin)
+}
+
+// compatibleVersion reports whether we can process a message stamped with
+// protocol version v. Messages from clients that predate versioning carry
+// none and are always processed.
+func compatibleVersion(v string) bool {
+	if v == "" {
+		return true
+	}
+	major, _, ok := parseVersion(v)
+	ours, _, _ := parseVersion(protocolVersion)
+	return ok && major == ours
+}
+
+// newerMajority returns the newest protocol version announced in the room
+// when more than half of the peers heard from recently run one newer than
+// ours.
+func (pr *presence) newerMajority() (string, bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	live, newer, newest := 0, 0, protocolVersion
+	now := time.Now()
+	for _, e := range pr.seen {
+		if now.Sub(e.at) > presenceTTL {
+			continue
+		}
+		live++
+		if newerVersion(e.version, protocolVersion) {
+			newer++
+			if newerVersion(e.version, newest) {
+				newest = e.version
+			}
+		}
+	}
+	return newest, newer*2 > live
+}
+
+// versionNotices makes sure each version problem is reported once rather
+// than for every message.
+type versionNotices struct {
+	mu       sync.Mutex
+	refused  map[peer.ID]bool
+	upgraded string // newest version we already suggested upgrading to
+}
+
+// refuse reports whether this is the first refused message from p.
+func (vn *versionNotices) refuse(p peer.ID) bool {
+	vn.mu.Lock()
+	defer vn.mu.Unlock()
+	if vn.refused == nil {
+		vn.refused = make(map[peer.ID]bool)
+	}
+	first := !vn.refused[p]
+	vn.refused[p] = true
+	return first
+}
+
+// upgrade reports whether an upgrade to version v has not been suggested yet.
+func (vn *versionNotices) upgrade(v string) bool {
+	vn.mu.Lock()
+	defer vn.mu.Unlock()
+	if vn.upgraded != "" && !newerVersion(v, vn.upgraded) {
+		return false
+	}
+	vn.upgraded = v
+	return true
+}
+
+// refuseIncompatible reports whether m must be dropped because its sender
+// speaks another major protocol version, telling the user once per peer.
+func (c *Chat) refuseIncompatible(m Message, from peer.ID) bool {
+	if compatibleVersion(m.Version) {
+		return false
+	}
+	if m.Text == joinText {
+		// its heartbeats still count towards the upgrade notice
+		c.presence.arrived(from, m, time.Now())
+		c.suggestUpgrade()
+	}
+	if c.versions.refuse(from) {
+		c.ui.Print("\033[31m" + fmt.Sprintf(T("Ignoring messages from %s (%s): it speaks protocol %s, this client speaks %s"),
+			m.Nick, shortID(from), m.Version, protocolVersion) + ansiReset)
+	}
+	return true
+}
+
+// suggestUpgrade prints an upgrade notice once most of the room runs a newer
+// protocol version than we do.
+func (c *Chat) suggestUpgrade() {
+	if v, ok := c.presence.newerMajority(); ok && c.versions.upgrade(v) {
+		c.ui.Print(ansiWarn + fmt.Sprintf(T("Most peers in this room run protocol %s, newer than this client's %s; run quichat update"),
+			v, protocolVersion) + ansiReset)
+	}
+}
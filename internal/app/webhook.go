@@ -0,0 +1,186 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	webhookQueue   = 256              // messages waiting to be posted; beyond this new ones are dropped
+	webhookTimeout = 10 * time.Second // per POST
+)
+
+// Webhook payload formats.
+const (
+	webhookJSON    = "json"
+	webhookSlack   = "slack"
+	webhookDiscord = "discord"
+)
+
+// WebhookConfig forwards the room's chat lines to an HTTP endpoint.
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Secret, if set, signs each body with HMAC-SHA256; the hex digest is
+	// sent as "X-Quichat-Signature: sha256=<digest>".
+	Secret string `json:"secret,omitempty"`
+	// Format is "json" (default), or "slack" or "discord" to post straight
+	// into those services' incoming webhooks.
+	Format string `json:"format,omitempty"`
+	// Rooms and Keywords narrow what is posted: only these rooms, and only
+	// lines containing one of the keywords (ignoring case). Empty means all.
+	Rooms    []string `json:"rooms,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// matches reports whether a line from room should go to this webhook.
+func (w WebhookConfig) matches(room, text string) bool {
+	if len(w.Rooms) > 0 && !slices.Contains(w.Rooms, room) {
+		return false
+	}
+	if len(w.Keywords) == 0 {
+		return true
+	}
+	text = strings.ToLower(text)
+	for _, k := range w.Keywords {
+		if strings.Contains(text, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookEvent is the body posted in the "json" format.
+type webhookEvent struct {
+	Room string    `json:"room"`
+	ID   string    `json:"id,omitempty"`
+	From string    `json:"from"`
+	Nick string    `json:"nick"`
+	Text string    `json:"text"`
+	Ts   time.Time `json:"ts"`
+}
+
+// body renders ev in the webhook's format.
+func (w WebhookConfig) body(ev webhookEvent) ([]byte, error) {
+	line := fmt.Sprintf("[%s] %s: %s", ev.Room, ev.Nick, ev.Text)
+	switch w.Format {
+	case webhookSlack:
+		return json.Marshal(map[string]string{"text": line})
+	case webhookDiscord:
+		return json.Marshal(map[string]string{"content": line})
+	}
+	return json.Marshal(ev)
+}
+
+// post delivers one event to the webhook.
+func (w WebhookConfig) post(ctx context.Context, client *http.Client, ev webhookEvent) error {
+	body, err := w.body(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "quichat/"+Version)
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Quichat-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := client.Do(req)
+	if uerr, ok := err.(*url.Error); ok {
+		return uerr.Err // without the URL, see host
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// host names the webhook in notices without its path, which for Slack and
+// Discord is the secret.
+func (w WebhookConfig) host() string {
+	if u, err := url.Parse(w.URL); err == nil {
+		return u.Host
+	}
+	return "?"
+}
+
+// webhookList returns a copy of the configured webhooks.
+func (c *Config) webhookList() []WebhookConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.Webhooks)
+}
+
+// validateWebhooks rejects webhook entries that could never be posted.
+func validateWebhooks(hooks []WebhookConfig) error {
+	for _, w := range hooks {
+		if !strings.HasPrefix(w.URL, "https://") && !strings.HasPrefix(w.URL, "http://") {
+			return fmt.Errorf("webhook %q: url must start with http:// or https://", w.URL)
+		}
+		switch w.Format {
+		case "", webhookJSON, webhookSlack, webhookDiscord:
+		default:
+			return fmt.Errorf("webhook %q: format must be json, slack or discord, not %q", w.URL, w.Format)
+		}
+	}
+	return nil
+}
+
+// forwardWebhook queues a delivered chat line for the webhooks, dropping it
+// if they have fallen too far behind.
+func (c *Chat) forwardWebhook(a arrival) {
+	if c.webhooks == nil {
+		return
+	}
+	ev := webhookEvent{Room: c.n.room, ID: a.m.ID, Nick: a.m.Nick, Text: a.m.Text, Ts: a.m.Ts}
+	if a.src != nil {
+		ev.From = a.src.GetFrom().String()
+	}
+	select {
+	case c.webhooks <- ev:
+	default:
+	}
+}
+
+// runWebhooks posts queued chat lines to every matching webhook in order.
+// A webhook that starts failing is reported once, and again after it
+// recovers and fails anew; failed posts are not retried.
+func (c *Chat) runWebhooks(ctx context.Context, hooks []WebhookConfig) error {
+	client := &http.Client{Timeout: webhookTimeout}
+	failing := make([]bool, len(hooks))
+	for {
+		var ev webhookEvent
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev = <-c.webhooks:
+		}
+		for i, w := range hooks {
+			if !w.matches(ev.Room, ev.Text) {
+				continue
+			}
+			err := w.post(ctx, client, ev)
+			if err != nil && !failing[i] && ctx.Err() == nil {
+				c.ui.Print(ansiWarn + fmt.Sprintf(T("Webhook %s failed: %v"), w.host(), err) + ansiReset)
+			}
+			failing[i] = err != nil
+		}
+	}
+}
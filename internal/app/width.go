@@ -0,0 +1,132 @@
+package app
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/bidi"
+	"golang.org/x/text/width"
+)
+
+// Unicode first-strong and pop directional isolates.
+const (
+	fsi = "\u2068"
+	pdi = "\u2069"
+)
+
+// runeWidth is the number of terminal cells r occupies.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200d || r == 0xfe0f || r == 0x2068 || r == 0x2069:
+		return 0 // joiners, variation selector, isolates
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.IsControl(r):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	if r >= 0x1f300 && r <= 0x1faff { // emoji render double width in most terminals
+		return 2
+	}
+	return 1
+}
+
+// displayWidth is the number of cells s occupies, ignoring ANSI escapes.
+func displayWidth(s string) int {
+	w := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w += runeWidth(r)
+		i += size
+	}
+	return w
+}
+
+// escapeLen returns the length of the CSI escape sequence s starts with, or 0.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != 0x1b || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// wrapLine breaks s into pieces no wider than cols cells, preferring to
+// break after a space and never splitting a wide character or an escape
+// sequence. Every piece after the first is prefixed with indent.
+func wrapLine(s string, cols int, indent string) []string {
+	if cols <= 0 || displayWidth(s) <= cols {
+		return []string{s}
+	}
+	limit := cols
+	var (
+		out      []string
+		start, w int
+		brk, bw  = -1, 0 // byte offset just past the last space, and the width up to it
+	)
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := runeWidth(r)
+		for w+rw > limit && i > start {
+			cut := i
+			if brk > start {
+				cut, w = brk, w-bw
+			} else {
+				w = 0
+			}
+			out = append(out, strings.TrimRight(s[start:cut], " "))
+			start, brk = cut, -1
+			limit = max(cols-displayWidth(indent), 1)
+		}
+		w += rw
+		i += size
+		if r == ' ' && w > len(indent) { // not the space after the » marker
+			brk, bw = i, w
+		}
+	}
+	out = append(out, s[start:])
+	for i := 1; i < len(out); i++ {
+		out[i] = indent + out[i]
+	}
+	return out
+}
+
+// hasRTL reports whether s contains right-to-left letters.
+func hasRTL(s string) bool {
+	for _, r := range s {
+		if p, _ := bidi.LookupRune(r); p.Class() == bidi.R || p.Class() == bidi.AL {
+			return true
+		}
+	}
+	return false
+}
+
+// isolateRTL wraps each line holding right-to-left text in directional
+// isolates, so terminals that apply bidi reorder only the message text and
+// leave the timestamp, nick and » marker where they belong.
+func isolateRTL(text string) string {
+	if !hasRTL(text) {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if hasRTL(l) {
+			lines[i] = fsi + l + pdi
+		}
+	}
+	return strings.Join(lines, "\n")
+}
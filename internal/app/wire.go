@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Wire format choices for Options.Wire.
+const (
+	WireAuto = "auto" // protobuf once every peer in the room reads it
+	WireJSON = "json" // always JSON
+)
+
+const (
+	wireProto   = "pb" // advertised in JOINs by peers that read protobuf
+	wireVersion = 0x01 // first byte of a protobuf-encoded message
+)
+
+// errWireVersion means a message uses an envelope this client can't read,
+// most likely from a newer release.
+var errWireVersion = errors.New("unknown wire version")
+
+// Envelope field numbers, see message.proto.
+const (
+	fieldID protowire.Number = iota + 1
+	fieldNick
+	fieldText
+	fieldTs
+	fieldSeq
+	fieldClock
+	fieldTrace
+	fieldWire
+	fieldCaps
+	fieldVersion
+	fieldPad
+	fieldEdit
+)
+
+// encodeMessage serialises m as protobuf when asked, JSON otherwise.
+func encodeMessage(m Message, proto bool) ([]byte, error) {
+	if !proto {
+		return json.Marshal(m)
+	}
+	b := []byte{wireVersion}
+	b = appendString(b, fieldID, m.ID)
+	b = appendString(b, fieldNick, m.Nick)
+	b = appendString(b, fieldText, m.Text)
+	if !m.Ts.IsZero() {
+		b = protowire.AppendTag(b, fieldTs, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Ts.UnixNano()))
+	}
+	b = appendUint(b, fieldSeq, m.Seq)
+	b = appendUint(b, fieldClock, m.Clock)
+	b = appendString(b, fieldTrace, m.Trace)
+	b = appendString(b, fieldWire, m.Wire)
+	b = appendUint(b, fieldCaps, m.Caps)
+	b = appendString(b, fieldVersion, m.Version)
+	b = appendString(b, fieldPad, m.Pad)
+	b = appendString(b, fieldEdit, m.Edit)
+	return b, nil
+}
+
+// decodeMessage accepts either encoding, telling them apart by the first
+// byte, and checks the result against the limits in inbound.go.
+func decodeMessage(b []byte) (Message, error) {
+	var m Message
+	switch {
+	case len(b) == 0:
+		return m, errors.New("empty message")
+	case len(b) > maxPayload:
+		return m, fmt.Errorf("%w: %d bytes", errInvalidMessage, len(b))
+	case b[0] == '{':
+		if err := json.Unmarshal(b, &m); err != nil {
+			return Message{}, err
+		}
+		return m, checkMessage(&m)
+	case b[0] != wireVersion:
+		return m, fmt.Errorf("%w %#x", errWireVersion, b[0])
+	}
+
+	b = b[1:]
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return m, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return m, protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case fieldID:
+				m.ID = v
+			case fieldNick:
+				m.Nick = v
+			case fieldText:
+				m.Text = v
+			case fieldTrace:
+				m.Trace = v
+			case fieldWire:
+				m.Wire = v
+			case fieldVersion:
+				m.Version = v
+			case fieldEdit:
+				m.Edit = v
+			}
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return m, protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case fieldTs:
+				m.Ts = time.Unix(0, int64(v)).UTC()
+			case fieldSeq:
+				m.Seq = v
+			case fieldClock:
+				m.Clock = v
+			case fieldCaps:
+				m.Caps = v
+			}
+		default: // skip fields this version doesn't know
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return m, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return m, checkMessage(&m)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendUint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+// sameMessage compares messages field by field, timestamps by instant.
+func sameMessage(a, b Message) bool {
+	if !a.Ts.Equal(b.Ts) {
+		return false
+	}
+	a.Ts, b.Ts = time.Time{}, time.Time{}
+	return a == b
+}
+
+func sampleMessage() Message {
+	return Message{
+		ID: "3f9a0c1d2e4b5a69", Nick: "alice", Text: "hello, room",
+		Ts:  time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		Seq: 7, Clock: 42, Version: protocolVersion,
+	}
+}
+
+func FuzzDecodeMessage(f *testing.F) {
+	m := sampleMessage()
+	for _, proto := range []bool{false, true} {
+		b, err := encodeMessage(m, proto)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	join, _ := encodeMessage(Message{Nick: "bob", Text: joinText, Wire: wireProto, Caps: ourCaps, Version: protocolVersion}, true)
+	f.Add(join)
+	f.Add([]byte(`{"id":"a1","nick":"carol","text":"hi","ts":"2026-10-16T09:30:00+05:30","edit":"b2"}`))
+	f.Add([]byte(`{"nick":"\u001b[2J","text":"‮evil"}`))
+	f.Add([]byte{wireVersion, 0x0a, 0xff}) // ID claiming more bytes than there are
+	f.Add([]byte{wireVersion, 0x12, 0x02, 0xc3, 0x28})
+	f.Add([]byte{0x02, 0x00})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m, err := decodeMessage(b)
+		if err != nil {
+			return
+		}
+		if err := checkMessage(&m); err != nil {
+			t.Fatalf("decoded message fails its own checks: %v", err)
+		}
+		for _, proto := range []bool{false, true} {
+			if proto && !m.Ts.IsZero() && !time.Unix(0, m.Ts.UnixNano()).Equal(m.Ts) {
+				continue // beyond what the protobuf timestamp holds
+			}
+			enc, err := encodeMessage(m, proto)
+			if err != nil {
+				t.Fatalf("encode (proto %v): %v", proto, err)
+			}
+			if len(enc) > maxPayload {
+				continue // JSON escaping can outgrow the limit
+			}
+			back, err := decodeMessage(enc)
+			if err != nil {
+				t.Fatalf("decode after encode (proto %v): %v", proto, err)
+			}
+			if !sameMessage(m, back) {
+				t.Fatalf("round trip (proto %v) changed\n%+v\ninto\n%+v", proto, m, back)
+			}
+		}
+	})
+}
+
+func BenchmarkEncodeMessage(b *testing.B) {
+	m := sampleMessage()
+	for _, proto := range []bool{false, true} {
+		b.Run(map[bool]string{false: "json", true: "proto"}[proto], func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := encodeMessage(m, proto); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeMessage(b *testing.B) {
+	for _, proto := range []bool{false, true} {
+		enc, err := encodeMessage(sampleMessage(), proto)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(map[bool]string{false: "json", true: "proto"}[proto], func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(enc)))
+			for b.Loop() {
+				if _, err := decodeMessage(enc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package app
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// XMPP gateway: an external server component (XEP-0114) that makes the room
+// look like a multi-user chat (XEP-0045) to the XMPP server's users. Any
+// address at the component's domain, such as room@chat.example.org, is the
+// room; XMPP occupants appear in the room through the gateway's own
+// messages, and room members appear to them as MUC occupants.
+const (
+	xmppDialTimeout = 10 * time.Second
+	xmppSyncEvery   = 5 * time.Second // how often room membership is mirrored as presence
+	nsStanzas       = "urn:ietf:params:xml:ns:xmpp-stanzas"
+)
+
+// XMPPGatewayOptions configures RunXMPPGateway.
+type XMPPGatewayOptions struct {
+	Server string // host:port of the server's component listener
+	Domain string // component domain configured on the server
+	Secret string // shared component secret
+}
+
+// xmppStanza is an incoming presence or message.
+type xmppStanza struct {
+	XMLName xml.Name
+	From    string `xml:"from,attr"`
+	To      string `xml:"to,attr"`
+	Type    string `xml:"type,attr"`
+	ID      string `xml:"id,attr"`
+	Body    string `xml:"body"`
+}
+
+type xmppPresence struct {
+	XMLName xml.Name   `xml:"presence"`
+	From    string     `xml:"from,attr"`
+	To      string     `xml:"to,attr"`
+	Type    string     `xml:"type,attr,omitempty"`
+	ID      string     `xml:"id,attr,omitempty"`
+	X       *mucUser   `xml:"http://jabber.org/protocol/muc#user x,omitempty"`
+	Error   *xmppError `xml:"error,omitempty"`
+}
+
+type mucUser struct {
+	Item   mucItem     `xml:"item"`
+	Status []mucStatus `xml:"status,omitempty"`
+}
+
+type mucItem struct {
+	Affiliation string `xml:"affiliation,attr"`
+	Role        string `xml:"role,attr"`
+}
+
+type mucStatus struct {
+	Code int `xml:"code,attr"`
+}
+
+type xmppMessage struct {
+	XMLName xml.Name   `xml:"message"`
+	From    string     `xml:"from,attr"`
+	To      string     `xml:"to,attr"`
+	Type    string     `xml:"type,attr"`
+	ID      string     `xml:"id,attr,omitempty"`
+	Body    string     `xml:"body,omitempty"`
+	Error   *xmppError `xml:"error,omitempty"`
+}
+
+type xmppError struct {
+	Type      string    `xml:"type,attr"`
+	Condition xmppInner `xml:",any"`
+}
+
+type xmppInner struct {
+	XMLName xml.Name
+}
+
+func stanzaError(typ, condition string) *xmppError {
+	return &xmppError{Type: typ, Condition: xmppInner{XMLName: xml.Name{Space: nsStanzas, Local: condition}}}
+}
+
+// occupant is an XMPP user in the room.
+type occupant struct {
+	muc  string // bare address of the MUC they joined
+	nick string
+}
+
+// xmppGateway is one component session.
+type xmppGateway struct {
+	c    *Chat
+	conn net.Conn
+	dec  *xml.Decoder
+	wmu  sync.Mutex
+
+	mu        sync.Mutex
+	occupants map[string]occupant // by full XMPP address
+	members   []string            // room nicks announced to occupants
+}
+
+// RunXMPPGateway joins the room as nick and serves it to an XMPP server as
+// a component until ctx is cancelled, reconnecting with backoff.
+func RunXMPPGateway(ctx context.Context, n *Node, nick string, opts XMPPGatewayOptions, out io.Writer) error {
+	ui := &lineUI{w: out}
+	chat := NewChat(n, nick, ui)
+	var (
+		mu  sync.Mutex
+		cur *xmppGateway
+	)
+	self := n.Host.ID()
+	chat.tap = func(a arrival) {
+		if a.src == nil || a.src.GetFrom() == self {
+			return // lines we posted for XMPP occupants were echoed to them already
+		}
+		mu.Lock()
+		gw := cur
+		mu.Unlock()
+		if gw != nil {
+			gw.broadcast(a.m.Nick, a.m.Text)
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error { return chat.Run(ctx) })
+	g.Go(guard("xmpp", func() error {
+		backoff := bridgeRetry
+		for {
+			gw, err := dialXMPP(ctx, chat, opts)
+			if err == nil {
+				ui.Print(fmt.Sprintf(T("XMPP: serving the room as %s"), opts.Domain))
+				mu.Lock()
+				cur = gw
+				mu.Unlock()
+				backoff = bridgeRetry
+				err = gw.run(ctx)
+				mu.Lock()
+				cur = nil
+				mu.Unlock()
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			ui.Print(ansiWarn + fmt.Sprintf(T("XMPP: %v; reconnecting in %s"), err, backoff) + ansiReset)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, bridgeMaxBackoff)
+		}
+	}))
+	return g.Wait()
+}
+
+// dialXMPP opens the component stream and authenticates with the secret.
+func dialXMPP(ctx context.Context, c *Chat, opts XMPPGatewayOptions) (*xmppGateway, error) {
+	d := &net.Dialer{Timeout: xmppDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", opts.Server)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(xmppDialTimeout))
+	fmt.Fprintf(conn, "<?xml version='1.0'?><stream:stream xmlns='jabber:component:accept' xmlns:stream='http://etherx.jabber.org/streams' to='%s'>",
+		xmlEscape(opts.Domain))
+
+	dec := xml.NewDecoder(conn)
+	streamID := ""
+	for streamID == "" {
+		tok, err := dec.Token()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "stream" {
+			for _, a := range se.Attr {
+				if a.Name.Local == "id" {
+					streamID = a.Value
+				}
+			}
+			if streamID == "" {
+				conn.Close()
+				return nil, errors.New("server sent no stream id")
+			}
+		}
+	}
+	sum := sha1.Sum([]byte(streamID + opts.Secret))
+	fmt.Fprintf(conn, "<handshake>%s</handshake>", hex.EncodeToString(sum[:]))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local != "handshake" {
+			conn.Close()
+			return nil, errors.New("server rejected the component secret or domain")
+		}
+		dec.Skip()
+		break
+	}
+	conn.SetDeadline(time.Time{})
+	return &xmppGateway{c: c, conn: conn, dec: dec, occupants: make(map[string]occupant)}, nil
+}
+
+// run handles stanzas and mirrors room membership until the stream ends.
+func (gw *xmppGateway) run(ctx context.Context) error {
+	defer gw.conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		tick := time.NewTicker(xmppSyncEvery)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				gw.conn.Close()
+				return
+			case <-done:
+				return
+			case <-tick.C:
+				gw.syncMembers()
+			}
+		}
+	}()
+
+	for {
+		tok, err := gw.dec.Token()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var st xmppStanza
+		if err := gw.dec.DecodeElement(&st, &se); err != nil {
+			return err
+		}
+		switch st.XMLName.Local {
+		case "presence":
+			gw.presence(st)
+		case "message":
+			gw.message(st)
+		case "error":
+			return errors.New("server closed the stream with an error")
+		}
+	}
+}
+
+// presence handles joining and leaving the room.
+func (gw *xmppGateway) presence(st xmppStanza) {
+	muc, nick, _ := strings.Cut(st.To, "/")
+	gw.mu.Lock()
+	prev, joined := gw.occupants[st.From]
+
+	if st.Type == "unavailable" {
+		if !joined {
+			gw.mu.Unlock()
+			return
+		}
+		delete(gw.occupants, st.From)
+		others := gw.others(st.From)
+		gw.mu.Unlock()
+		gw.send(xmppPresence{From: prev.muc + "/" + prev.nick, To: st.From, Type: "unavailable",
+			X: &mucUser{Item: mucItem{"none", "none"}, Status: []mucStatus{{110}}}})
+		for _, to := range others {
+			gw.send(xmppPresence{From: prev.muc + "/" + prev.nick, To: to, Type: "unavailable", X: &mucUser{Item: mucItem{"none", "none"}}})
+		}
+		gw.c.post(fmt.Sprintf("*** %s left (XMPP) ***", prev.nick))
+		return
+	}
+	if st.Type != "" || joined || nick == "" {
+		gw.mu.Unlock()
+		return // probes, subscriptions and status changes need no answer
+	}
+	if gw.nickTaken(nick) {
+		gw.mu.Unlock()
+		gw.send(xmppPresence{From: st.To, To: st.From, Type: "error", ID: st.ID, Error: stanzaError("cancel", "conflict")})
+		return
+	}
+	others := gw.others(st.From)
+	var existing []occupant
+	for _, o := range gw.occupants {
+		existing = append(existing, o)
+	}
+	members := slices.Clone(gw.members)
+	gw.occupants[st.From] = occupant{muc: muc, nick: nick}
+	gw.mu.Unlock()
+
+	// everyone already in the room, then the newcomer's own presence last
+	for _, m := range members {
+		gw.send(xmppPresence{From: muc + "/" + m, To: st.From, X: &mucUser{Item: mucItem{"none", "participant"}}})
+	}
+	for _, o := range existing {
+		gw.send(xmppPresence{From: muc + "/" + o.nick, To: st.From, X: &mucUser{Item: mucItem{"none", "participant"}}})
+	}
+	for _, to := range others {
+		gw.send(xmppPresence{From: muc + "/" + nick, To: to, X: &mucUser{Item: mucItem{"none", "participant"}}})
+	}
+	gw.send(xmppPresence{From: st.To, To: st.From, X: &mucUser{Item: mucItem{"none", "participant"}, Status: []mucStatus{{110}}}})
+	gw.c.post(fmt.Sprintf("*** %s joined (XMPP) ***", nick))
+}
+
+// message posts an occupant's groupchat line to the room and echoes it to
+// every occupant, as a MUC does.
+func (gw *xmppGateway) message(st xmppStanza) {
+	if st.Type != "groupchat" || st.Body == "" {
+		return
+	}
+	gw.mu.Lock()
+	o, ok := gw.occupants[st.From]
+	gw.mu.Unlock()
+	if !ok {
+		gw.send(xmppMessage{From: st.To, To: st.From, Type: "error", ID: st.ID, Error: stanzaError("modify", "not-acceptable")})
+		return
+	}
+	if _, err := gw.c.post(fmt.Sprintf("<%s> %s", o.nick, st.Body)); err != nil {
+		gw.send(xmppMessage{From: st.To, To: st.From, Type: "error", ID: st.ID, Error: stanzaError("wait", "resource-constraint")})
+		return
+	}
+	gw.mu.Lock()
+	all := gw.others("")
+	gw.mu.Unlock()
+	for _, to := range all {
+		gw.send(xmppMessage{From: o.muc + "/" + o.nick, To: to, Type: "groupchat", ID: st.ID, Body: st.Body})
+	}
+}
+
+// broadcast relays a room line from nick to every occupant.
+func (gw *xmppGateway) broadcast(nick, text string) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	for jid, o := range gw.occupants {
+		gw.send(xmppMessage{From: o.muc + "/" + nick, To: jid, Type: "groupchat", Body: text})
+	}
+}
+
+// syncMembers announces room members that came or went since the last
+// call to every occupant.
+func (gw *xmppGateway) syncMembers() {
+	now := gw.c.presence.nicks()
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	for _, m := range now {
+		if !slices.Contains(gw.members, m) {
+			for jid, o := range gw.occupants {
+				gw.send(xmppPresence{From: o.muc + "/" + m, To: jid, X: &mucUser{Item: mucItem{"none", "participant"}}})
+			}
+		}
+	}
+	for _, m := range gw.members {
+		if !slices.Contains(now, m) {
+			for jid, o := range gw.occupants {
+				gw.send(xmppPresence{From: o.muc + "/" + m, To: jid, Type: "unavailable", X: &mucUser{Item: mucItem{"none", "none"}}})
+			}
+		}
+	}
+	gw.members = now
+}
+
+// others lists every occupant's address except except. gw.mu must be held.
+func (gw *xmppGateway) others(except string) []string {
+	var out []string
+	for jid := range gw.occupants {
+		if jid != except {
+			out = append(out, jid)
+		}
+	}
+	return out
+}
+
+// nickTaken reports whether nick is in use in the room. gw.mu must be held.
+func (gw *xmppGateway) nickTaken(nick string) bool {
+	if nick == gw.c.myNick() || slices.Contains(gw.members, nick) {
+		return true
+	}
+	for _, o := range gw.occupants {
+		if o.nick == nick {
+			return true
+		}
+	}
+	return false
+}
+
+func (gw *xmppGateway) send(v any) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return
+	}
+	gw.wmu.Lock()
+	defer gw.wmu.Unlock()
+	gw.conn.SetWriteDeadline(time.Now().Add(xmppDialTimeout))
+	gw.conn.Write(b) // a broken stream ends run, which reconnects
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}